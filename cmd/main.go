@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -27,44 +30,147 @@ func loadConfig() (*convoai.ConvoAIConfig, error) {
 		BaseURL:        os.Getenv("AGORA_CONVO_AI_BASE_URL"),
 		AgentUID:       os.Getenv("AGENT_UID"),
 
+		// Agora Webhook Configuration
+		AgoraWebhookSecret: os.Getenv("AGORA_WEBHOOK_SECRET"),
+
 		// LLM Configuration
-		LLMModel: os.Getenv("LLM_MODEL"),
-		LLMURL:   os.Getenv("LLM_URL"),
-		LLMToken: os.Getenv("LLM_TOKEN"),
+		LLMProvider: os.Getenv("LLM_PROVIDER"),
+		LLM:         convoai.LoadLLMConfigs(os.Getenv),
+
+		// LLM Proxy Configuration
+		LLMProxyURL:           os.Getenv("LLM_PROXY_URL"),
+		LLMProxyContextSecret: os.Getenv("LLM_PROXY_CONTEXT_SECRET"),
 
 		// TTS Configuration
 		TTSVendor: os.Getenv("TTS_VENDOR"),
-	}
+		TTS:       convoai.LoadTTSConfigs(os.Getenv),
 
-	// Microsoft TTS Configuration
-	if msKey := os.Getenv("MICROSOFT_TTS_KEY"); msKey != "" {
-		config.MicrosoftTTS = &convoai.MicrosoftTTSConfig{
-			Key:       msKey,
-			Region:    os.Getenv("MICROSOFT_TTS_REGION"),
-			VoiceName: os.Getenv("MICROSOFT_TTS_VOICE_NAME"),
-			Rate:      os.Getenv("MICROSOFT_TTS_RATE"),
-			Volume:    os.Getenv("MICROSOFT_TTS_VOLUME"),
-		}
-	}
-
-	// ElevenLabs TTS Configuration
-	if elKey := os.Getenv("ELEVENLABS_API_KEY"); elKey != "" {
-		config.ElevenLabsTTS = &convoai.ElevenLabsTTSConfig{
-			Key:     elKey,
-			VoiceID: os.Getenv("ELEVENLABS_VOICE_ID"),
-			ModelID: os.Getenv("ELEVENLABS_MODEL_ID"),
-		}
+		// ASR Configuration
+		ASRVendor: os.Getenv("ASR_VENDOR"),
+		ASR:       convoai.LoadASRConfigs(os.Getenv),
 	}
 
 	// Modalities Configuration
 	config.InputModalities = os.Getenv("INPUT_MODALITIES")
 	config.OutputModalities = os.Getenv("OUTPUT_MODALITIES")
 
+	// Agent Profile Configuration
+	config.ProfilesDir = os.Getenv("AGENT_PROFILES_DIR")
+	profiles, err := convoai.LoadProfiles(config.ProfilesDir)
+	if err != nil {
+		return nil, err
+	}
+	config.Profiles = profiles
+
+	// Session Store Configuration
+	config.SessionStore = os.Getenv("SESSION_STORE")
+	if config.SessionStore == "" {
+		config.SessionStore = convoai.SessionStoreMemory
+	}
+	config.SessionStorePath = os.Getenv("SESSION_STORE_PATH")
+
+	// Shutdown Configuration
+	config.ShutdownPolicy = os.Getenv("SHUTDOWN_POLICY")
+	if config.ShutdownPolicy == "" {
+		config.ShutdownPolicy = convoai.ShutdownPolicyLeave
+	}
+	if concurrency := os.Getenv("SHUTDOWN_CONCURRENCY"); concurrency != "" {
+		value, err := strconv.Atoi(concurrency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SHUTDOWN_CONCURRENCY: %v", err)
+		}
+		config.ShutdownConcurrency = value
+	}
+	if config.RAGDocsDir = os.Getenv("RAG_DOCS_DIR"); config.RAGDocsDir != "" {
+		index, err := convoai.LoadBM25Index(config.RAGDocsDir)
+		if err != nil {
+			return nil, err
+		}
+		config.Retriever = index
+	}
+	if config.ToolsFile = os.Getenv("TOOLS_FILE"); config.ToolsFile != "" {
+		tools, err := convoai.LoadToolsFile(config.ToolsFile)
+		if err != nil {
+			return nil, err
+		}
+		config.WebhookTools = tools.Tools
+		config.MCPServers = tools.MCPServers
+	}
+	if config.WebhooksFile = os.Getenv("WEBHOOKS_FILE"); config.WebhooksFile != "" {
+		subscriptions, err := convoai.LoadWebhookSubscriptions(config.WebhooksFile)
+		if err != nil {
+			return nil, err
+		}
+		config.WebhookSubscriptions = subscriptions
+	}
+	config.AgentPollInterval = convoai.DefaultAgentPollInterval
+	config.AgentTokenRefreshInterval = convoai.DefaultAgentTokenRefreshInterval
+	if topK := os.Getenv("RAG_TOP_K"); topK != "" {
+		value, err := strconv.Atoi(topK)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RAG_TOP_K: %v", err)
+		}
+		config.RetrievalTopK = value
+	}
+	for name, limit := range map[string]*int{
+		"TRANSCRIPT_MAX_ENTRIES":       &config.TranscriptMaxEntries,
+		"TRANSCRIPT_MAX_AGENTS":        &config.TranscriptMaxAgents,
+		"TRANSCRIPT_RETENTION_HOURS":   &config.TranscriptRetentionHours,
+		"WEBHOOK_MAX_ATTEMPTS":         &config.WebhookMaxAttempts,
+		"AGENT_POLL_INTERVAL":          &config.AgentPollInterval,
+		"AGENT_TOKEN_REFRESH_INTERVAL": &config.AgentTokenRefreshInterval,
+	} {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*limit = parsed
+		}
+	}
+	if rateLimit := os.Getenv("LLM_PROXY_RATE_LIMIT"); rateLimit != "" {
+		value, err := strconv.Atoi(rateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LLM_PROXY_RATE_LIMIT: %v", err)
+		}
+		config.LLMProxyRateLimit = value
+	}
+
 	return config, nil
 }
 
-func setupServer() *http.Server {
+// processEnv records the variables set in the process environment before the .env
+// file is loaded, so a reload never lets the .env file override them.
+var processEnv = map[string]bool{}
+
+// reloadEnvFile re-reads the .env file, applying only the variables that were not
+// set in the process environment, matching the behaviour of godotenv.Load.
+func reloadEnvFile() {
+	values, err := godotenv.Read()
+	if err != nil {
+		log.Println("Warning: Error reading .env file. Using existing environment variables.")
+		return
+	}
+	for key, value := range values {
+		if !processEnv[key] {
+			os.Setenv(key, value)
+		}
+	}
+}
+
+// reloadConfig re-reads the environment and agent profiles for a hot reload
+func reloadConfig() (*convoai.ConvoAIConfig, error) {
+	reloadEnvFile()
+	return loadConfig()
+}
+
+func setupServer() (*http.Server, *convoai.ConvoAIService) {
 	log.Println("Starting setupServer")
+	for _, entry := range os.Environ() {
+		if key, _, ok := strings.Cut(entry, "="); ok {
+			processEnv[key] = true
+		}
+	}
 	if err := godotenv.Load(); err != nil {
 		log.Println("Warning: Error loading .env file. Using existing environment variables.")
 	}
@@ -100,9 +206,26 @@ func setupServer() *http.Server {
 	tokenService := token_service.NewTokenService(config.AppID, config.AppCertificate)
 	tokenService.RegisterRoutes(router)
 
-	convoAIService := convoai.NewConvoAIService(config, tokenService)
+	sessionStore, err := convoai.NewSessionStore(config)
+	if err != nil {
+		log.Fatal("Failed to open session store: ", err)
+	}
+	convoAIService := convoai.NewConvoAIService(config, tokenService, sessionStore)
 	convoAIService.RegisterRoutes(router)
 
+	// Stop agents orphaned by a previous run of the server
+	log.Printf("Reconciling agent sessions recorded in the %s session store", config.SessionStore)
+	if result, err := convoAIService.ReconcileSessions(); err != nil {
+		log.Println("Warning: failed to reconcile agent sessions:", err)
+	} else {
+		convoai.LogReconcileResult(result)
+	}
+
+	// Offer the tools of the configured MCP servers to agents
+	if err := convoAIService.ConnectMCPServers(context.Background()); err != nil {
+		log.Println("Warning: failed to connect MCP servers:", err)
+	}
+
 	// Register healthcheck route
 	router.GET("/ping", Ping)
 
@@ -111,14 +234,16 @@ func setupServer() *http.Server {
 		Addr:    ":" + serverPort,
 		Handler: router,
 	}
+	// End open event streams and agent sockets on shutdown, instead of waiting for their clients
+	server.RegisterOnShutdown(convoAIService.CloseStreams)
 
 	log.Println("Server setup completed")
 	log.Println("- listening on port", serverPort)
-	return server
+	return server, convoAIService
 }
 
 func main() {
-	server := setupServer()
+	server, convoAIService := setupServer()
 
 	// Start the server in a separate goroutine to handle graceful shutdown.
 	go func() {
@@ -128,6 +253,27 @@ func main() {
 
 	}()
 
+	// Watch the agents this instance started for status changes, such as idle timeouts,
+	// and refresh their tokens before they expire.
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	go convoAIService.WatchAgents(watchCtx)
+	go convoAIService.RefreshAgentTokens(watchCtx)
+
+	// Reload the configuration and agent profiles on SIGHUP.
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			log.Println("Reloading configuration...")
+			revision, err := convoAIService.ReloadConfig(reloadConfig, validation.ValidateEnvironment)
+			if err != nil {
+				log.Printf("Configuration reload failed, keeping revision %s: %v", revision, err)
+				continue
+			}
+			log.Println("Configuration reloaded, active revision", revision)
+		}
+	}()
+
 	// Prepare to handle graceful shutdown.
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
@@ -135,12 +281,24 @@ func main() {
 	// Wait for a shutdown signal.
 	<-quit
 	log.Println("Shutting down server...")
+	stopWatching()
 
 	// Attempt to gracefully shutdown the server with a timeout of 5 seconds.
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		log.Println("Server forced to shutdown:", err)
+	}
+	cancel()
+
+	// Stop accepting invites first, then drain the agents this instance started, with
+	// a deadline of its own so a slow server shutdown doesn't use it up.
+	timeout := convoAIService.ShutdownTimeout()
+	agentsCtx, cancelAgents := context.WithTimeout(context.Background(), timeout)
+	defer cancelAgents()
+	log.Printf("Applying agent shutdown policy (timeout %s)", timeout)
+	convoai.LogShutdownResults(convoAIService.ShutdownAgents(agentsCtx))
+	if err := convoAIService.Close(); err != nil {
+		log.Println("Warning:", err)
 	}
 
 	log.Println("Server exiting")
@@ -1,7 +1,12 @@
 package convoai
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/AgoraIO-Community/convo-ai-go-server/token_service"
 	"github.com/gin-gonic/gin"
@@ -9,16 +14,50 @@ import (
 
 // ConvoAIService handles AI conversation functionality
 type ConvoAIService struct {
-	config       *ConvoAIConfig
+	state        atomic.Pointer[configState]
+	reloadMu     sync.Mutex
 	tokenService *token_service.TokenService
+	sessions     SessionStore
+	instanceID   string
+
+	// LLM proxy state
+	llmRateLimiter  rateLimiter
+	channelContexts channelContexts
+	tools           *ToolRegistry
+	transcripts     *TranscriptStore
+
+	// Agent lifecycle state, and the bus its events are published on
+	events       *EventBus
+	agentStates  agentStates
+	agoraNotices noticeSet
+
+	// Closed on shutdown, ending the open event streams and agent sockets
+	streamsDone  chan struct{}
+	closeStreams sync.Once
+
+	// Outbound webhooks, queued for every event published
+	webhooks *webhookDispatcher
+
+	// MCP servers whose tools are registered
+	mcpMu      sync.Mutex
+	mcpClients []*MCPClient
 }
 
 // NewConvoAIService creates a new ConvoAIService instance
-func NewConvoAIService(config *ConvoAIConfig, tokenService *token_service.TokenService) *ConvoAIService {
-	return &ConvoAIService{
-		config:       config,
+func NewConvoAIService(config *ConvoAIConfig, tokenService *token_service.TokenService, sessions SessionStore) *ConvoAIService {
+	s := &ConvoAIService{
 		tokenService: tokenService,
+		sessions:     sessions,
+		tools:        NewToolRegistry(),
+		transcripts:  NewTranscriptStore(),
+		events:       NewEventBus(),
+		streamsDone:  make(chan struct{}),
+		instanceID:   fmt.Sprintf("%d-%s", time.Now().UnixNano(), randomString(8)),
 	}
+	s.state.Store(newConfigState(config))
+
+	s.webhooks = newWebhookDispatcher()
+	return s
 }
 
 // Register the ConvoAI service routes
@@ -26,6 +65,29 @@ func (s *ConvoAIService) RegisterRoutes(router *gin.Engine) {
 	agent := router.Group("/agent")
 	agent.POST("/invite", s.InviteAgent)
 	agent.POST("/remove", s.RemoveAgent)
+	agent.GET("/list", s.ListAgents)
+	agent.GET("/:agent_id", s.GetAgent)
+	agent.GET("/:agent_id/history", s.GetAgentHistory)
+	agent.GET("/:agent_id/events", s.AgentEvents)
+	agent.GET("/:agent_id/ws", s.AgentSocket)
+	agent.POST("/:agent_id/speak", s.SpeakAgent)
+	agent.POST("/:agent_id/interrupt", s.InterruptAgent)
+	agent.POST("/:agent_id/update", s.UpdateAgent)
+
+	llm := router.Group("/llm")
+	llm.POST("/chat/completions", s.LLMChatCompletions)
+	channelContext := llm.Group("/context", s.authorizeChannelContext)
+	channelContext.PUT("/:channel_name", s.SetChannelContext)
+	channelContext.DELETE("/:channel_name", s.ClearChannelContext)
+
+	webhooks := router.Group("/webhooks")
+	webhooks.POST("/agora", s.AgoraWebhook)
+
+	admin := router.Group("/admin")
+	admin.GET("/config/version", s.ConfigVersion)
+	admin.GET("/webhooks/deliveries", s.WebhookDeliveries)
+	admin.GET("/webhooks/dead-letters", s.WebhookDeadLetters)
+	admin.POST("/webhooks/dead-letters/:delivery_id/retry", s.RetryWebhookDeadLetter)
 }
 
 // InviteAgent handles the agent invitation request
@@ -75,3 +137,180 @@ func (s *ConvoAIService) RemoveAgent(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// ListAgents handles the request to list agents, filtered by channel and requester
+func (s *ConvoAIService) ListAgents(c *gin.Context) {
+	var req ListAgentsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call the handler
+	response, err := s.HandleListAgents(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAgent handles the request to query the status of a single agent
+func (s *ConvoAIService) GetAgent(c *gin.Context) {
+	agentID := c.Param("agent_id")
+
+	// Call the handler
+	response, err := s.HandleGetAgent(agentID)
+	if err != nil {
+		if errors.Is(err, ErrAgentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAgentHistory handles the request for an agent's transcript, as JSON, NDJSON or plain text
+func (s *ConvoAIService) GetAgentHistory(c *gin.Context) {
+	var req AgentHistoryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Format == "" {
+		req.Format = TranscriptFormatJSON
+	}
+	if req.Format != TranscriptFormatJSON && req.Format != TranscriptFormatNDJSON && req.Format != TranscriptFormatText {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json, ndjson or text"})
+		return
+	}
+	agentID := c.Param("agent_id")
+
+	// Call the handler
+	response, err := s.HandleGetAgentHistory(agentID)
+	if err != nil {
+		if errors.Is(err, ErrAgentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch req.Format {
+	case TranscriptFormatNDJSON:
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ndjson"`, agentID))
+		c.Data(http.StatusOK, "application/x-ndjson", formatTranscriptNDJSON(response.Entries))
+	case TranscriptFormatText:
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.txt"`, agentID))
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", formatTranscriptText(response.Entries))
+	default:
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// SpeakAgent handles the request to make a running agent say the text
+func (s *ConvoAIService) SpeakAgent(c *gin.Context) {
+	var req SpeakAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate the request
+	if err := validateSpeakRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call the handler
+	response, err := s.HandleSpeakAgent(c.Param("agent_id"), req)
+	if err != nil {
+		if errors.Is(err, ErrAgentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// InterruptAgent handles the request to stop a running agent mid-utterance
+func (s *ConvoAIService) InterruptAgent(c *gin.Context) {
+	// Call the handler
+	response, err := s.HandleInterruptAgent(c.Param("agent_id"))
+	if err != nil {
+		if errors.Is(err, ErrAgentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateAgent handles the request to change the settings of a running agent
+func (s *ConvoAIService) UpdateAgent(c *gin.Context) {
+	var req UpdateAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate the request
+	if err := validateUpdateRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call the handler
+	response, err := s.HandleUpdateAgent(c.Param("agent_id"), req)
+	if err != nil {
+		if errors.Is(err, ErrAgentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ConfigVersion handles the request for the active configuration revision
+func (s *ConvoAIService) ConfigVersion(c *gin.Context) {
+	c.JSON(http.StatusOK, s.HandleConfigVersion())
+}
+
+// WebhookDeliveries handles the request for the outbound webhook delivery log
+func (s *ConvoAIService) WebhookDeliveries(c *gin.Context) {
+	c.JSON(http.StatusOK, s.HandleWebhookDeliveries())
+}
+
+// WebhookDeadLetters handles the request for the webhooks that exhausted their attempts
+func (s *ConvoAIService) WebhookDeadLetters(c *gin.Context) {
+	c.JSON(http.StatusOK, s.HandleWebhookDeadLetters())
+}
+
+// RetryWebhookDeadLetter handles the request to deliver a dead letter again
+func (s *ConvoAIService) RetryWebhookDeadLetter(c *gin.Context) {
+	delivery, err := s.HandleRetryWebhookDeadLetter(c.Param("delivery_id"))
+	if err != nil {
+		if errors.Is(err, ErrDeliveryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
@@ -2,11 +2,35 @@ package convoai
 
 // InviteAgentRequest represents the request body for inviting an AI agent
 type InviteAgentRequest struct {
-	RequesterID      string   `json:"requester_id"`
-	ChannelName      string   `json:"channel_name"`
-	RtcCodec         *int     `json:"rtc_codec,omitempty"`
-	InputModalities  []string `json:"input_modalities,omitempty"`
-	OutputModalities []string `json:"output_modalities,omitempty"`
+	RequesterID      string               `json:"requester_id"`
+	ChannelName      string               `json:"channel_name"`
+	Profile          string               `json:"profile,omitempty"`
+	RtcCodec         *int                 `json:"rtc_codec,omitempty"`
+	InputModalities  []string             `json:"input_modalities,omitempty"`
+	OutputModalities []string             `json:"output_modalities,omitempty"`
+	Overrides        *AgentOverrides      `json:"overrides,omitempty"`
+	Multilingual     *MultilingualRequest `json:"multilingual,omitempty"`
+}
+
+// MultilingualRequest enables multilingual mode, where the agent recognizes and answers in
+// any of the listed languages. The first language is the primary one.
+type MultilingualRequest struct {
+	Languages []string `json:"languages"`
+}
+
+// AgentOverrides holds optional per-request overrides of the agent defaults.
+// Each field is validated against server-side bounds before it is applied.
+type AgentOverrides struct {
+	SystemMessage   *string  `json:"system_message,omitempty"`
+	GreetingMessage *string  `json:"greeting_message,omitempty"`
+	FailureMessage  *string  `json:"failure_message,omitempty"`
+	MaxHistory      *int     `json:"max_history,omitempty"`
+	MaxTokens       *int     `json:"max_tokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"top_p,omitempty"`
+	ASRLanguage     *string  `json:"asr_language,omitempty"`
+	IdleTimeout     *int     `json:"idle_timeout,omitempty"`
+	LLMProvider     *string  `json:"llm_provider,omitempty"`
 }
 
 // RemoveAgentRequest represents the request body for removing an AI agent
@@ -20,9 +44,33 @@ type TTSVendor string
 const (
 	TTSVendorMicrosoft  TTSVendor = "microsoft"
 	TTSVendorElevenLabs TTSVendor = "elevenlabs"
+	TTSVendorCartesia   TTSVendor = "cartesia"
+	TTSVendorOpenAI     TTSVendor = "openai"
+	TTSVendorGoogle     TTSVendor = "google"
+)
+
+// LLMProviderName identifies an LLM provider
+type LLMProviderName string
+
+const (
+	LLMProviderOpenAI    LLMProviderName = "openai"
+	LLMProviderAzure     LLMProviderName = "azure"
+	LLMProviderAnthropic LLMProviderName = "anthropic"
+	LLMProviderGemini    LLMProviderName = "gemini"
+	LLMProviderCustom    LLMProviderName = "custom"
 )
 
-// TTSConfig represents the text-to-speech configuration
+// ASRVendor represents the speech recognition vendor type
+type ASRVendor string
+
+const (
+	ASRVendorAres      ASRVendor = "ares"
+	ASRVendorDeepgram  ASRVendor = "deepgram"
+	ASRVendorMicrosoft ASRVendor = "microsoft"
+)
+
+// TTSConfig represents the text-to-speech configuration. Params holds the
+// vendor's typed params struct built by TTSVendorConfig.Params.
 type TTSConfig struct {
 	Vendor TTSVendor   `json:"vendor"`
 	Params interface{} `json:"params"`
@@ -49,52 +97,63 @@ type Properties struct {
 	AdvancedFeatures Features  `json:"advanced_features"`
 }
 
-// ASR represents the Automatic Speech Recognition configuration
+// ASR represents the Automatic Speech Recognition configuration. Params holds
+// the vendor's typed params struct built by ASRVendorConfig.Params, if any.
 type ASR struct {
-	Language string `json:"language"`
-	Task     string `json:"task"`
+	Language string      `json:"language"`
+	Task     string      `json:"task"`
+	Vendor   ASRVendor   `json:"vendor,omitempty"`
+	Params   interface{} `json:"params,omitempty"`
 }
 
 // LLM represents the Language Learning Model configuration
 type LLM struct {
-	URL              string          `json:"url"`
-	APIKey           string          `json:"api_key"`
-	SystemMessages   []SystemMessage `json:"system_messages"`
-	GreetingMessage  string          `json:"greeting_message"`
-	FailureMessage   string          `json:"failure_message"`
-	MaxHistory       int             `json:"max_history"`
-	Params           LLMParams       `json:"params"`
-	InputModalities  []string        `json:"input_modalities"`
-	OutputModalities []string        `json:"output_modalities"`
+	URL              string            `json:"url"`
+	APIKey           string            `json:"api_key"`
+	Style            string            `json:"style,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	SystemMessages   []SystemMessage   `json:"system_messages"`
+	GreetingMessage  string            `json:"greeting_message"`
+	FailureMessage   string            `json:"failure_message"`
+	MaxHistory       int               `json:"max_history"`
+	Params           LLMParams         `json:"params"`
+	InputModalities  []string          `json:"input_modalities"`
+	OutputModalities []string          `json:"output_modalities"`
 }
 
 // SystemMessage represents a system message in the conversation
 type SystemMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string `json:"role" yaml:"role"`
+	Content string `json:"content" yaml:"content"`
 }
 
-// LLMParams represents the parameters for the Language Learning Model
+// LLMParams represents the parameters for the Language Learning Model. The optional
+// params are OpenAI-style and converted for providers using another request style.
 type LLMParams struct {
-	Model       string  `json:"model"`
-	MaxTokens   int     `json:"max_tokens"`
-	Temperature float64 `json:"temperature"`
-	TopP        float64 `json:"top_p"`
+	Model            string                 `json:"model" yaml:"model"`
+	MaxTokens        int                    `json:"max_tokens" yaml:"max_tokens"`
+	Temperature      float64                `json:"temperature" yaml:"temperature"`
+	TopP             float64                `json:"top_p" yaml:"top_p"`
+	ResponseFormat   map[string]interface{} `json:"response_format,omitempty" yaml:"response_format,omitempty"`
+	Stop             []string               `json:"stop,omitempty" yaml:"stop,omitempty"`
+	StopSequences    []string               `json:"stop_sequences,omitempty" yaml:"-"`
+	PresencePenalty  *float64               `json:"presence_penalty,omitempty" yaml:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64               `json:"frequency_penalty,omitempty" yaml:"frequency_penalty,omitempty"`
 }
 
 // VAD represents the Voice Activity Detection configuration
 type VAD struct {
-	SilenceDurationMS   int     `json:"silence_duration_ms"`
-	SpeechDurationMS    int     `json:"speech_duration_ms"`
-	Threshold           float64 `json:"threshold"`
-	InterruptDurationMS int     `json:"interrupt_duration_ms"`
-	PrefixPaddingMS     int     `json:"prefix_padding_ms"`
+	SilenceDurationMS   int     `json:"silence_duration_ms" yaml:"silence_duration_ms"`
+	SpeechDurationMS    int     `json:"speech_duration_ms" yaml:"speech_duration_ms"`
+	Threshold           float64 `json:"threshold" yaml:"threshold"`
+	InterruptDurationMS int     `json:"interrupt_duration_ms" yaml:"interrupt_duration_ms"`
+	PrefixPaddingMS     int     `json:"prefix_padding_ms" yaml:"prefix_padding_ms"`
 }
 
 // Features represents advanced features configuration
 type Features struct {
-	EnableAIVAD bool `json:"enable_aivad"`
-	EnableBHVS  bool `json:"enable_bhvs"`
+	EnableAIVAD bool `json:"enable_aivad" yaml:"enable_aivad"`
+	EnableBHVS  bool `json:"enable_bhvs" yaml:"enable_bhvs"`
 }
 
 // InviteAgentResponse represents the response for an agent invitation
@@ -110,6 +169,60 @@ type RemoveAgentResponse struct {
 	AgentID string `json:"agent_id"`
 }
 
+// Speak priorities, deciding what happens when the agent is already speaking
+const (
+	SpeakPriorityInterrupt = "INTERRUPT"
+	SpeakPriorityAppend    = "APPEND"
+	SpeakPriorityIgnore    = "IGNORE"
+)
+
+// SpeakAgentRequest asks a running agent to say the text in its channel
+type SpeakAgentRequest struct {
+	Text          string `json:"text"`
+	Priority      string `json:"priority,omitempty"`
+	Interruptable *bool  `json:"interruptable,omitempty"`
+}
+
+// UpdateAgentRequest changes the settings of a running agent. RefreshToken pushes a
+// freshly generated RTC token, extending the agent's stay in the channel.
+type UpdateAgentRequest struct {
+	SystemMessages []SystemMessage `json:"system_messages,omitempty"`
+	MaxTokens      *int            `json:"max_tokens,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	TopP           *float64        `json:"top_p,omitempty"`
+	RefreshToken   bool            `json:"refresh_token,omitempty"`
+}
+
+// AgentCommandResponse represents the response for a command sent to a running agent
+type AgentCommandResponse struct {
+	Success bool   `json:"success"`
+	AgentID string `json:"agent_id"`
+}
+
+// AgoraUpdateRequest represents the request to update a running agent
+type AgoraUpdateRequest struct {
+	Properties AgoraUpdateProperties `json:"properties"`
+}
+
+// AgoraUpdateProperties holds the agent properties that can be updated while it runs
+type AgoraUpdateProperties struct {
+	Token string          `json:"token,omitempty"`
+	LLM   *AgoraUpdateLLM `json:"llm,omitempty"`
+}
+
+// AgoraUpdateLLM holds the LLM settings that can be updated while the agent runs
+type AgoraUpdateLLM struct {
+	SystemMessages []SystemMessage       `json:"system_messages,omitempty"`
+	Params         *AgoraUpdateLLMParams `json:"params,omitempty"`
+}
+
+// AgoraUpdateLLMParams holds the LLM params changed by an update
+type AgoraUpdateLLMParams struct {
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+}
+
 // ConvoAIConfig holds all configuration for the ConvoAI service
 type ConvoAIConfig struct {
 	// Agora Configuration
@@ -120,19 +233,74 @@ type ConvoAIConfig struct {
 	BaseURL        string
 	AgentUID       string
 
-	// LLM Configuration
-	LLMModel string
-	LLMURL   string
-	LLMToken string
+	// LLM Configuration, keyed by the registered providers that are configured
+	LLMProvider string
+	LLM         map[LLMProviderName]LLMProviderConfig
 
-	// TTS Configuration
-	TTSVendor     string
-	MicrosoftTTS  *MicrosoftTTSConfig
-	ElevenLabsTTS *ElevenLabsTTSConfig
+	// LLM Proxy Configuration. When LLMProxyURL is set, agents call this server's
+	// LLM proxy instead of the provider, so the provider key never leaves the server.
+	// The channel context routes require LLMProxyContextSecret, and are disabled without it.
+	LLMProxyURL           string
+	LLMProxyRateLimit     int
+	LLMProxyContextSecret string
+
+	// Retrieval Configuration. The retriever adds knowledge base snippets to the
+	// requests relayed by the LLM proxy.
+	RAGDocsDir    string
+	Retriever     Retriever
+	RetrievalTopK int
+
+	// Tool Configuration. Webhook tools and the tools of the MCP servers are offered
+	// to agents by the LLM proxy, along with the tools registered in code.
+	ToolsFile    string
+	WebhookTools []WebhookToolConfig
+	MCPServers   []MCPServerConfig
+
+	// Agora Webhook Configuration. Notifications from Agora are verified with the secret.
+	AgoraWebhookSecret string
+
+	// Outbound Webhook Configuration. Agent lifecycle events are sent to the subscriptions.
+	WebhooksFile         string
+	WebhookSubscriptions []WebhookSubscription
+	WebhookMaxAttempts   int
+
+	// Agent Watch Configuration. The agents this instance started are polled to notice
+	// them stopping without a remove request, e.g. on idle timeout; zero disables polling.
+	AgentPollInterval int
+
+	// Agent Token Configuration. The tokens of this instance's agents are refreshed
+	// before they expire, checking at this interval; zero disables refreshing.
+	AgentTokenRefreshInterval int
+
+	// Transcript Configuration. Transcripts are kept in memory within these limits;
+	// zero values use the defaults.
+	TranscriptMaxEntries     int
+	TranscriptMaxAgents      int
+	TranscriptRetentionHours int
+
+	// TTS Configuration, keyed by the registered vendors that are configured
+	TTSVendor string
+	TTS       map[TTSVendor]TTSVendorConfig
+
+	// ASR Configuration, keyed by the registered vendors that are configured
+	ASRVendor string
+	ASR       map[ASRVendor]ASRVendorConfig
 
 	// Modalities Configuration
 	InputModalities  string
 	OutputModalities string
+
+	// Agent Profile Configuration
+	ProfilesDir string
+	Profiles    map[string]*AgentProfile
+
+	// Session Store Configuration
+	SessionStore     string
+	SessionStorePath string
+
+	// Shutdown Configuration
+	ShutdownPolicy      string
+	ShutdownConcurrency int
 }
 
 // MicrosoftTTSConfig holds Microsoft TTS specific configuration
@@ -142,6 +310,8 @@ type MicrosoftTTSConfig struct {
 	VoiceName string `json:"voice_name"`
 	Rate      string `json:"rate"`
 	Volume    string `json:"volume"`
+	// Voices maps a locale to its voice in multilingual mode
+	Voices map[string]string `json:"voices,omitempty"`
 }
 
 // ElevenLabsTTSConfig holds ElevenLabs TTS specific configuration
@@ -150,3 +320,90 @@ type ElevenLabsTTSConfig struct {
 	VoiceID string `json:"voice_id"`
 	ModelID string `json:"model_id"`
 }
+
+// AgentSession holds the metadata this server keeps for an agent it invited
+type AgentSession struct {
+	AgentID          string   `json:"agent_id"`
+	Name             string   `json:"name"`
+	ChannelName      string   `json:"channel_name"`
+	RequesterID      string   `json:"requester_id"`
+	Profile          string   `json:"profile,omitempty"`
+	CreateTS         int64    `json:"create_ts"`
+	InputModalities  []string `json:"input_modalities"`
+	OutputModalities []string `json:"output_modalities"`
+	InstanceID       string   `json:"instance_id"`
+	ExpireTS         int64    `json:"expire_ts"`
+	HandedOff        bool     `json:"handed_off,omitempty"`
+}
+
+// ListAgentsRequest represents the query parameters for listing agents
+type ListAgentsRequest struct {
+	ChannelName string `form:"channel_name"`
+	RequesterID string `form:"requester_id"`
+}
+
+// AgentStatusResponse represents the status of a single agent, merging the
+// Agora status with the metadata recorded when the agent was invited
+type AgentStatusResponse struct {
+	AgentID          string   `json:"agent_id"`
+	Status           string   `json:"status"`
+	StartTS          int64    `json:"start_ts,omitempty"`
+	StopTS           int64    `json:"stop_ts,omitempty"`
+	ChannelName      string   `json:"channel_name,omitempty"`
+	RequesterID      string   `json:"requester_id,omitempty"`
+	Profile          string   `json:"profile,omitempty"`
+	CreateTS         int64    `json:"create_ts,omitempty"`
+	InputModalities  []string `json:"input_modalities,omitempty"`
+	OutputModalities []string `json:"output_modalities,omitempty"`
+}
+
+// ReconcileResult summarises a reconciliation of recorded sessions against the agents Agora reports
+type ReconcileResult struct {
+	Removed []string          `json:"removed"`
+	Adopted []string          `json:"adopted"`
+	Cleared []string          `json:"cleared"`
+	Kept    []string          `json:"kept"`
+	Failed  map[string]string `json:"failed"`
+}
+
+// ShutdownResult records what happened to one agent when the server shut down
+type ShutdownResult struct {
+	AgentID string
+	Action  string
+	Err     error
+}
+
+// ConfigVersionResponse represents the revision of the active configuration
+type ConfigVersionResponse struct {
+	Revision string   `json:"revision"`
+	LoadedAt int64    `json:"loaded_at"`
+	Profiles []string `json:"profiles"`
+}
+
+// ListAgentsResponse represents the response for listing agents
+type ListAgentsResponse struct {
+	Agents []AgentStatusResponse `json:"agents"`
+	Total  int                   `json:"total"`
+}
+
+// AgoraAgentStatus represents an agent as reported by the Agora query and list APIs
+type AgoraAgentStatus struct {
+	AgentID string `json:"agent_id"`
+	Status  string `json:"status"`
+	StartTS int64  `json:"start_ts"`
+	StopTS  int64  `json:"stop_ts,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// AgoraListResponse represents the response of the Agora list agents API
+type AgoraListResponse struct {
+	Data struct {
+		Count int                `json:"count"`
+		List  []AgoraAgentStatus `json:"list"`
+	} `json:"data"`
+	Meta struct {
+		Cursor string `json:"cursor"`
+		Total  int    `json:"total"`
+	} `json:"meta"`
+	Status string `json:"status"`
+}
@@ -1,17 +1,74 @@
 package convoai
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
+	"io"
+	"net/http"
+	"time"
 )
 
+// ErrAgentNotFound is returned when the Agora API does not know the requested agent
+var ErrAgentNotFound = errors.New("agent not found")
+
 func (s *ConvoAIService) getBasicAuth() string {
-	auth := fmt.Sprintf("%s:%s", s.config.CustomerID, s.config.CustomerSecret)
+	config := s.getConfig()
+	auth := fmt.Sprintf("%s:%s", config.CustomerID, config.CustomerSecret)
 	return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
 }
 
+// agoraRequest sends an authenticated request to the Agora Conversational AI API.
+// The path is relative to the project, e.g. "/agents/{agent_id}". When body is not nil
+// it is sent as JSON, and when out is not nil the JSON response is decoded into it.
+func (s *ConvoAIService) agoraRequest(method, path string, body interface{}, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %v", err)
+		}
+		reqBody = bytes.NewBuffer(jsonData)
+	}
+
+	config := s.getConfig()
+	url := fmt.Sprintf("%s/%s%s", config.BaseURL, config.AppID, path)
+	httpReq, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+
+	// Add headers
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", s.getBasicAuth())
+
+	// Send the request using a client with a timeout
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrAgentNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("agora request failed: status=%d, body=%s", resp.StatusCode, string(respBody))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
+
 // Helper function to check if the string is purely numeric (false) or contains any non-digit characters (true)
 func isStringUID(s string) bool {
 	// if s == "0" {
@@ -25,62 +82,6 @@ func isStringUID(s string) bool {
 	return false // Contains only digits
 }
 
-// getTTSConfig returns the appropriate TTS configuration based on the configured vendor
-func (s *ConvoAIService) getTTSConfig() (*TTSConfig, error) {
-	switch s.config.TTSVendor {
-	case string(TTSVendorMicrosoft):
-		if s.config.MicrosoftTTS == nil ||
-			s.config.MicrosoftTTS.Key == "" ||
-			s.config.MicrosoftTTS.Region == "" ||
-			s.config.MicrosoftTTS.VoiceName == "" ||
-			s.config.MicrosoftTTS.Rate == "" ||
-			s.config.MicrosoftTTS.Volume == "" {
-			return nil, fmt.Errorf("missing Microsoft TTS configuration")
-		}
-
-		// Convert rate and volume from string to float64
-		rate, err := strconv.ParseFloat(s.config.MicrosoftTTS.Rate, 64)
-		if err != nil {
-			return nil, fmt.Errorf("invalid rate value: %v", err)
-		}
-
-		volume, err := strconv.ParseFloat(s.config.MicrosoftTTS.Volume, 64)
-		if err != nil {
-			return nil, fmt.Errorf("invalid volume value: %v", err)
-		}
-
-		return &TTSConfig{
-			Vendor: TTSVendorMicrosoft,
-			Params: map[string]interface{}{
-				"key":        s.config.MicrosoftTTS.Key,
-				"region":     s.config.MicrosoftTTS.Region,
-				"voice_name": s.config.MicrosoftTTS.VoiceName,
-				"rate":       rate,
-				"volume":     volume,
-			},
-		}, nil
-
-	case string(TTSVendorElevenLabs):
-		if s.config.ElevenLabsTTS == nil ||
-			s.config.ElevenLabsTTS.Key == "" ||
-			s.config.ElevenLabsTTS.ModelID == "" ||
-			s.config.ElevenLabsTTS.VoiceID == "" {
-			return nil, fmt.Errorf("missing ElevenLabs TTS configuration")
-		}
-		return &TTSConfig{
-			Vendor: TTSVendorElevenLabs,
-			Params: map[string]interface{}{
-				"api_key":  s.config.ElevenLabsTTS.Key,
-				"model_id": s.config.ElevenLabsTTS.ModelID,
-				"voice_id": s.config.ElevenLabsTTS.VoiceID,
-			},
-		}, nil
-
-	default:
-		return nil, fmt.Errorf("unsupported TTS vendor: %s", s.config.TTSVendor)
-	}
-}
-
 // validateInviteRequest validates the invite agent request
 func (s *ConvoAIService) validateInviteRequest(req *InviteAgentRequest) error {
 	if req.RequesterID == "" {
@@ -96,6 +97,40 @@ func (s *ConvoAIService) validateInviteRequest(req *InviteAgentRequest) error {
 		return errors.New("channel_name length must be between 3 and 64 characters")
 	}
 
+	// Validate the profile exists
+	profile, err := s.getProfile(req.Profile)
+	if err != nil {
+		return err
+	}
+
+	// Validate the optional overrides against the server-side bounds
+	if err := validateOverrides(req.Overrides); err != nil {
+		return err
+	}
+
+	// Validate the selected LLM provider is configured and supports the profile's params,
+	// and that the LLM proxy, when enabled, can relay its requests
+	if req.Overrides != nil && req.Overrides.LLMProvider != nil {
+		config := s.getConfig()
+		endpoint, _, err := ResolveLLMConfig(config, profile, *req.Overrides.LLMProvider)
+		if err != nil {
+			return err
+		}
+		if config.LLMProxyURL != "" && endpoint.Style != LLMStyleOpenAI {
+			return fmt.Errorf("llm_provider %s uses the %s style, which the LLM proxy does not support", *req.Overrides.LLMProvider, endpoint.Style)
+		}
+	}
+
+	// Validate the languages, and that the profile's vendors can serve all of them
+	if err := validateMultilingual(req.Multilingual, req.Overrides); err != nil {
+		return err
+	}
+	if req.Multilingual != nil {
+		if _, err := ResolveMultilingualConfig(s.getConfig(), profile, req.Multilingual.Languages); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
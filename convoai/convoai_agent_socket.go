@@ -0,0 +1,247 @@
+package convoai
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Agent socket commands, sent by the client
+const (
+	AgentCommandInterrupt    = "interrupt"
+	AgentCommandSpeak        = "speak"
+	AgentCommandUpdatePrompt = "update_prompt"
+)
+
+// Agent socket messages, sent by the server
+const (
+	AgentMessageState      = "state"
+	AgentMessageTranscript = "transcript"
+	AgentMessageResult     = "result"
+)
+
+// Agent socket settings
+const (
+	maxAgentSocketMessageSize = 64 * 1024
+	agentSocketPongWait       = 60 * time.Second
+	agentSocketPingInterval   = agentSocketPongWait * 9 / 10
+	agentSocketWriteWait      = 10 * time.Second
+)
+
+// agentSocketUpgrader upgrades agent socket requests. Origins are already checked by the
+// CORS middleware, and clients authenticate with a token rather than cookies.
+var agentSocketUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// AgentSocketCommand is a command sent by the client over the agent socket. The fields
+// used depend on the command type; ID is echoed in the result.
+type AgentSocketCommand struct {
+	ID             string          `json:"id,omitempty"`
+	Type           string          `json:"type"`
+	Text           string          `json:"text,omitempty"`
+	Priority       string          `json:"priority,omitempty"`
+	Interruptable  *bool           `json:"interruptable,omitempty"`
+	SystemMessages []SystemMessage `json:"system_messages,omitempty"`
+}
+
+// AgentSocketMessage is a message sent by the server over the agent socket: a state
+// change, a transcript entry, or the result of a command
+type AgentSocketMessage struct {
+	Type    string           `json:"type"`
+	Event   *AgentEvent      `json:"event,omitempty"`
+	Entry   *TranscriptEntry `json:"entry,omitempty"`
+	ID      string           `json:"id,omitempty"`
+	Command string           `json:"command,omitempty"`
+	Success bool             `json:"success,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// AgentSocket upgrades the request to a WebSocket over which the client controls a running
+// agent and receives its state changes and transcript. The client authenticates with an
+// RTC token for the agent's channel, issued by the token service, in the token query
+// parameter or as a bearer token.
+func (s *ConvoAIService) AgentSocket(c *gin.Context) {
+	agentID := c.Param("agent_id")
+	token := c.Query("token")
+	if token == "" {
+		token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	}
+	channelName, _, err := s.tokenService.VerifyRtcToken(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, ok := s.agentStates.get(agentID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrAgentNotFound.Error()})
+		return
+	}
+	if current.ChannelName != channelName {
+		c.JSON(http.StatusForbidden, gin.H{"error": "token is not valid for the agent's channel"})
+		return
+	}
+
+	// Subscribe before upgrading, so no change is missed after the current state is sent
+	events, unsubscribe := s.events.Subscribe(defaultEventBuffer)
+	defer unsubscribe()
+
+	conn, err := agentSocketUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already replied
+		log.Printf("Agent socket upgrade failed for agent %s: %v", agentID, err)
+		return
+	}
+	defer conn.Close()
+
+	results := make(chan AgentSocketMessage, 1)
+	done := make(chan struct{})
+	go s.readAgentSocket(conn, agentID, results, done)
+
+	replay, sent, _ := s.agentStates.replay(agentID, 0)
+	if err := writeAgentStates(conn, replay); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(agentSocketPingInterval)
+	defer ping.Stop()
+	for {
+		var message AgentSocketMessage
+		select {
+		case <-done:
+			return
+		case <-s.streamsDone:
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"), time.Now().Add(agentSocketWriteWait))
+			return
+		case result := <-results:
+			message = result
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.AgentID != agentID {
+				continue
+			}
+			if event.Sequence != 0 {
+				if event.Sequence <= sent {
+					continue
+				}
+				// Changes dropped while the subscription was full are sent from the history
+				if err := writeAgentStates(conn, s.agentStates.missed(agentID, sent, event.Sequence)); err != nil {
+					return
+				}
+				sent = event.Sequence
+				message = AgentSocketMessage{Type: AgentMessageState, Event: &event}
+			} else if entry, ok := event.Data.(TranscriptEntry); ok {
+				message = AgentSocketMessage{Type: AgentMessageTranscript, Entry: &entry}
+			} else {
+				continue
+			}
+		case <-ping.C:
+			// Including the last changes, which no later event reveals as missed
+			missed := s.agentStates.missed(agentID, sent, 0)
+			if err := writeAgentStates(conn, missed); err != nil {
+				return
+			}
+			if len(missed) > 0 {
+				sent = missed[len(missed)-1].Sequence
+			}
+			conn.SetWriteDeadline(time.Now().Add(agentSocketWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+			continue
+		}
+
+		if err := writeAgentSocket(conn, message); err != nil {
+			return
+		}
+	}
+}
+
+// readAgentSocket runs the commands read from the socket until it is closed, sending
+// their results to the writer
+func (s *ConvoAIService) readAgentSocket(conn *websocket.Conn, agentID string, results chan<- AgentSocketMessage, done chan<- struct{}) {
+	defer close(done)
+	conn.SetReadLimit(maxAgentSocketMessageSize)
+	conn.SetReadDeadline(time.Now().Add(agentSocketPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(agentSocketPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var result AgentSocketMessage
+		var command AgentSocketCommand
+		if err := json.Unmarshal(data, &command); err != nil {
+			result = AgentSocketMessage{Type: AgentMessageResult, Error: fmt.Sprintf("invalid command: %v", err)}
+		} else {
+			result = AgentSocketMessage{Type: AgentMessageResult, ID: command.ID, Command: command.Type, Success: true}
+			if err := s.runAgentCommand(agentID, command); err != nil {
+				result.Success = false
+				result.Error = err.Error()
+			}
+		}
+
+		select {
+		case results <- result:
+		case <-time.After(agentSocketWriteWait):
+			return
+		}
+	}
+}
+
+// runAgentCommand validates and runs a command received over the agent socket
+func (s *ConvoAIService) runAgentCommand(agentID string, command AgentSocketCommand) error {
+	switch command.Type {
+	case AgentCommandInterrupt:
+		_, err := s.HandleInterruptAgent(agentID)
+		return err
+
+	case AgentCommandSpeak:
+		req := SpeakAgentRequest{Text: command.Text, Priority: command.Priority, Interruptable: command.Interruptable}
+		if err := validateSpeakRequest(&req); err != nil {
+			return err
+		}
+		_, err := s.HandleSpeakAgent(agentID, req)
+		return err
+
+	case AgentCommandUpdatePrompt:
+		req := UpdateAgentRequest{SystemMessages: command.SystemMessages}
+		if err := validateUpdateRequest(&req); err != nil {
+			return err
+		}
+		_, err := s.HandleUpdateAgent(agentID, req)
+		return err
+
+	default:
+		return fmt.Errorf("unknown command type: %q", command.Type)
+	}
+}
+
+// writeAgentStates sends state changes as state messages
+func writeAgentStates(conn *websocket.Conn, events []AgentEvent) error {
+	for i := range events {
+		if err := writeAgentSocket(conn, AgentSocketMessage{Type: AgentMessageState, Event: &events[i]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAgentSocket sends a message, giving up on clients that stop reading
+func writeAgentSocket(conn *websocket.Conn, message AgentSocketMessage) error {
+	conn.SetWriteDeadline(time.Now().Add(agentSocketWriteWait))
+	return conn.WriteJSON(message)
+}
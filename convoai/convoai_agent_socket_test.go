@@ -0,0 +1,199 @@
+package convoai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AgoraIO-Community/convo-ai-go-server/token_service"
+	"github.com/gorilla/websocket"
+)
+
+// dialAgentSocket connects to the agent socket with the token
+func dialAgentSocket(t *testing.T, server *httptest.Server, agentID, token string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	endpoint := "ws" + strings.TrimPrefix(server.URL, "http") + "/agent/" + agentID + "/ws?token=" + url.QueryEscape(token)
+	conn, resp, err := websocket.DefaultDialer.Dial(endpoint, nil)
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+// readSocketMessage reads the next message from the agent socket
+func readSocketMessage(t *testing.T, conn *websocket.Conn) AgentSocketMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message AgentSocketMessage
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatalf("failed to read socket message: %v", err)
+	}
+	return message
+}
+
+// channelToken returns an RTC token for the channel from the service's token service
+func channelToken(t *testing.T, service *ConvoAIService, channelName string) string {
+	t.Helper()
+	token, err := service.tokenService.GenRtcToken(token_service.TokenRequest{TokenType: "rtc", Channel: channelName, Uid: "1234"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAgentSocketAuthentication(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	server := httptest.NewServer(newTestRouter(service))
+	t.Cleanup(server.Close)
+	service.setAgentState(AgentEventJoined, "AGENT0001", "test-channel", AgentStateRunning, "", nil)
+
+	tests := []struct {
+		name       string
+		agentID    string
+		token      string
+		wantStatus int
+	}{
+		{"missing token", "AGENT0001", "", http.StatusUnauthorized},
+		{"invalid token", "AGENT0001", "007invalid", http.StatusUnauthorized},
+		{"token for another channel", "AGENT0001", channelToken(t, service, "other-channel"), http.StatusForbidden},
+		{"unknown agent", "UNKNOWN", channelToken(t, service, "test-channel"), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp, err := dialAgentSocket(t, server, tt.agentID, tt.token)
+			if err == nil {
+				t.Fatal("expected the connection to be refused")
+			}
+			if resp == nil || resp.StatusCode != tt.wantStatus {
+				t.Errorf("response = %v, want status %d", resp, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAgentSocket(t *testing.T) {
+	agora := newFakeAgora(t)
+	llm := newFakeLLM(t)
+	service := newTestService(t, agora, withLLMProxy(llm, 0))
+	router := newTestRouter(service)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	agentLLM := inviteProxiedAgent(t, router, agora)
+	conn, _, err := dialAgentSocket(t, server, "AGENT0001", channelToken(t, service, "test-channel"))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+
+	// The socket starts with the current state, then streams changes
+	if message := readSocketMessage(t, conn); message.Type != AgentMessageState || message.Event.State != AgentStateStarting {
+		t.Errorf("first message = %+v", message)
+	}
+	service.setAgentState(AgentEventJoined, "AGENT0001", "", AgentStateRunning, "", nil)
+	if message := readSocketMessage(t, conn); message.Type != AgentMessageState || message.Event.Type != AgentEventJoined {
+		t.Errorf("joined message = %+v", message)
+	}
+
+	// Turns relayed by the LLM proxy are streamed as transcript entries
+	body := `{"model": "gpt-4o-mini", "messages": [{"role": "user", "content": "Where is my order?"}]}`
+	if rr := doProxy(router, agentLLM.APIKey, body); rr.Code != http.StatusOK {
+		t.Fatalf("proxy returned status %d", rr.Code)
+	}
+	for _, want := range []TranscriptEntry{
+		{Role: TranscriptRoleUser, Text: "Where is my order?"},
+		{Role: TranscriptRoleAssistant, Text: "Your order has shipped."},
+	} {
+		message := readSocketMessage(t, conn)
+		if message.Type != AgentMessageTranscript || message.Entry.Role != want.Role || message.Entry.Text != want.Text {
+			t.Errorf("transcript message = %+v, want %+v", message, want)
+		}
+	}
+	if message := readSocketMessage(t, conn); message.Type != AgentMessageState || message.Event.State != AgentStateSpeaking {
+		t.Errorf("speaking message = %+v", message)
+	}
+
+	// Commands are run against the agent, and their results echo the command ID
+	commands := []string{
+		`{"id": "1", "type": "speak", "text": "Your order is ready", "priority": "APPEND", "interruptable": false}`,
+		`{"id": "2", "type": "interrupt"}`,
+		`{"id": "3", "type": "update_prompt", "system_messages": [{"role": "system", "content": "Be brief."}]}`,
+	}
+	for i, command := range commands {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(command)); err != nil {
+			t.Fatal(err)
+		}
+		message := readSocketMessage(t, conn)
+		if command == commands[1] {
+			// Interrupting a speaking agent makes it listen again
+			if message.Type != AgentMessageState || message.Event.Type != AgentEventListening {
+				t.Errorf("interrupted message = %+v", message)
+			}
+			message = readSocketMessage(t, conn)
+		}
+		if message.Type != AgentMessageResult || !message.Success || message.ID != string(rune('1'+i)) {
+			t.Errorf("result of %s = %+v", command, message)
+		}
+	}
+
+	sent := agora.agentCommands()
+	if len(sent) != 3 {
+		t.Fatalf("agora received %d commands, want 3", len(sent))
+	}
+	if sent[0].Action != "speak" || sent[0].Body["text"] != "Your order is ready" || sent[0].Body["priority"] != SpeakPriorityAppend || sent[0].Body["interruptable"] != false {
+		t.Errorf("speak command = %+v", sent[0])
+	}
+	if sent[1].Action != "interrupt" {
+		t.Errorf("interrupt command = %+v", sent[1])
+	}
+	properties, _ := sent[2].Body["properties"].(map[string]interface{})
+	llmProperties, _ := properties["llm"].(map[string]interface{})
+	if messages, _ := llmProperties["system_messages"].([]interface{}); sent[2].Action != "update" || len(messages) != 1 {
+		t.Errorf("update command = %+v", sent[2])
+	}
+
+	// Invalid commands are rejected without reaching Agora
+	for _, command := range []string{
+		`not json`,
+		`{"id": "4", "type": "dance"}`,
+		`{"id": "5", "type": "speak", "text": ""}`,
+		`{"id": "6", "type": "speak", "text": "Hi", "priority": "LOUD"}`,
+		`{"id": "7", "type": "update_prompt", "system_messages": [{"role": "user", "content": "Hi"}]}`,
+	} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(command)); err != nil {
+			t.Fatal(err)
+		}
+		if message := readSocketMessage(t, conn); message.Type != AgentMessageResult || message.Success || message.Error == "" {
+			t.Errorf("result of %s = %+v", command, message)
+		}
+	}
+	if sent := agora.agentCommands(); len(sent) != 3 {
+		t.Errorf("agora received %d commands after invalid ones, want 3", len(sent))
+	}
+}
+
+func TestAgentSocketRecoversDroppedChanges(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	server := httptest.NewServer(newTestRouter(service))
+	t.Cleanup(server.Close)
+
+	service.setAgentState(AgentEventInvited, "AGENT0001", "test-channel", AgentStateStarting, "", nil)
+	conn, _, err := dialAgentSocket(t, server, "AGENT0001", channelToken(t, service, "test-channel"))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	readSocketMessage(t, conn)
+
+	// A change dropped for a full subscription is sent before the next published one
+	service.agentStates.set(AgentEvent{Type: AgentEventJoined, AgentID: "AGENT0001", State: AgentStateRunning}, time.Now(), func(AgentEvent) {})
+	service.setAgentState(AgentEventSpeaking, "AGENT0001", "", AgentStateSpeaking, "", nil)
+	for _, want := range []string{AgentStateRunning, AgentStateSpeaking} {
+		if message := readSocketMessage(t, conn); message.Type != AgentMessageState || message.Event.State != want {
+			t.Errorf("message = %+v, want state %s", message, want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package convoai
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Agora notification event types for Conversational AI agents
+const (
+	AgoraEventAgentJoined  = 101
+	AgoraEventAgentLeft    = 102
+	AgoraEventAgentHistory = 103
+	AgoraEventAgentError   = 110
+	AgoraEventAgentMetrics = 111
+)
+
+// Agora webhook limits. Notifications sent further than maxAgoraNoticeAge from the
+// server's clock are rejected, so a captured notification can't be replayed once its
+// ID has left the remembered ones.
+const (
+	maxAgoraWebhookSize       = 1 << 20
+	maxAgoraNoticesRemembered = 1000
+	maxAgoraNoticeAge         = 10 * time.Minute
+)
+
+// AgoraNotification is an event callback sent by the Agora notification service
+type AgoraNotification struct {
+	NoticeID  string          `json:"noticeId"`
+	ProductID int             `json:"productId"`
+	EventType int             `json:"eventType"`
+	NotifyMs  int64           `json:"notifyMs"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// AgoraAgentJoinedPayload is the payload of an agent joined event
+type AgoraAgentJoinedPayload struct {
+	AgentID string `json:"agent_id"`
+	StartTS int64  `json:"start_ts"`
+	Channel string `json:"channel"`
+}
+
+// AgoraAgentLeftPayload is the payload of an agent left event. Status is STOPPED, or
+// FAILED when the agent exited with an error.
+type AgoraAgentLeftPayload struct {
+	AgentID string `json:"agent_id"`
+	StartTS int64  `json:"start_ts"`
+	StopTS  int64  `json:"stop_ts"`
+	Channel string `json:"channel"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// AgoraAgentHistoryPayload is the payload of an agent history event, sent when the agent stops
+type AgoraAgentHistoryPayload struct {
+	AgentID  string `json:"agent_id"`
+	StartTS  int64  `json:"start_ts"`
+	StopTS   int64  `json:"stop_ts"`
+	Channel  string `json:"channel"`
+	Contents []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"contents"`
+}
+
+// AgoraAgentErrorPayload is the payload of an agent error event, reporting a failing module
+type AgoraAgentErrorPayload struct {
+	AgentID   string `json:"agent_id"`
+	Channel   string `json:"channel"`
+	Module    string `json:"module"`
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// AgoraAgentMetricsPayload is the payload of an agent metrics event
+type AgoraAgentMetricsPayload struct {
+	AgentID string                 `json:"agent_id"`
+	StartTS int64                  `json:"start_ts"`
+	StopTS  int64                  `json:"stop_ts"`
+	Channel string                 `json:"channel"`
+	Metrics map[string]interface{} `json:"metrics"`
+}
+
+// ParseAgoraNotification decodes a notification and its typed payload. The payload is
+// nil for event types the server doesn't handle.
+func ParseAgoraNotification(data []byte) (*AgoraNotification, interface{}, error) {
+	var notification AgoraNotification
+	if err := json.Unmarshal(data, &notification); err != nil {
+		return nil, nil, fmt.Errorf("invalid notification: %v", err)
+	}
+
+	var payload interface{}
+	switch notification.EventType {
+	case AgoraEventAgentJoined:
+		payload = &AgoraAgentJoinedPayload{}
+	case AgoraEventAgentLeft:
+		payload = &AgoraAgentLeftPayload{}
+	case AgoraEventAgentHistory:
+		payload = &AgoraAgentHistoryPayload{}
+	case AgoraEventAgentError:
+		payload = &AgoraAgentErrorPayload{}
+	case AgoraEventAgentMetrics:
+		payload = &AgoraAgentMetricsPayload{}
+	default:
+		return &notification, nil, nil
+	}
+	if err := json.Unmarshal(notification.Payload, payload); err != nil {
+		return nil, nil, fmt.Errorf("invalid payload for event type %d: %v", notification.EventType, err)
+	}
+	return &notification, payload, nil
+}
+
+// verifyAgoraSignature checks the request body against the Agora-Signature-V2 header,
+// an HMAC-SHA256 of the body, or else the older Agora-Signature header, an HMAC-SHA1
+func verifyAgoraSignature(secret string, body []byte, signatureV2, signatureV1 string) bool {
+	sign := func(newHash func() hash.Hash) string {
+		mac := hmac.New(newHash, []byte(secret))
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	if signatureV2 != "" {
+		return hmac.Equal([]byte(signatureV2), []byte(sign(sha256.New)))
+	}
+	if signatureV1 != "" {
+		return hmac.Equal([]byte(signatureV1), []byte(sign(sha1.New)))
+	}
+	return false
+}
+
+// noticeSet remembers the most recent notification IDs, as Agora retries notifications
+// that weren't acknowledged in time
+type noticeSet struct {
+	mu    sync.Mutex
+	seen  map[string]bool
+	order []string
+}
+
+// add records the ID and reports whether it was new
+func (n *noticeSet) add(id string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.seen == nil {
+		n.seen = make(map[string]bool)
+	}
+	if n.seen[id] {
+		return false
+	}
+	n.seen[id] = true
+	n.order = append(n.order, id)
+	if len(n.order) > maxAgoraNoticesRemembered {
+		delete(n.seen, n.order[0])
+		n.order = n.order[1:]
+	}
+	return true
+}
+
+// AgoraWebhook handles an event callback from Agora, verified with the webhook secret
+func (s *ConvoAIService) AgoraWebhook(c *gin.Context) {
+	config := s.getConfig()
+	if config.AgoraWebhookSecret == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Agora webhooks are not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxAgoraWebhookSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+	if !verifyAgoraSignature(config.AgoraWebhookSecret, body, c.GetHeader("Agora-Signature-V2"), c.GetHeader("Agora-Signature")) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+		return
+	}
+
+	notification, payload, err := ParseAgoraNotification(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if age := time.Since(time.UnixMilli(notification.NotifyMs)); age > maxAgoraNoticeAge || age < -maxAgoraNoticeAge {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notification timestamp is outside the accepted window"})
+		return
+	}
+	if notification.NoticeID == "" || s.agoraNotices.add(notification.NoticeID) {
+		if err := s.HandleAgoraNotification(payload); err != nil {
+			log.Printf("Warning: failed to handle Agora notification %s: %v", notification.NoticeID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// HandleAgoraNotification records the agent state reported by a notification payload and
+// publishes the matching event. Payloads of unhandled event types are ignored.
+func (s *ConvoAIService) HandleAgoraNotification(payload interface{}) error {
+	switch p := payload.(type) {
+	case *AgoraAgentJoinedPayload:
+		if p.AgentID == "" {
+			return errors.New("agent joined event without an agent ID")
+		}
+		// A late or retried notification doesn't bring back an agent that has ended
+		if current, ok := s.agentStates.get(p.AgentID); ok && isAgentEnded(current.State) {
+			return nil
+		}
+		s.setAgentState(AgentEventJoined, p.AgentID, p.Channel, AgentStateRunning, "", p)
+
+	case *AgoraAgentLeftPayload:
+		if p.AgentID == "" {
+			return errors.New("agent left event without an agent ID")
+		}
+		// An agent removed through this server has already been marked as stopped
+		if current, ok := s.agentStates.get(p.AgentID); ok && isAgentEnded(current.State) {
+			return nil
+		}
+		if p.Status == AgentStateFailed {
+			s.setAgentState(AgentEventFailed, p.AgentID, p.Channel, AgentStateFailed, p.Message, p)
+		} else {
+			s.setAgentState(AgentEventLeft, p.AgentID, p.Channel, AgentStateStopped, p.Message, p)
+		}
+		if err := s.sessions.Delete(p.AgentID); err != nil {
+			return fmt.Errorf("failed to delete session for agent %s: %v", p.AgentID, err)
+		}
+
+	case *AgoraAgentHistoryPayload:
+		entries := make([]TranscriptEntry, 0, len(p.Contents))
+		for _, content := range p.Contents {
+			entries = append(entries, TranscriptEntry{Role: content.Role, Text: content.Content, Source: TranscriptSourceAgora})
+		}
+		s.keepAgoraHistory(p.AgentID, entries)
+		s.publishAgentEvent(AgentEventHistory, p.AgentID, p.Channel, "", p)
+
+	case *AgoraAgentErrorPayload:
+		s.publishAgentEvent(AgentEventError, p.AgentID, p.Channel, fmt.Sprintf("%s error %d: %s", p.Module, p.Code, p.Message), p)
+
+	case *AgoraAgentMetricsPayload:
+		s.publishAgentEvent(AgentEventMetrics, p.AgentID, p.Channel, "", p)
+	}
+	return nil
+}
+
+// isAgentEnded reports whether the state is final
+func isAgentEnded(state string) bool {
+	return state == AgentStateStopped || state == AgentStateFailed
+}
@@ -0,0 +1,220 @@
+package convoai
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+// signAgora returns the Agora-Signature-V2 header value for the body
+func signAgora(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// postAgoraWebhook sends a notification signed with the given secret
+func postAgoraWebhook(router *gin.Engine, secret, body string) int {
+	req := httptest.NewRequest("POST", "/webhooks/agora", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Agora-Signature-V2", signAgora(secret, body))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+// agoraNotifyMs returns the notifyMs field of a notification sent now
+func agoraNotifyMs() string {
+	return `"notifyMs": ` + strconv.FormatInt(time.Now().UnixMilli(), 10)
+}
+
+// nextEvent waits for the next event published on the bus
+func nextEvent(t *testing.T, events <-chan AgentEvent) AgentEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an agent event")
+		return AgentEvent{}
+	}
+}
+
+// withWebhookSecret accepts Agora webhooks signed with the secret
+func withWebhookSecret(secret string) func(*ConvoAIConfig) {
+	return func(config *ConvoAIConfig) { config.AgoraWebhookSecret = secret }
+}
+
+func TestAgoraWebhook(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora, withWebhookSecret(testWebhookSecret))
+	router := newTestRouter(service)
+	events, unsubscribe := service.SubscribeEvents(10)
+	defer unsubscribe()
+
+	// The invite reports the status Agora returned, rather than assuming the agent runs
+	var invited InviteAgentResponse
+	if status := doJSON(t, router, "POST", "/agent/invite", `{"requester_id": "user-1", "channel_name": "test-channel"}`, &invited); status != http.StatusOK {
+		t.Fatalf("invite returned status %d", status)
+	}
+	if invited.Status != AgentStateStarting {
+		t.Errorf("invite status = %q, want %q", invited.Status, AgentStateStarting)
+	}
+	if event := nextEvent(t, events); event.Type != AgentEventInvited || event.ChannelName != "test-channel" {
+		t.Errorf("invite event = %+v", event)
+	}
+
+	joined := `{"noticeId": "n-1", "productId": 17, "eventType": 101, ` + agoraNotifyMs() + `,
+		"payload": {"agent_id": "AGENT0001", "start_ts": 1700000000, "channel": "test-channel"}}`
+	if status := postAgoraWebhook(router, testWebhookSecret, joined); status != http.StatusOK {
+		t.Fatalf("joined webhook returned status %d", status)
+	}
+	event := nextEvent(t, events)
+	if event.Type != AgentEventJoined || event.State != AgentStateRunning {
+		t.Errorf("joined event = %+v", event)
+	}
+	if payload, ok := event.Data.(*AgoraAgentJoinedPayload); !ok || payload.StartTS != 1700000000 {
+		t.Errorf("joined event data = %#v", event.Data)
+	}
+
+	// Retried notifications are acknowledged but not published again
+	if status := postAgoraWebhook(router, testWebhookSecret, joined); status != http.StatusOK {
+		t.Errorf("retried webhook returned status %d", status)
+	}
+
+	failed := `{"noticeId": "n-2", "eventType": 110, ` + agoraNotifyMs() + `,
+		"payload": {"agent_id": "AGENT0001", "channel": "test-channel", "module": "tts", "code": 401, "message": "invalid key"}}`
+	postAgoraWebhook(router, testWebhookSecret, failed)
+	event = nextEvent(t, events)
+	if event.Type != AgentEventError || event.Reason != "tts error 401: invalid key" || event.State != AgentStateRunning {
+		t.Errorf("error event = %+v", event)
+	}
+
+	left := `{"noticeId": "n-3", "eventType": 102, ` + agoraNotifyMs() + `,
+		"payload": {"agent_id": "AGENT0001", "channel": "test-channel", "status": "FAILED", "message": "tts failure"}}`
+	postAgoraWebhook(router, testWebhookSecret, left)
+	event = nextEvent(t, events)
+	if event.Type != AgentEventFailed || event.State != AgentStateFailed || event.Reason != "tts failure" {
+		t.Errorf("left event = %+v", event)
+	}
+	if _, ok, _ := service.sessions.Get("AGENT0001"); ok {
+		t.Errorf("session of the agent that left was kept")
+	}
+
+	// A late joined notification doesn't bring the agent back
+	rejoined := `{"noticeId": "n-4", "eventType": 101, ` + agoraNotifyMs() + `,
+		"payload": {"agent_id": "AGENT0001", "channel": "test-channel"}}`
+	if status := postAgoraWebhook(router, testWebhookSecret, rejoined); status != http.StatusOK {
+		t.Errorf("late joined webhook returned status %d", status)
+	}
+	if current, _ := service.agentStates.get("AGENT0001"); current.State != AgentStateFailed {
+		t.Errorf("state after a late joined notification = %+v", current)
+	}
+
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestAgoraWebhookRejected(t *testing.T) {
+	body := `{"noticeId": "n-1", "eventType": 101, ` + agoraNotifyMs() + `, "payload": {"agent_id": "AGENT0001"}}`
+	stale := strconv.FormatInt(time.Now().Add(-maxAgoraNoticeAge-time.Minute).UnixMilli(), 10)
+
+	tests := []struct {
+		name       string
+		secret     string
+		signWith   string
+		body       string
+		wantStatus int
+	}{
+		{name: "Not configured", secret: "", signWith: "", body: body, wantStatus: http.StatusServiceUnavailable},
+		{name: "Wrong secret", secret: testWebhookSecret, signWith: "other-secret", body: body, wantStatus: http.StatusUnauthorized},
+		{name: "Invalid payload", secret: testWebhookSecret, signWith: testWebhookSecret, body: `{"eventType": 101, "payload": []}`, wantStatus: http.StatusBadRequest},
+		{name: "Unhandled event type", secret: testWebhookSecret, signWith: testWebhookSecret, body: `{"eventType": 999, ` + agoraNotifyMs() + `, "payload": {}}`, wantStatus: http.StatusOK},
+		{name: "Stale notification", secret: testWebhookSecret, signWith: testWebhookSecret, body: `{"noticeId": "n-1", "eventType": 101, "notifyMs": ` + stale + `, "payload": {"agent_id": "AGENT0001"}}`, wantStatus: http.StatusBadRequest},
+		{name: "Missing timestamp", secret: testWebhookSecret, signWith: testWebhookSecret, body: `{"noticeId": "n-1", "eventType": 101, "payload": {"agent_id": "AGENT0001"}}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTestService(t, newFakeAgora(t), withWebhookSecret(tt.secret))
+
+			if status := postAgoraWebhook(newTestRouter(service), tt.signWith, tt.body); status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestVerifyAgoraSignature(t *testing.T) {
+	body := []byte(`{"eventType": 101}`)
+	mac := hmac.New(sha1.New, []byte(testWebhookSecret))
+	mac.Write(body)
+	signatureV1 := hex.EncodeToString(mac.Sum(nil))
+	signatureV2 := signAgora(testWebhookSecret, string(body))
+
+	tests := []struct {
+		name        string
+		signatureV2 string
+		signatureV1 string
+		want        bool
+	}{
+		{name: "V2", signatureV2: signatureV2, want: true},
+		{name: "V1", signatureV1: signatureV1, want: true},
+		{name: "Wrong V2 is not rescued by V1", signatureV2: "00", signatureV1: signatureV1, want: false},
+		{name: "Unsigned", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyAgoraSignature(testWebhookSecret, body, tt.signatureV2, tt.signatureV1); got != tt.want {
+				t.Errorf("verifyAgoraSignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	first, unsubscribeFirst := bus.Subscribe(1)
+	second, unsubscribeSecond := bus.Subscribe(1)
+	defer unsubscribeSecond()
+
+	bus.Publish(AgentEvent{Type: AgentEventJoined, AgentID: "a"})
+	if event := nextEvent(t, first); event.AgentID != "a" {
+		t.Errorf("first subscriber got %+v", event)
+	}
+
+	// A full subscriber misses events rather than blocking the publisher
+	bus.Publish(AgentEvent{Type: AgentEventLeft, AgentID: "b"})
+	if event := nextEvent(t, second); event.AgentID != "a" {
+		t.Errorf("second subscriber got %+v", event)
+	}
+	select {
+	case event := <-second:
+		t.Errorf("full subscriber got %+v", event)
+	default:
+	}
+
+	unsubscribeFirst()
+	if event, ok := <-first; ok && event.AgentID != "b" {
+		t.Errorf("unsubscribed channel got %+v", event)
+	}
+	if _, ok := <-first; ok {
+		t.Errorf("channel was not closed on unsubscribe")
+	}
+	bus.Publish(AgentEvent{Type: AgentEventJoined, AgentID: "c"})
+}
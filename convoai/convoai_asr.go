@@ -0,0 +1,136 @@
+package convoai
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultASRVendor is used when ASR_VENDOR is not set
+const DefaultASRVendor = ASRVendorAres
+
+// ASRProvider describes a speech recognition vendor supported by the Agora engine.
+// Like TTS vendors, ASR vendors register themselves with RegisterASRProvider.
+type ASRProvider interface {
+	// Vendor returns the name used in ASR_VENDOR and in the Agora request
+	Vendor() ASRVendor
+	// LoadEnv reads the vendor configuration from the environment, returning nil
+	// when the vendor isn't configured
+	LoadEnv(getenv func(string) string) ASRVendorConfig
+	// NewConfig returns an empty configuration to decode profile params into
+	NewConfig() ASRVendorConfig
+}
+
+// ASRVendorConfig is the configuration of a single ASR vendor
+type ASRVendorConfig interface {
+	// Validate reports missing or invalid settings
+	Validate() error
+	// Params builds the vendor params sent to the Agora engine for the given
+	// language. It validates the configuration first. Vendors that need no
+	// params return nil.
+	Params(language string) (interface{}, error)
+}
+
+// asrProviders holds the registered ASR vendors
+var asrProviders = struct {
+	sync.RWMutex
+	byVendor map[ASRVendor]ASRProvider
+}{byVendor: make(map[ASRVendor]ASRProvider)}
+
+// RegisterASRProvider makes an ASR vendor available, replacing any provider
+// previously registered for the same vendor
+func RegisterASRProvider(provider ASRProvider) {
+	asrProviders.Lock()
+	defer asrProviders.Unlock()
+	asrProviders.byVendor[provider.Vendor()] = provider
+}
+
+// GetASRProvider returns the provider registered for the vendor
+func GetASRProvider(vendor ASRVendor) (ASRProvider, bool) {
+	asrProviders.RLock()
+	defer asrProviders.RUnlock()
+	provider, ok := asrProviders.byVendor[vendor]
+	return provider, ok
+}
+
+// ASRProviders returns the registered providers, sorted by vendor name
+func ASRProviders() []ASRProvider {
+	asrProviders.RLock()
+	defer asrProviders.RUnlock()
+
+	providers := make([]ASRProvider, 0, len(asrProviders.byVendor))
+	for _, provider := range asrProviders.byVendor {
+		providers = append(providers, provider)
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Vendor() < providers[j].Vendor() })
+	return providers
+}
+
+// LoadASRConfigs reads the configuration of every registered vendor from the environment
+func LoadASRConfigs(getenv func(string) string) map[ASRVendor]ASRVendorConfig {
+	configs := make(map[ASRVendor]ASRVendorConfig)
+	for _, provider := range ASRProviders() {
+		if config := provider.LoadEnv(getenv); config != nil {
+			configs[provider.Vendor()] = config
+		}
+	}
+	return configs
+}
+
+// ResolveASRConfig returns the ASR configuration for a profile in the given language.
+// The profile may select a different vendor than ASR_VENDOR, and its params are laid
+// over that vendor's configuration.
+func ResolveASRConfig(config *ConvoAIConfig, profile *AgentProfile, language string) (*ASR, error) {
+	if language == "" {
+		return nil, fmt.Errorf("missing ASR language")
+	}
+
+	vendor, vendorConfig, err := resolveASRVendorConfig(config, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	asrParams, err := vendorConfig.Params(language)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ASR{
+		Language: language,
+		Task:     "conversation",
+		Vendor:   vendor,
+		Params:   asrParams,
+	}, nil
+}
+
+// resolveASRVendorConfig selects the vendor for a profile and lays the profile params over its configuration
+func resolveASRVendorConfig(config *ConvoAIConfig, profile *AgentProfile) (ASRVendor, ASRVendorConfig, error) {
+	vendor := ASRVendor(config.ASRVendor)
+	var params map[string]interface{}
+	if profile != nil && profile.ASR != nil {
+		if profile.ASR.Vendor != "" {
+			vendor = ASRVendor(profile.ASR.Vendor)
+		}
+		params = profile.ASR.Params
+	}
+	if vendor == "" {
+		vendor = DefaultASRVendor
+	}
+
+	provider, ok := GetASRProvider(vendor)
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported ASR vendor: %s", vendor)
+	}
+
+	// Vendors without environment configuration may still be fully configured by the profile
+	base := config.ASR[vendor]
+	if base == nil {
+		base = provider.NewConfig()
+	}
+
+	vendorConfig, err := overlayVendorConfig(provider.NewConfig, base, params)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid %s ASR params: %v", vendor, err)
+	}
+	return vendor, vendorConfig, nil
+}
@@ -0,0 +1,33 @@
+package convoai
+
+func init() {
+	RegisterASRProvider(aresASRProvider{})
+}
+
+// AresASRConfig is the configuration of Agora's built-in ASR, which needs no credentials
+type AresASRConfig struct{}
+
+// aresASRProvider registers Agora's built-in ASR
+type aresASRProvider struct{}
+
+func (aresASRProvider) Vendor() ASRVendor {
+	return ASRVendorAres
+}
+
+func (aresASRProvider) LoadEnv(getenv func(string) string) ASRVendorConfig {
+	return &AresASRConfig{}
+}
+
+func (aresASRProvider) NewConfig() ASRVendorConfig {
+	return &AresASRConfig{}
+}
+
+// Validate always succeeds; the built-in ASR has nothing to configure
+func (c *AresASRConfig) Validate() error {
+	return nil
+}
+
+// Params returns nil, the language is set on the ASR configuration itself
+func (c *AresASRConfig) Params(language string) (interface{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,116 @@
+package convoai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// deepgramLocales are the regional language codes Deepgram accepts as-is. Other
+// locales are sent as their base language, e.g. "ja-JP" as "ja".
+var deepgramLocales = map[string]bool{
+	"en-AU": true, "en-GB": true, "en-IN": true, "en-NZ": true, "en-US": true,
+	"es-419": true, "fr-CA": true, "ko-KR": true, "nl-BE": true, "pt-BR": true,
+	"pt-PT": true, "zh-CN": true, "zh-HK": true, "zh-TW": true,
+}
+
+// deepgramMultilingualLanguages are the base languages Deepgram's "multi" mode
+// recognizes; it is only available on nova-3 models
+var deepgramMultilingualLanguages = map[string]bool{
+	"de": true, "en": true, "es": true, "fr": true, "hi": true,
+	"it": true, "ja": true, "nl": true, "pt": true, "ru": true,
+}
+
+func init() {
+	RegisterASRProvider(deepgramASRProvider{})
+}
+
+// DeepgramASRConfig holds Deepgram ASR specific configuration
+type DeepgramASRConfig struct {
+	Key   string `json:"key"`
+	Model string `json:"model"`
+	URL   string `json:"url,omitempty"`
+}
+
+// DeepgramASRParams are the Deepgram params sent to the Agora engine
+type DeepgramASRParams struct {
+	URL      string `json:"url,omitempty"`
+	Key      string `json:"key"`
+	Model    string `json:"model"`
+	Language string `json:"language"`
+}
+
+// deepgramASRProvider registers Deepgram ASR
+type deepgramASRProvider struct{}
+
+func (deepgramASRProvider) Vendor() ASRVendor {
+	return ASRVendorDeepgram
+}
+
+func (deepgramASRProvider) LoadEnv(getenv func(string) string) ASRVendorConfig {
+	key := getenv("DEEPGRAM_API_KEY")
+	if key == "" {
+		return nil
+	}
+	return &DeepgramASRConfig{
+		Key:   key,
+		Model: getenv("DEEPGRAM_MODEL"),
+		URL:   getenv("DEEPGRAM_URL"),
+	}
+}
+
+func (deepgramASRProvider) NewConfig() ASRVendorConfig {
+	return &DeepgramASRConfig{}
+}
+
+// Validate checks the Deepgram ASR configuration is complete
+func (c *DeepgramASRConfig) Validate() error {
+	if c.Key == "" || c.Model == "" {
+		return errors.New("deepgram ASR configuration is incomplete")
+	}
+	return nil
+}
+
+// Params builds the Deepgram ASR params for the Agora engine
+func (c *DeepgramASRConfig) Params(language string) (interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return DeepgramASRParams{
+		URL:      c.URL,
+		Key:      c.Key,
+		Model:    c.Model,
+		Language: deepgramLanguage(language),
+	}, nil
+}
+
+// MultilingualParams builds the Deepgram params for code-switching between the languages
+func (c *DeepgramASRConfig) MultilingualParams(languages []string) (interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(c.Model, "nova-3") {
+		return nil, fmt.Errorf("deepgram multilingual mode requires a nova-3 model, not %s", c.Model)
+	}
+	for _, language := range languages {
+		base, _, _ := strings.Cut(language, "-")
+		if !deepgramMultilingualLanguages[base] {
+			return nil, fmt.Errorf("deepgram multilingual mode does not support %s", language)
+		}
+	}
+	return DeepgramASRParams{
+		URL:      c.URL,
+		Key:      c.Key,
+		Model:    c.Model,
+		Language: "multi",
+	}, nil
+}
+
+// deepgramLanguage converts a locale into the language code Deepgram expects
+func deepgramLanguage(locale string) string {
+	if deepgramLocales[locale] {
+		return locale
+	}
+	base, _, _ := strings.Cut(locale, "-")
+	return base
+}
@@ -0,0 +1,74 @@
+package convoai
+
+import (
+	"errors"
+	"strings"
+)
+
+func init() {
+	RegisterASRProvider(microsoftASRProvider{})
+}
+
+// MicrosoftASRConfig holds Microsoft Azure speech recognition configuration
+type MicrosoftASRConfig struct {
+	Key        string   `json:"key"`
+	Region     string   `json:"region"`
+	PhraseList []string `json:"phrase_list,omitempty"`
+}
+
+// MicrosoftASRParams are the Microsoft params sent to the Agora engine
+type MicrosoftASRParams struct {
+	Key        string   `json:"key"`
+	Region     string   `json:"region"`
+	Language   string   `json:"language"`
+	PhraseList []string `json:"phrase_list,omitempty"`
+}
+
+// microsoftASRProvider registers Microsoft Azure ASR
+type microsoftASRProvider struct{}
+
+func (microsoftASRProvider) Vendor() ASRVendor {
+	return ASRVendorMicrosoft
+}
+
+func (microsoftASRProvider) LoadEnv(getenv func(string) string) ASRVendorConfig {
+	key := getenv("MICROSOFT_ASR_KEY")
+	if key == "" {
+		return nil
+	}
+	config := &MicrosoftASRConfig{
+		Key:    key,
+		Region: getenv("MICROSOFT_ASR_REGION"),
+	}
+	for _, phrase := range strings.Split(getenv("MICROSOFT_ASR_PHRASE_LIST"), ",") {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			config.PhraseList = append(config.PhraseList, phrase)
+		}
+	}
+	return config
+}
+
+func (microsoftASRProvider) NewConfig() ASRVendorConfig {
+	return &MicrosoftASRConfig{}
+}
+
+// Validate checks the Microsoft ASR configuration is complete
+func (c *MicrosoftASRConfig) Validate() error {
+	if c.Key == "" || c.Region == "" {
+		return errors.New("microsoft ASR configuration is incomplete")
+	}
+	return nil
+}
+
+// Params builds the Microsoft ASR params for the Agora engine
+func (c *MicrosoftASRConfig) Params(language string) (interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return MicrosoftASRParams{
+		Key:        c.Key,
+		Region:     c.Region,
+		Language:   language,
+		PhraseList: c.PhraseList,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package convoai
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestResolveASRConfig(t *testing.T) {
+	env := map[string]string{
+		"DEEPGRAM_API_KEY":          "dg-key",
+		"DEEPGRAM_MODEL":            "nova-3",
+		"MICROSOFT_ASR_KEY":         "ms-key",
+		"MICROSOFT_ASR_REGION":      "eastus",
+		"MICROSOFT_ASR_PHRASE_LIST": "Agora, ConvoAI",
+	}
+	configs := LoadASRConfigs(func(key string) string { return env[key] })
+
+	tests := []struct {
+		name     string
+		vendor   string
+		profile  *AgentProfile
+		language string
+		wantJSON string
+		wantErr  bool
+	}{
+		{
+			name:     "Default vendor",
+			language: "en-US",
+			wantJSON: `{"language":"en-US","task":"conversation","vendor":"ares"}`,
+		},
+		{
+			name:     "Deepgram keeps supported locales",
+			vendor:   string(ASRVendorDeepgram),
+			language: "en-GB",
+			wantJSON: `{"language":"en-GB","task":"conversation","vendor":"deepgram","params":{"key":"dg-key","model":"nova-3","language":"en-GB"}}`,
+		},
+		{
+			name:     "Deepgram uses the base language",
+			vendor:   string(ASRVendorDeepgram),
+			language: "ja-JP",
+			wantJSON: `{"language":"ja-JP","task":"conversation","vendor":"deepgram","params":{"key":"dg-key","model":"nova-3","language":"ja"}}`,
+		},
+		{
+			name:     "Microsoft",
+			vendor:   string(ASRVendorMicrosoft),
+			language: "es-ES",
+			wantJSON: `{"language":"es-ES","task":"conversation","vendor":"microsoft","params":{"key":"ms-key","region":"eastus","language":"es-ES","phrase_list":["Agora","ConvoAI"]}}`,
+		},
+		{
+			name:     "Profile selects the vendor and model",
+			profile:  &AgentProfile{ASR: &ProfileASR{Vendor: string(ASRVendorDeepgram), Params: map[string]interface{}{"model": "nova-2"}}},
+			language: "es-ES",
+			wantJSON: `{"language":"es-ES","task":"conversation","vendor":"deepgram","params":{"key":"dg-key","model":"nova-2","language":"es"}}`,
+		},
+		{
+			name:     "Unconfigured vendor",
+			vendor:   string(ASRVendorMicrosoft),
+			profile:  &AgentProfile{ASR: &ProfileASR{Params: map[string]interface{}{"key": ""}}},
+			language: "en-US",
+			wantErr:  true,
+		},
+		{
+			name:     "Unregistered vendor",
+			vendor:   "unknown",
+			language: "en-US",
+			wantErr:  true,
+		},
+		{
+			name:    "Missing language",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ConvoAIConfig{ASRVendor: tt.vendor, ASR: configs}
+
+			asr, err := ResolveASRConfig(config, tt.profile, tt.language)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveASRConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			data, err := json.Marshal(asr)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(data) != tt.wantJSON {
+				t.Errorf("ASR JSON =\n%s\nwant\n%s", data, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func TestInviteAgentASRLanguage(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora, func(config *ConvoAIConfig) {
+		config.ASRVendor = string(ASRVendorDeepgram)
+		config.ASR = map[ASRVendor]ASRVendorConfig{
+			ASRVendorDeepgram: &DeepgramASRConfig{Key: "dg-key", Model: "nova-3"},
+		}
+	})
+	router := newTestRouter(service)
+
+	for _, language := range []string{"es-ES", "ja-JP"} {
+		body := `{"requester_id": "user-1", "channel_name": "test-channel", "overrides": {"asr_language": "` + language + `"}}`
+		if status := doJSON(t, router, "POST", "/agent/invite", body, nil); status != http.StatusOK {
+			t.Fatalf("invite in %s returned status %d", language, status)
+		}
+	}
+
+	want := []struct{ language, vendorLanguage string }{
+		{"es-ES", "es"},
+		{"ja-JP", "ja"},
+	}
+	for i, w := range want {
+		asr := agora.joins[i].Properties.ASR
+		params, _ := asr.Params.(map[string]interface{})
+		if asr.Vendor != ASRVendorDeepgram || asr.Language != w.language || params["language"] != w.vendorLanguage {
+			t.Errorf("join %d ASR = %+v, want language %s", i, asr, w.language)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package convoai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// configState is the active configuration, swapped atomically on reload
+type configState struct {
+	config   *ConvoAIConfig
+	revision string
+	loadedAt time.Time
+}
+
+// newConfigState wraps a configuration with its revision hash
+func newConfigState(config *ConvoAIConfig) *configState {
+	return &configState{
+		config:   config,
+		revision: ConfigRevision(config),
+		loadedAt: time.Now(),
+	}
+}
+
+// getConfig returns the active configuration. Callers that read several fields
+// should keep the returned pointer, so a concurrent reload can't mix revisions.
+func (s *ConvoAIService) getConfig() *ConvoAIConfig {
+	return s.state.Load().config
+}
+
+// ConfigRevision returns a short hash identifying the content of a configuration
+func ConfigRevision(config *ConvoAIConfig) string {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "unknown"
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// ReloadConfig loads and validates a new configuration, then swaps it in atomically.
+// If loading or validation fails the active configuration stays in place, so a bad
+// edit never reaches running requests. Settings that can't change without a restart
+// are rejected. It returns the revision that is active afterwards.
+func (s *ConvoAIService) ReloadConfig(load func() (*ConvoAIConfig, error), validate func(*ConvoAIConfig) error) (string, error) {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	current := s.state.Load()
+
+	config, err := load()
+	if err != nil {
+		return current.revision, fmt.Errorf("failed to load configuration: %v", err)
+	}
+	if err := validate(config); err != nil {
+		return current.revision, fmt.Errorf("invalid configuration: %v", err)
+	}
+	if err := checkRestartRequired(current.config, config); err != nil {
+		return current.revision, err
+	}
+
+	next := newConfigState(config)
+	if next.revision == current.revision {
+		return current.revision, nil
+	}
+	s.state.Store(next)
+	return next.revision, nil
+}
+
+// checkRestartRequired rejects changes to settings that are bound at startup
+func checkRestartRequired(current, next *ConvoAIConfig) error {
+	switch {
+	case current.AppID != next.AppID || current.AppCertificate != next.AppCertificate:
+		return fmt.Errorf("AGORA_APP_ID and AGORA_APP_CERTIFICATE can't change without a restart")
+	case current.SessionStore != next.SessionStore || current.SessionStorePath != next.SessionStorePath:
+		return fmt.Errorf("SESSION_STORE and SESSION_STORE_PATH can't change without a restart")
+	case !reflect.DeepEqual(current.MCPServers, next.MCPServers):
+		return fmt.Errorf("MCP servers can't change without a restart")
+	}
+	return nil
+}
+
+// HandleConfigVersion returns the revision of the active configuration
+func (s *ConvoAIService) HandleConfigVersion() *ConfigVersionResponse {
+	state := s.state.Load()
+
+	profiles := make([]string, 0, len(state.config.Profiles))
+	for name := range state.config.Profiles {
+		profiles = append(profiles, name)
+	}
+	sort.Strings(profiles)
+
+	return &ConfigVersionResponse{
+		Revision: state.revision,
+		LoadedAt: state.loadedAt.Unix(),
+		Profiles: profiles,
+	}
+}
@@ -0,0 +1,98 @@
+package convoai
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestReloadConfig(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	router := newTestRouter(service)
+	initial := service.HandleConfigVersion().Revision
+
+	// withGreeting returns a loader producing the test config with a custom default greeting
+	withGreeting := func(greeting string) func() (*ConvoAIConfig, error) {
+		return func() (*ConvoAIConfig, error) {
+			config := newTestConfig(agora.server.URL)
+			profile := DefaultAgentProfile()
+			profile.GreetingMessage = greeting
+			config.Profiles = map[string]*AgentProfile{DefaultProfileName: profile}
+			return config, nil
+		}
+	}
+	accept := func(*ConvoAIConfig) error { return nil }
+	reject := func(*ConvoAIConfig) error { return errors.New("invalid profile") }
+
+	tests := []struct {
+		name        string
+		load        func() (*ConvoAIConfig, error)
+		validate    func(*ConvoAIConfig) error
+		wantErr     bool
+		wantChanged bool
+	}{
+		{
+			name:     "Load failure keeps the active config",
+			load:     func() (*ConvoAIConfig, error) { return nil, errors.New("bad file") },
+			validate: accept,
+			wantErr:  true,
+		},
+		{
+			name:     "Validation failure keeps the active config",
+			load:     withGreeting("Rejected"),
+			validate: reject,
+			wantErr:  true,
+		},
+		{
+			name: "Restart-only settings are rejected",
+			load: func() (*ConvoAIConfig, error) {
+				config := newTestConfig(agora.server.URL)
+				config.AppID = "another-app"
+				return config, nil
+			},
+			validate: accept,
+			wantErr:  true,
+		},
+		{
+			name:        "Valid config is swapped in",
+			load:        withGreeting("Welcome back!"),
+			validate:    accept,
+			wantChanged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := service.HandleConfigVersion().Revision
+			revision, err := service.ReloadConfig(tt.load, tt.validate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReloadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if changed := revision != before; changed != tt.wantChanged {
+				t.Errorf("revision changed = %v, want %v", changed, tt.wantChanged)
+			}
+
+			var version ConfigVersionResponse
+			if status := doJSON(t, router, "GET", "/admin/config/version", "", &version); status != http.StatusOK {
+				t.Fatalf("version returned status %d", status)
+			}
+			if version.Revision != revision {
+				t.Errorf("version endpoint reports %s, want %s", version.Revision, revision)
+			}
+		})
+	}
+
+	if service.HandleConfigVersion().Revision == initial {
+		t.Fatalf("revision did not change after a successful reload")
+	}
+
+	// New invites use the reloaded profile
+	body := `{"requester_id": "user-1", "channel_name": "test-channel"}`
+	if status := doJSON(t, router, "POST", "/agent/invite", body, nil); status != http.StatusOK {
+		t.Fatalf("invite returned status %d", status)
+	}
+	if greeting := agora.joins[0].Properties.LLM.GreetingMessage; greeting != "Welcome back!" {
+		t.Errorf("greeting = %q, want the reloaded greeting", greeting)
+	}
+}
@@ -0,0 +1,84 @@
+package convoai
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// agentEventsHeartbeat is the interval between heartbeats on an idle event stream,
+// keeping proxies from closing it
+var agentEventsHeartbeat = 15 * time.Second
+
+// AgentEvents streams the state changes of an agent as Server-Sent Events. Each event is
+// named after the new state (starting, running, speaking, stopped or failed) and carries
+// its sequence number as the event ID, so a reconnecting client sending Last-Event-ID
+// receives the changes it missed. Without Last-Event-ID, the stream starts with the
+// agent's current state.
+func (s *ConvoAIService) AgentEvents(c *gin.Context) {
+	agentID := c.Param("agent_id")
+	lastEventID, _ := strconv.ParseInt(c.GetHeader("Last-Event-ID"), 10, 64)
+
+	// Subscribe before taking the replay, so no change falls between the two
+	events, unsubscribe := s.events.Subscribe(defaultEventBuffer)
+	defer unsubscribe()
+
+	replay, sent, ok := s.agentStates.replay(agentID, lastEventID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrAgentNotFound.Error()})
+		return
+	}
+
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	for _, event := range replay {
+		renderAgentEvent(c, event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(agentEventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-s.streamsDone:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			// Changes up to the replayed sequence were sent, or were not asked for
+			if event.AgentID != agentID || event.Sequence <= sent {
+				continue
+			}
+			// Changes dropped while the subscription was full are sent from the history
+			for _, missed := range s.agentStates.missed(agentID, sent, event.Sequence) {
+				renderAgentEvent(c, missed)
+			}
+			renderAgentEvent(c, event)
+			sent = event.Sequence
+		case now := <-heartbeat.C:
+			// Including the last changes, which no later event reveals as missed
+			for _, missed := range s.agentStates.missed(agentID, sent, 0) {
+				renderAgentEvent(c, missed)
+				sent = missed.Sequence
+			}
+			c.Render(-1, sse.Event{Event: "heartbeat", Data: now.UnixMilli()})
+		}
+		c.Writer.Flush()
+	}
+}
+
+// renderAgentEvent writes a state change as a Server-Sent Event
+func renderAgentEvent(c *gin.Context, event AgentEvent) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatInt(event.Sequence, 10),
+		Event: strings.ToLower(event.State),
+		Data:  event,
+	})
+}
@@ -0,0 +1,263 @@
+package convoai
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// streamedEvent is a Server-Sent Event read from a stream
+type streamedEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// openEventStream connects to the agent's event stream, resuming after lastEventID if set
+func openEventStream(t *testing.T, server *httptest.Server, agentID, lastEventID string) (*http.Response, <-chan streamedEvent) {
+	t.Helper()
+	req, err := http.NewRequest("GET", server.URL+"/agent/"+agentID+"/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	events := make(chan streamedEvent, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		var event streamedEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				events <- event
+				event = streamedEvent{}
+			case strings.HasPrefix(line, "id:"):
+				event.ID = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+			case strings.HasPrefix(line, "event:"):
+				event.Event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				event.Data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			}
+		}
+	}()
+	return resp, events
+}
+
+// nextStreamedEvent waits for the next event on the stream that isn't a heartbeat
+func nextStreamedEvent(t *testing.T, events <-chan streamedEvent) streamedEvent {
+	t.Helper()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("event stream closed")
+			}
+			if event.Event != "heartbeat" {
+				return event
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a streamed event")
+			return streamedEvent{}
+		}
+	}
+}
+
+func TestAgentEvents(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	router := newTestRouter(service)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	var invited InviteAgentResponse
+	if status := doJSON(t, router, "POST", "/agent/invite", `{"requester_id": "user-1", "channel_name": "test-channel"}`, &invited); status != http.StatusOK {
+		t.Fatalf("invite returned status %d", status)
+	}
+
+	// Agents the server doesn't track have no stream
+	if status := doJSON(t, router, "GET", "/agent/UNKNOWN/events", "", nil); status != http.StatusNotFound {
+		t.Errorf("unknown agent returned status %d", status)
+	}
+
+	// The stream starts with the current state
+	resp, events := openEventStream(t, server, invited.AgentID, "")
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/event-stream") {
+		t.Errorf("Content-Type = %q", got)
+	}
+	starting := nextStreamedEvent(t, events)
+	if starting.Event != "starting" || starting.ID == "" {
+		t.Errorf("first event = %+v", starting)
+	}
+	var data AgentEvent
+	if err := json.Unmarshal([]byte(starting.Data), &data); err != nil || data.AgentID != invited.AgentID || data.ChannelName != "test-channel" {
+		t.Errorf("first event data = %s (%v)", starting.Data, err)
+	}
+
+	// Changes of other agents are not streamed
+	service.setAgentState(AgentEventJoined, "OTHER", "other-channel", AgentStateRunning, "", nil)
+	service.setAgentState(AgentEventJoined, invited.AgentID, "", AgentStateRunning, "", nil)
+	if event := nextStreamedEvent(t, events); event.Event != "running" {
+		t.Errorf("joined event = %+v", event)
+	}
+
+	if status := doJSON(t, router, "POST", "/agent/remove", `{"agent_id": "`+invited.AgentID+`"}`, nil); status != http.StatusOK {
+		t.Fatalf("remove returned status %d", status)
+	}
+	if event := nextStreamedEvent(t, events); event.Event != "stopped" {
+		t.Errorf("removed event = %+v", event)
+	}
+
+	// A reconnecting client receives the changes after its Last-Event-ID
+	_, resumed := openEventStream(t, server, invited.AgentID, starting.ID)
+	for _, want := range []string{"running", "stopped"} {
+		if event := nextStreamedEvent(t, resumed); event.Event != want {
+			t.Errorf("resumed event = %+v, want %s", event, want)
+		}
+	}
+}
+
+func TestAgentEventsHeartbeat(t *testing.T) {
+	heartbeat := agentEventsHeartbeat
+	agentEventsHeartbeat = 10 * time.Millisecond
+	defer func() { agentEventsHeartbeat = heartbeat }()
+
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	server := httptest.NewServer(newTestRouter(service))
+	t.Cleanup(server.Close)
+
+	service.setAgentState(AgentEventJoined, "AGENT0001", "test-channel", AgentStateRunning, "", nil)
+	_, events := openEventStream(t, server, "AGENT0001", "")
+	nextStreamedEvent(t, events)
+
+	select {
+	case event := <-events:
+		if event.Event != "heartbeat" {
+			t.Errorf("event = %+v, want a heartbeat", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for a heartbeat")
+	}
+}
+
+func TestAgentEventsRecoverDroppedChanges(t *testing.T) {
+	heartbeat := agentEventsHeartbeat
+	agentEventsHeartbeat = 50 * time.Millisecond
+	defer func() { agentEventsHeartbeat = heartbeat }()
+
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	server := httptest.NewServer(newTestRouter(service))
+	t.Cleanup(server.Close)
+
+	service.setAgentState(AgentEventInvited, "AGENT0001", "test-channel", AgentStateStarting, "", nil)
+	_, events := openEventStream(t, server, "AGENT0001", "")
+	nextStreamedEvent(t, events)
+
+	// dropChange records a change without publishing it, as for a full subscription
+	dropChange := func(eventType, state string) {
+		service.agentStates.set(AgentEvent{Type: eventType, AgentID: "AGENT0001", State: state}, time.Now(), func(AgentEvent) {})
+	}
+
+	// A dropped change is sent before the next published one
+	dropChange(AgentEventJoined, AgentStateRunning)
+	service.setAgentState(AgentEventSpeaking, "AGENT0001", "", AgentStateSpeaking, "", nil)
+	for _, want := range []string{"running", "speaking"} {
+		if event := nextStreamedEvent(t, events); event.Event != want {
+			t.Errorf("event = %+v, want %s", event, want)
+		}
+	}
+
+	// The last change, dropped with no change after it, is sent on the next heartbeat
+	dropChange(AgentEventLeft, AgentStateStopped)
+	if event := nextStreamedEvent(t, events); event.Event != "stopped" {
+		t.Errorf("event = %+v, want stopped", event)
+	}
+}
+
+// nextStateEvent waits for the next state change published on the bus
+func nextStateEvent(t *testing.T, events <-chan AgentEvent) AgentEvent {
+	t.Helper()
+	for {
+		if event := nextEvent(t, events); event.Sequence != 0 {
+			return event
+		}
+	}
+}
+
+func TestAgentSpeakingState(t *testing.T) {
+	agora := newFakeAgora(t)
+	llm := newFakeLLM(t)
+	service := newTestService(t, agora, withLLMProxy(llm, 0))
+	router := newTestRouter(service)
+	agentLLM := inviteProxiedAgent(t, router, agora)
+	service.setAgentState(AgentEventJoined, "AGENT0001", "", AgentStateRunning, "", nil)
+
+	events, unsubscribe := service.SubscribeEvents(10)
+	defer unsubscribe()
+
+	// The relayed reply makes the agent speak, and the next user turn ends it
+	body := `{"model": "gpt-4o-mini", "messages": [{"role": "user", "content": "Where is my order?"}]}`
+	if rr := doProxy(router, agentLLM.APIKey, body); rr.Code != http.StatusOK {
+		t.Fatalf("proxy returned status %d", rr.Code)
+	}
+	if event := nextStateEvent(t, events); event.Type != AgentEventSpeaking || event.State != AgentStateSpeaking || event.AgentID != "AGENT0001" {
+		t.Errorf("speaking event = %+v", event)
+	}
+
+	if rr := doProxy(router, agentLLM.APIKey, body); rr.Code != http.StatusOK {
+		t.Fatalf("proxy returned status %d", rr.Code)
+	}
+	if event := nextStateEvent(t, events); event.Type != AgentEventListening || event.State != AgentStateRunning {
+		t.Errorf("listening event = %+v", event)
+	}
+	if event := nextStateEvent(t, events); event.Type != AgentEventSpeaking {
+		t.Errorf("second speaking event = %+v", event)
+	}
+}
+
+func TestCloseStreams(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	server := httptest.NewServer(newTestRouter(service))
+	t.Cleanup(server.Close)
+
+	service.setAgentState(AgentEventJoined, "AGENT0001", "test-channel", AgentStateRunning, "", nil)
+	_, events := openEventStream(t, server, "AGENT0001", "")
+	nextStreamedEvent(t, events)
+	conn, _, err := dialAgentSocket(t, server, "AGENT0001", channelToken(t, service, "test-channel"))
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	readSocketMessage(t, conn)
+
+	// Shutting down ends the streams without waiting for their clients
+	service.CloseStreams()
+	select {
+	case event, ok := <-events:
+		if ok {
+			t.Errorf("event stream still open, received %+v", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the event stream to end")
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Errorf("socket read error = %v, want a going away close", err)
+	}
+}
@@ -0,0 +1,299 @@
+package convoai
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Agent states, matching the statuses reported by the Agora API. SPEAKING is tracked
+// by the server: a running agent is speaking from the time the LLM proxy relays its
+// reply until the next user turn reaches the proxy.
+const (
+	AgentStateStarting = "STARTING"
+	AgentStateRunning  = "RUNNING"
+	AgentStateSpeaking = "SPEAKING"
+	AgentStateStopped  = "STOPPED"
+	AgentStateFailed   = "FAILED"
+)
+
+// Agent event types published on the event bus
+const (
+	AgentEventInvited     = "agent.invited"
+	AgentEventJoined      = "agent.joined"
+	AgentEventSpeaking    = "agent.speaking"
+	AgentEventListening   = "agent.listening"
+	AgentEventLeft        = "agent.left"
+	AgentEventFailed      = "agent.failed"
+	AgentEventIdleTimeout = "agent.idle_timeout"
+	AgentEventError       = "agent.error"
+	AgentEventHistory     = "agent.history"
+	AgentEventTranscript  = "agent.transcript"
+	AgentEventMetrics     = "agent.metrics"
+)
+
+// agentEventTypes lists the event types, for validating subscriptions
+var agentEventTypes = []string{
+	AgentEventInvited, AgentEventJoined, AgentEventSpeaking, AgentEventListening,
+	AgentEventLeft, AgentEventFailed, AgentEventIdleTimeout, AgentEventError,
+	AgentEventHistory, AgentEventTranscript, AgentEventMetrics,
+}
+
+// ValidAgentEvent reports whether name is an event type published on the event bus
+func ValidAgentEvent(name string) bool {
+	for _, eventType := range agentEventTypes {
+		if eventType == name {
+			return true
+		}
+	}
+	return false
+}
+
+// agentStateRetention is how long the state of a stopped or failed agent is kept
+const agentStateRetention = 24 * time.Hour
+
+// defaultEventBuffer is the number of events buffered for a subscriber
+const defaultEventBuffer = 64
+
+// dropWarningInterval is the minimum time between warnings about events dropped for full subscribers
+const dropWarningInterval = 10 * time.Second
+
+// maxAgentStateHistory is the number of state changes kept per agent for replay
+const maxAgentStateHistory = 50
+
+// AgentEvent is a change in an agent's lifecycle, published to the subscribers of the event bus.
+// Sequence numbers the state changes across all agents; it is zero for events that don't
+// change the agent's state.
+type AgentEvent struct {
+	Type        string      `json:"type"`
+	AgentID     string      `json:"agent_id"`
+	ChannelName string      `json:"channel_name,omitempty"`
+	State       string      `json:"state"`
+	Reason      string      `json:"reason,omitempty"`
+	Timestamp   int64       `json:"timestamp"`
+	Sequence    int64       `json:"sequence,omitempty"`
+	Data        interface{} `json:"data,omitempty"`
+}
+
+// AgentState is the state the server tracks for an agent
+type AgentState struct {
+	AgentID     string `json:"agent_id"`
+	ChannelName string `json:"channel_name,omitempty"`
+	State       string `json:"state"`
+	Reason      string `json:"reason,omitempty"`
+	UpdatedAt   int64  `json:"updated_at"`
+}
+
+// EventBus fans agent events out to its subscribers. Publishing never blocks: a
+// subscriber whose buffer is full misses the event.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers map[int]chan AgentEvent
+	nextID      int
+
+	dropMu          sync.Mutex
+	drops           int
+	lastDropWarning time.Time
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[int]chan AgentEvent)}
+}
+
+// Subscribe returns a channel receiving every event published from now on, and a
+// function that ends the subscription and closes the channel
+func (b *EventBus) Subscribe(buffer int) (<-chan AgentEvent, func()) {
+	if buffer <= 0 {
+		buffer = defaultEventBuffer
+	}
+	ch := make(chan AgentEvent, buffer)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = ch
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers the event to every subscriber
+func (b *EventBus) Publish(event AgentEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for id, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			b.dropped(id, event)
+		}
+	}
+}
+
+// dropped counts an event dropped for a full subscriber, logging a warning at most once
+// per dropWarningInterval
+func (b *EventBus) dropped(id int, event AgentEvent) {
+	b.dropMu.Lock()
+	defer b.dropMu.Unlock()
+	b.drops++
+	now := time.Now()
+	if now.Sub(b.lastDropWarning) < dropWarningInterval {
+		return
+	}
+	log.Printf("Warning: event subscriber %d is full, dropped %s for agent %s (%d event(s) dropped since the last warning)", id, event.Type, event.AgentID, b.drops)
+	b.drops = 0
+	b.lastDropWarning = now
+}
+
+// SubscribeEvents subscribes to the lifecycle events of every agent
+func (s *ConvoAIService) SubscribeEvents(buffer int) (<-chan AgentEvent, func()) {
+	return s.events.Subscribe(buffer)
+}
+
+// agentStates holds the state of the agents the server knows about, and their
+// recent state changes
+type agentStates struct {
+	mu       sync.RWMutex
+	byAgent  map[string]AgentState
+	history  map[string][]AgentEvent
+	sequence int64
+}
+
+// get returns the state of the agent, and false if it isn't tracked
+func (a *agentStates) get(agentID string) (AgentState, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	state, ok := a.byAgent[agentID]
+	return state, ok
+}
+
+// set records the state change of event.AgentID, keeping its known channel when the
+// event has none, and drops agents that stopped or failed outside the retention period.
+// The event is numbered and published while the lock is held, so subscribers receive
+// the state changes in sequence.
+func (a *agentStates) set(event AgentEvent, now time.Time, publish func(AgentEvent)) AgentState {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.byAgent == nil {
+		a.byAgent = make(map[string]AgentState)
+		a.history = make(map[string][]AgentEvent)
+	}
+
+	if event.ChannelName == "" {
+		event.ChannelName = a.byAgent[event.AgentID].ChannelName
+	}
+	a.sequence++
+	event.Sequence = a.sequence
+	current := AgentState{
+		AgentID:     event.AgentID,
+		ChannelName: event.ChannelName,
+		State:       event.State,
+		Reason:      event.Reason,
+		UpdatedAt:   now.UnixMilli(),
+	}
+	a.byAgent[event.AgentID] = current
+
+	// The history keeps the change without its payload
+	changes := append(a.history[event.AgentID], event)
+	changes[len(changes)-1].Data = nil
+	if len(changes) > maxAgentStateHistory {
+		changes = changes[len(changes)-maxAgentStateHistory:]
+	}
+	a.history[event.AgentID] = changes
+
+	for id, old := range a.byAgent {
+		ended := old.State == AgentStateStopped || old.State == AgentStateFailed
+		if ended && now.Sub(time.UnixMilli(old.UpdatedAt)) > agentStateRetention {
+			delete(a.byAgent, id)
+			delete(a.history, id)
+		}
+	}
+
+	publish(event)
+	return current
+}
+
+// replay returns the state changes of the agent after the given sequence number, or
+// only its latest change when after is zero or unknown, along with the sequence number
+// of the last change recorded for any agent. It returns false if the agent isn't tracked.
+func (a *agentStates) replay(agentID string, after int64) ([]AgentEvent, int64, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	changes, ok := a.history[agentID]
+	if !ok {
+		return nil, a.sequence, false
+	}
+
+	if after <= 0 || after > a.sequence {
+		return changes[len(changes)-1:], a.sequence, true
+	}
+	var replayed []AgentEvent
+	for _, change := range changes {
+		if change.Sequence > after {
+			replayed = append(replayed, change)
+		}
+	}
+	return replayed, a.sequence, true
+}
+
+// missed returns the state changes of the agent after the sequence number after, and
+// before the sequence number before unless it is zero. Subscribers use it to recover the
+// changes dropped while their buffer was full.
+func (a *agentStates) missed(agentID string, after, before int64) []AgentEvent {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	var missed []AgentEvent
+	for _, change := range a.history[agentID] {
+		if change.Sequence > after && (before == 0 || change.Sequence < before) {
+			missed = append(missed, change)
+		}
+	}
+	return missed
+}
+
+// setAgentState records the agent's new state and publishes the event that caused it
+func (s *ConvoAIService) setAgentState(eventType, agentID, channelName, state, reason string, data interface{}) AgentState {
+	now := time.Now()
+	return s.agentStates.set(AgentEvent{
+		Type:        eventType,
+		AgentID:     agentID,
+		ChannelName: channelName,
+		State:       state,
+		Reason:      reason,
+		Timestamp:   now.UnixMilli(),
+		Data:        data,
+	}, now, s.publishEvent)
+}
+
+// publishEvent publishes the event on the bus and queues it for the webhook subscriptions,
+// which receive it even when a bus subscriber's buffer is full
+func (s *ConvoAIService) publishEvent(event AgentEvent) {
+	s.events.Publish(event)
+	s.dispatchWebhooks(event)
+}
+
+// publishAgentEvent publishes an event that doesn't change the agent's state
+func (s *ConvoAIService) publishAgentEvent(eventType, agentID, channelName, reason string, data interface{}) {
+	current, _ := s.agentStates.get(agentID)
+	if channelName == "" {
+		channelName = current.ChannelName
+	}
+	s.publishEvent(AgentEvent{
+		Type:        eventType,
+		AgentID:     agentID,
+		ChannelName: channelName,
+		State:       current.State,
+		Reason:      reason,
+		Timestamp:   time.Now().UnixMilli(),
+		Data:        data,
+	})
+}
@@ -0,0 +1,119 @@
+package convoai
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+	"unicode/utf8"
+)
+
+// maxSpeakTextLength bounds the text an agent is asked to say
+const maxSpeakTextLength = 512
+
+// HandleSpeakAgent makes a running agent say the text, through the Agora speak API.
+// The request is validated with validateSpeakRequest.
+func (s *ConvoAIService) HandleSpeakAgent(agentID string, req SpeakAgentRequest) (*AgentCommandResponse, error) {
+	if err := s.agoraRequest("POST", "/agents/"+url.PathEscape(agentID)+"/speak", req, nil); err != nil {
+		return nil, err
+	}
+	return &AgentCommandResponse{Success: true, AgentID: agentID}, nil
+}
+
+// HandleInterruptAgent stops a running agent mid-utterance, through the Agora interrupt API
+func (s *ConvoAIService) HandleInterruptAgent(agentID string) (*AgentCommandResponse, error) {
+	if err := s.agoraRequest("POST", "/agents/"+url.PathEscape(agentID)+"/interrupt", struct{}{}, nil); err != nil {
+		return nil, err
+	}
+
+	// The agent listens again once it is cut off
+	if current, ok := s.agentStates.get(agentID); ok && current.State == AgentStateSpeaking {
+		s.setAgentState(AgentEventListening, agentID, "", AgentStateRunning, "interrupted", nil)
+	}
+	return &AgentCommandResponse{Success: true, AgentID: agentID}, nil
+}
+
+// HandleUpdateAgent changes the settings of a running agent, through the Agora update API.
+// A refreshed token is generated for the channel recorded in the agent's session, whose
+// expiry is then extended. The request is validated with validateUpdateRequest.
+func (s *ConvoAIService) HandleUpdateAgent(agentID string, req UpdateAgentRequest) (*AgentCommandResponse, error) {
+	var update AgoraUpdateRequest
+	if len(req.SystemMessages) > 0 || req.MaxTokens != nil || req.Temperature != nil || req.TopP != nil {
+		update.Properties.LLM = &AgoraUpdateLLM{SystemMessages: req.SystemMessages}
+		if req.MaxTokens != nil || req.Temperature != nil || req.TopP != nil {
+			update.Properties.LLM.Params = &AgoraUpdateLLMParams{
+				MaxTokens:   req.MaxTokens,
+				Temperature: req.Temperature,
+				TopP:        req.TopP,
+			}
+		}
+	}
+
+	var session AgentSession
+	if req.RefreshToken {
+		var ok bool
+		var err error
+		session, ok, err = s.sessions.Get(agentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get session: %v", err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: no session records its channel", ErrAgentNotFound)
+		}
+		if update.Properties.Token, err = s.agentToken(session.ChannelName); err != nil {
+			return nil, err
+		}
+	}
+	issuedAt := time.Now().Unix()
+
+	if err := s.agoraRequest("POST", "/agents/"+url.PathEscape(agentID)+"/update", update, nil); err != nil {
+		return nil, err
+	}
+
+	// The agent stays in the channel until the new token expires
+	if req.RefreshToken {
+		session.ExpireTS = issuedAt + agentTokenExpirationSeconds
+		if err := s.sessions.Save(session); err != nil {
+			log.Printf("Warning: failed to record the token expiry of agent %s: %v", agentID, err)
+		}
+	}
+	return &AgentCommandResponse{Success: true, AgentID: agentID}, nil
+}
+
+// validateSpeakRequest checks the text and priority, defaulting the priority to INTERRUPT
+func validateSpeakRequest(req *SpeakAgentRequest) error {
+	if req.Text == "" || utf8.RuneCountInString(req.Text) > maxSpeakTextLength {
+		return fmt.Errorf("text must be between 1 and %d characters", maxSpeakTextLength)
+	}
+	switch req.Priority {
+	case "":
+		req.Priority = SpeakPriorityInterrupt
+	case SpeakPriorityInterrupt, SpeakPriorityAppend, SpeakPriorityIgnore:
+	default:
+		return fmt.Errorf("priority must be %s, %s or %s", SpeakPriorityInterrupt, SpeakPriorityAppend, SpeakPriorityIgnore)
+	}
+	return nil
+}
+
+// validateUpdateRequest checks the update changes something, within the bounds of the invite overrides
+func validateUpdateRequest(req *UpdateAgentRequest) error {
+	if len(req.SystemMessages) == 0 && req.MaxTokens == nil && req.Temperature == nil && req.TopP == nil && !req.RefreshToken {
+		return errors.New("the update must set system_messages, max_tokens, temperature, top_p or refresh_token")
+	}
+	for _, message := range req.SystemMessages {
+		if message.Role != "system" || message.Content == "" || utf8.RuneCountInString(message.Content) > maxSystemMessageLength {
+			return fmt.Errorf("system_messages must have the system role and between 1 and %d characters", maxSystemMessageLength)
+		}
+	}
+	if req.MaxTokens != nil && (*req.MaxTokens < minOverrideMaxTokens || *req.MaxTokens > maxOverrideMaxTokens) {
+		return fmt.Errorf("max_tokens must be between %d and %d", minOverrideMaxTokens, maxOverrideMaxTokens)
+	}
+	if req.Temperature != nil && (*req.Temperature < minOverrideTemperature || *req.Temperature > maxOverrideTemperature) {
+		return fmt.Errorf("temperature must be between %.1f and %.1f", minOverrideTemperature, maxOverrideTemperature)
+	}
+	if req.TopP != nil && (*req.TopP < minOverrideTopP || *req.TopP > maxOverrideTopP) {
+		return fmt.Errorf("top_p must be between %.1f and %.1f", minOverrideTopP, maxOverrideTopP)
+	}
+	return nil
+}
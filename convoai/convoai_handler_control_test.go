@@ -0,0 +1,214 @@
+package convoai
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpeakAgent(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	router := newTestRouter(service)
+	agora.addAgent("AGENT0001")
+
+	tests := []struct {
+		name          string
+		agentID       string
+		body          string
+		wantStatus    int
+		wantPriority  string
+		wantInterrupt interface{}
+	}{
+		{name: "Default priority", agentID: "AGENT0001", body: `{"text": "Your order is ready"}`, wantStatus: http.StatusOK, wantPriority: SpeakPriorityInterrupt},
+		{name: "Append, not interruptable", agentID: "AGENT0001", body: `{"text": "Your order is ready", "priority": "APPEND", "interruptable": false}`, wantStatus: http.StatusOK, wantPriority: SpeakPriorityAppend, wantInterrupt: false},
+		{name: "Ignore if busy", agentID: "AGENT0001", body: `{"text": "Your order is ready", "priority": "IGNORE"}`, wantStatus: http.StatusOK, wantPriority: SpeakPriorityIgnore},
+		{name: "Missing text", agentID: "AGENT0001", body: `{"priority": "APPEND"}`, wantStatus: http.StatusBadRequest},
+		{name: "Text too long", agentID: "AGENT0001", body: `{"text": "` + strings.Repeat("a", maxSpeakTextLength+1) + `"}`, wantStatus: http.StatusBadRequest},
+		{name: "Unknown priority", agentID: "AGENT0001", body: `{"text": "Hi", "priority": "LOUD"}`, wantStatus: http.StatusBadRequest},
+		{name: "Invalid JSON", agentID: "AGENT0001", body: `{"text": `, wantStatus: http.StatusBadRequest},
+		{name: "Unknown agent", agentID: "UNKNOWN", body: `{"text": "Hi"}`, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(agora.agentCommands())
+			var response AgentCommandResponse
+			if status := doJSON(t, router, "POST", "/agent/"+tt.agentID+"/speak", tt.body, &response); status != tt.wantStatus {
+				t.Fatalf("speak returned status %d, want %d", status, tt.wantStatus)
+			}
+
+			commands := agora.agentCommands()
+			if tt.wantStatus != http.StatusOK {
+				if len(commands) != before {
+					t.Errorf("agora received a command for a rejected request: %+v", commands[before:])
+				}
+				return
+			}
+			if !response.Success || response.AgentID != tt.agentID {
+				t.Errorf("unexpected response: %+v", response)
+			}
+			if len(commands) != before+1 {
+				t.Fatalf("agora received %d commands, want 1", len(commands)-before)
+			}
+			command := commands[before]
+			if command.Action != "speak" || command.Body["text"] != "Your order is ready" || command.Body["priority"] != tt.wantPriority || command.Body["interruptable"] != tt.wantInterrupt {
+				t.Errorf("speak command = %+v", command)
+			}
+		})
+	}
+}
+
+func TestInterruptAgent(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	router := newTestRouter(service)
+	agora.addAgent("AGENT0001")
+	service.setAgentState(AgentEventSpeaking, "AGENT0001", "test-channel", AgentStateSpeaking, "", nil)
+
+	var response AgentCommandResponse
+	if status := doJSON(t, router, "POST", "/agent/AGENT0001/interrupt", "", &response); status != http.StatusOK {
+		t.Fatalf("interrupt returned status %d", status)
+	}
+	if !response.Success || response.AgentID != "AGENT0001" {
+		t.Errorf("unexpected response: %+v", response)
+	}
+	if commands := agora.agentCommands(); len(commands) != 1 || commands[0].Action != "interrupt" {
+		t.Errorf("agora received %+v, want an interrupt", commands)
+	}
+
+	// The interrupted agent listens again
+	if current, _ := service.agentStates.get("AGENT0001"); current.State != AgentStateRunning || current.Reason != "interrupted" {
+		t.Errorf("state after interrupt = %+v", current)
+	}
+
+	if status := doJSON(t, router, "POST", "/agent/UNKNOWN/interrupt", "", nil); status != http.StatusNotFound {
+		t.Errorf("interrupt unknown agent returned status %d, want %d", status, http.StatusNotFound)
+	}
+
+	// Agora rejecting the interrupt is reported
+	agora.mu.Lock()
+	agora.agents["AGENT0001"].Status = "STOPPED"
+	agora.mu.Unlock()
+	if status := doJSON(t, router, "POST", "/agent/AGENT0001/interrupt", "", nil); status != http.StatusInternalServerError {
+		t.Errorf("interrupt stopped agent returned status %d, want %d", status, http.StatusInternalServerError)
+	}
+}
+
+func TestUpdateAgent(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	router := newTestRouter(service)
+
+	var invited InviteAgentResponse
+	if status := doJSON(t, router, "POST", "/agent/invite", `{"requester_id": "user-1", "channel_name": "test-channel"}`, &invited); status != http.StatusOK {
+		t.Fatalf("invite returned status %d", status)
+	}
+	// An agent started outside this server has no session recording its channel
+	agora.addAgent("EXTERNAL")
+
+	tests := []struct {
+		name       string
+		agentID    string
+		body       string
+		wantStatus int
+		wantLLM    string
+		wantToken  bool
+	}{
+		{name: "System messages", agentID: invited.AgentID, body: `{"system_messages": [{"role": "system", "content": "Be brief."}]}`, wantStatus: http.StatusOK, wantLLM: `{"system_messages":[{"content":"Be brief.","role":"system"}]}`},
+		{name: "LLM params", agentID: invited.AgentID, body: `{"max_tokens": 256, "temperature": 0.2}`, wantStatus: http.StatusOK, wantLLM: `{"params":{"max_tokens":256,"temperature":0.2}}`},
+		{name: "Refresh token", agentID: invited.AgentID, body: `{"refresh_token": true}`, wantStatus: http.StatusOK, wantToken: true},
+		{name: "Refresh token without session", agentID: "EXTERNAL", body: `{"refresh_token": true}`, wantStatus: http.StatusNotFound},
+		{name: "Params of an external agent", agentID: "EXTERNAL", body: `{"top_p": 0.5}`, wantStatus: http.StatusOK, wantLLM: `{"params":{"top_p":0.5}}`},
+		{name: "Nothing to update", agentID: invited.AgentID, body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "Temperature out of bounds", agentID: invited.AgentID, body: `{"temperature": 3}`, wantStatus: http.StatusBadRequest},
+		{name: "Max tokens out of bounds", agentID: invited.AgentID, body: `{"max_tokens": 0}`, wantStatus: http.StatusBadRequest},
+		{name: "User system message", agentID: invited.AgentID, body: `{"system_messages": [{"role": "user", "content": "Hi"}]}`, wantStatus: http.StatusBadRequest},
+		{name: "Unknown agent", agentID: "UNKNOWN", body: `{"max_tokens": 256}`, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(agora.agentCommands())
+			if status := doJSON(t, router, "POST", "/agent/"+tt.agentID+"/update", tt.body, nil); status != tt.wantStatus {
+				t.Fatalf("update returned status %d, want %d", status, tt.wantStatus)
+			}
+
+			commands := agora.agentCommands()
+			if tt.wantStatus != http.StatusOK {
+				if len(commands) != before {
+					t.Errorf("agora received a command for a rejected request: %+v", commands[before:])
+				}
+				return
+			}
+			if len(commands) != before+1 || commands[before].Action != "update" {
+				t.Fatalf("agora received %+v, want an update", commands[before:])
+			}
+			properties, _ := commands[before].Body["properties"].(map[string]interface{})
+
+			llm, _ := json.Marshal(properties["llm"])
+			if tt.wantLLM == "" && properties["llm"] != nil || tt.wantLLM != "" && string(llm) != tt.wantLLM {
+				t.Errorf("llm properties = %s, want %s", llm, tt.wantLLM)
+			}
+
+			token, _ := properties["token"].(string)
+			if !tt.wantToken {
+				if token != "" {
+					t.Errorf("unexpected token in update: %v", properties)
+				}
+				return
+			}
+			if channel, _, err := service.tokenService.VerifyRtcToken(token); err != nil || channel != "test-channel" {
+				t.Errorf("refreshed token is for channel %q (%v)", channel, err)
+			}
+		})
+	}
+}
+
+func TestRefreshAgentTokens(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+
+	now := time.Now().Unix()
+	sessions := []AgentSession{
+		{AgentID: "EXPIRING", ChannelName: "test-channel", InstanceID: service.instanceID, ExpireTS: now + 60},
+		{AgentID: "FRESH", ChannelName: "test-channel", InstanceID: service.instanceID, ExpireTS: now + 3000},
+		{AgentID: "OTHER_INSTANCE", ChannelName: "test-channel", InstanceID: "other-instance", ExpireTS: now + 60},
+		{AgentID: "STOPPED", ChannelName: "test-channel", InstanceID: service.instanceID, ExpireTS: now + 60},
+	}
+	for _, session := range sessions {
+		agora.addAgent(session.AgentID)
+		if err := service.sessions.Save(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	service.setAgentState(AgentEventLeft, "STOPPED", "test-channel", AgentStateStopped, "removed", nil)
+
+	if err := service.refreshAgentTokens(300); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+
+	// Only the running agent of this instance whose token expires soon is refreshed
+	commands := agora.agentCommands()
+	if len(commands) != 1 || commands[0].AgentID != "EXPIRING" || commands[0].Action != "update" {
+		t.Fatalf("agora received %+v, want an update of EXPIRING", commands)
+	}
+	properties, _ := commands[0].Body["properties"].(map[string]interface{})
+	if token, _ := properties["token"].(string); token == "" {
+		t.Errorf("update carries no token: %v", commands[0].Body)
+	}
+	session, _, _ := service.sessions.Get("EXPIRING")
+	if session.ExpireTS < now+agentTokenExpirationSeconds {
+		t.Errorf("session expiry = %d, want at least %d", session.ExpireTS, now+agentTokenExpirationSeconds)
+	}
+
+	// Failures are reported, and retried on the next run
+	agora.mu.Lock()
+	agora.agents["EXPIRING"].Status = "STOPPED"
+	agora.mu.Unlock()
+	if err := service.refreshAgentTokens(3600); err == nil || !strings.Contains(err.Error(), "EXPIRING") {
+		t.Errorf("refresh error = %v, want a failure for EXPIRING", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"time"
 
@@ -13,31 +14,88 @@ import (
 	"github.com/AgoraIO-Community/convo-ai-go-server/token_service"
 )
 
+// agentTokenExpirationSeconds is the lifetime of the RTC token issued to an agent
+const agentTokenExpirationSeconds = 3600
+
 // HandleInviteAgent processes the agent invitation request
 func (s *ConvoAIService) HandleInviteAgent(req InviteAgentRequest) (*InviteAgentResponse, error) {
+	// Use one configuration for the whole request, even if it is reloaded meanwhile
+	config := s.getConfig()
+
 	// Generate token for the agent
-	tokenReq := token_service.TokenRequest{
-		TokenType: "rtc",
-		Channel:   req.ChannelName,
-		Uid:       "0",
-		RtcRole:   "publisher",
+	token, err := s.agentToken(req.ChannelName)
+	if err != nil {
+		return nil, err
 	}
 
-	token, err := s.tokenService.GenRtcToken(tokenReq)
+	// Resolve the agent profile, falling back to the default profile
+	profile, err := lookupProfile(config, req.Profile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %v", err)
+		return nil, err
 	}
 
-	// Get TTS config based on vendor
-	ttsConfig, err := s.getTTSConfig()
+	// Get TTS config based on the profile's vendor
+	ttsConfig, err := ResolveTTSConfig(config, profile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get TTS config: %v", err)
 	}
 
-	// Set up system message for AI behavior
-	systemMessage := SystemMessage{
-		Role:    "system",
-		Content: "You are a helpful assistant. Pretend that the text input is audio, and you are responding to it. Speak fast, clearly, and concisely.",
+	// Resolve the ASR vendor in the requested language, falling back to the profile's.
+	// Multilingual mode replaces the ASR and TTS configuration with language-aware ones.
+	var asrConfig *ASR
+	var multilingual *MultilingualConfig
+	if req.Multilingual != nil {
+		multilingual, err = ResolveMultilingualConfig(config, profile, req.Multilingual.Languages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get multilingual config: %v", err)
+		}
+		asrConfig = multilingual.ASR
+		ttsConfig = multilingual.TTS
+	} else {
+		asrLanguage := profile.ASRLanguage
+		if req.Overrides != nil && req.Overrides.ASRLanguage != nil {
+			asrLanguage = *req.Overrides.ASRLanguage
+		}
+		asrConfig, err = ResolveASRConfig(config, profile, asrLanguage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get ASR config: %v", err)
+		}
+	}
+
+	// Resolve the LLM provider selected by the invite, the profile or the configuration.
+	// The provider's configured model is used unless the profile selects its own.
+	llmProvider := ""
+	if req.Overrides != nil && req.Overrides.LLMProvider != nil {
+		llmProvider = *req.Overrides.LLMProvider
+	}
+	llmEndpoint, llmParams, err := ResolveLLMConfig(config, profile, llmProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LLM config: %v", err)
+	}
+
+	// Route the agent through the LLM proxy, handing it a signed key instead of the provider's
+	agentName := fmt.Sprintf("agent-%d-%s", time.Now().UnixNano(), randomString(6))
+	if config.LLMProxyURL != "" {
+		if llmEndpoint.Style != LLMStyleOpenAI {
+			return nil, fmt.Errorf("LLM proxy does not support the %s style of the %s provider", llmEndpoint.Style, llmEndpoint.Provider)
+		}
+		proxyKey, err := newLLMProxyKey(config, llmProxyClaims{
+			AgentName:   agentName,
+			ChannelName: req.ChannelName,
+			Profile:     profile.Name,
+			Provider:    llmProvider,
+			Expires:     time.Now().Add(llmProxyKeyLifetime).Unix(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		llmEndpoint = &LLMEndpoint{
+			Provider: llmEndpoint.Provider,
+			URL:      llmProxyURL(config),
+			APIKey:   proxyKey,
+			Style:    LLMStyleOpenAI,
+			Model:    llmEndpoint.Model,
+		}
 	}
 
 	// Set default modalities if not provided
@@ -53,52 +111,44 @@ func (s *ConvoAIService) HandleInviteAgent(req InviteAgentRequest) (*InviteAgent
 
 	// Build the request body for Agora Conversation AI service
 	agoraReq := AgoraStartRequest{
-		Name: fmt.Sprintf("agent-%d-%s", time.Now().UnixNano(), randomString(6)),
+		Name: agentName,
 		Properties: Properties{
 			Channel:         req.ChannelName,
 			Token:           token,
-			AgentRtcUID:     s.config.AgentUID,
+			AgentRtcUID:     config.AgentUID,
 			RemoteRtcUIDs:   getRemoteRtcUIDs(req.RequesterID),
 			EnableStringUID: isStringUID(req.RequesterID),
-			IdleTimeout:     30,
-			ASR: ASR{
-				Language: "en-US",
-				Task:     "conversation",
-			},
+			IdleTimeout:     profile.IdleTimeout,
+			ASR:             *asrConfig,
 			LLM: LLM{
-				URL:             s.config.LLMURL,
-				APIKey:          s.config.LLMToken,
-				SystemMessages:  []SystemMessage{systemMessage},
-				GreetingMessage: "Hello! How can I assist you today?",
-				FailureMessage:  "Please wait a moment.",
-				MaxHistory:      10,
-				Params: LLMParams{
-					Model:       s.config.LLMModel,
-					MaxTokens:   1024,
-					Temperature: 0.7,
-					TopP:        0.95,
-				},
+				URL:              llmEndpoint.URL,
+				APIKey:           llmEndpoint.APIKey,
+				Style:            llmEndpoint.Style,
+				Headers:          llmEndpoint.Headers,
+				SystemMessages:   append([]SystemMessage(nil), profile.SystemMessages...),
+				GreetingMessage:  profile.GreetingMessage,
+				FailureMessage:   profile.FailureMessage,
+				MaxHistory:       profile.MaxHistory,
+				Params:           llmParams,
 				InputModalities:  inputModalities,
 				OutputModalities: outputModalities,
 			},
-			TTS: *ttsConfig,
-			VAD: VAD{
-				SilenceDurationMS:   480,
-				SpeechDurationMS:    15000,
-				Threshold:           0.5,
-				InterruptDurationMS: 160,
-				PrefixPaddingMS:     300,
-			},
-			AdvancedFeatures: Features{
-				EnableAIVAD: false,
-				EnableBHVS:  false,
-			},
+			TTS:              *ttsConfig,
+			VAD:              profile.VAD,
+			AdvancedFeatures: profile.AdvancedFeatures,
 		},
 	}
 
-	// Debug logging
-	prettyJSON, _ := json.MarshalIndent(agoraReq, "", "  ")
-	fmt.Printf("Sending request to start agent: %s\n", string(prettyJSON))
+	// Apply the per-request overrides, already validated against the server-side bounds
+	applyOverrides(&agoraReq.Properties, req.Overrides)
+
+	// Added after the overrides so a system message override doesn't drop it
+	if multilingual != nil {
+		agoraReq.Properties.LLM.SystemMessages = append(agoraReq.Properties.LLM.SystemMessages, multilingual.SystemMessage)
+	}
+
+	// The request holds the LLM and TTS keys, so only the agent is logged
+	log.Printf("Starting agent %s in channel %s", agentName, req.ChannelName)
 
 	// Convert request to JSON
 	jsonData, err := json.Marshal(agoraReq)
@@ -107,8 +157,7 @@ func (s *ConvoAIService) HandleInviteAgent(req InviteAgentRequest) (*InviteAgent
 	}
 
 	// Create the HTTP request
-	url := fmt.Sprintf("%s/%s/join", s.config.BaseURL, s.config.AppID)
-	fmt.Printf("URL: %s\n", url)
+	url := fmt.Sprintf("%s/%s/join", config.BaseURL, config.AppID)
 	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
@@ -118,9 +167,6 @@ func (s *ConvoAIService) HandleInviteAgent(req InviteAgentRequest) (*InviteAgent
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Authorization", s.getBasicAuth())
 
-	// TODO: Remove debug logging
-	fmt.Printf("Request headers: %v\n", httpReq.Header)
-
 	// Send the request using a client with a timeout
 	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(httpReq)
@@ -142,16 +188,56 @@ func (s *ConvoAIService) HandleInviteAgent(req InviteAgentRequest) (*InviteAgent
 		return nil, fmt.Errorf("failed to decode response: %v", err)
 	}
 
-	// Create the response
+	// Create the response, tracking the agent from the status Agora reports until its
+	// notifications arrive
 	response := &InviteAgentResponse{
 		AgentID:  agoraResp["agent_id"].(string),
 		CreateTS: time.Now().Unix(),
-		Status:   "RUNNING",
+	}
+	status, _ := agoraResp["status"].(string)
+	if status == "" {
+		status = AgentStateStarting
+	}
+	response.Status = s.setAgentState(AgentEventInvited, response.AgentID, req.ChannelName, status, "", nil).State
+
+	// Record the session so the agent can be listed, queried and reconciled later.
+	// The agent is already running, so a storage failure is logged rather than returned.
+	err = s.sessions.Save(AgentSession{
+		AgentID:          response.AgentID,
+		Name:             agoraReq.Name,
+		ChannelName:      req.ChannelName,
+		RequesterID:      req.RequesterID,
+		Profile:          profile.Name,
+		CreateTS:         response.CreateTS,
+		InputModalities:  inputModalities,
+		OutputModalities: outputModalities,
+		InstanceID:       s.instanceID,
+		ExpireTS:         response.CreateTS + agentTokenExpirationSeconds,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to record session for agent %s: %v", response.AgentID, err)
 	}
 
 	return response, nil
 }
 
+// agentToken generates the RTC token an agent joins the channel with
+func (s *ConvoAIService) agentToken(channelName string) (string, error) {
+	tokenReq := token_service.TokenRequest{
+		TokenType:         "rtc",
+		Channel:           channelName,
+		Uid:               "0",
+		RtcRole:           "publisher",
+		ExpirationSeconds: agentTokenExpirationSeconds,
+	}
+
+	token, err := s.tokenService.GenRtcToken(tokenReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	return token, nil
+}
+
 // getRemoteRtcUIDs returns the appropriate RemoteRtcUIDs array based on the requesterID
 func getRemoteRtcUIDs(requesterID string) []string {
 	// if requesterID == "0" {
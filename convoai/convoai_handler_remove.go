@@ -2,17 +2,40 @@ package convoai
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"time"
 )
 
+// removeAgentTimeout bounds a single leave call to the Agora API
+const removeAgentTimeout = 10 * time.Second
+
 // HandleRemoveAgent processes the agent removal request
 func (s *ConvoAIService) HandleRemoveAgent(req RemoveAgentRequest) (*RemoveAgentResponse, error) {
+	// Agora discards the conversation history when the agent leaves, so keep it first
+	s.captureAgoraHistory(req.AgentID)
+
+	if err := s.leaveAgent(req.AgentID); err != nil {
+		return nil, err
+	}
+
+	// Return success response
+	response := &RemoveAgentResponse{
+		Success: true,
+		AgentID: req.AgentID,
+	}
+
+	return response, nil
+}
+
+// leaveAgent asks Agora to stop the agent, then forgets its session
+func (s *ConvoAIService) leaveAgent(agentID string) error {
 	// Create the HTTP request
-	url := fmt.Sprintf("%s/%s/agents/%s/leave", s.config.BaseURL, s.config.AppID, req.AgentID)
+	config := s.getConfig()
+	url := fmt.Sprintf("%s/%s/agents/%s/leave", config.BaseURL, config.AppID, agentID)
 	httpReq, err := http.NewRequest("POST", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return fmt.Errorf("failed to create request: %v", err)
 	}
 
 	// Add headers
@@ -21,22 +44,21 @@ func (s *ConvoAIService) HandleRemoveAgent(req RemoveAgentRequest) (*RemoveAgent
 	httpReq.Header.Set("Authorization", auth)
 
 	// Send the request using a client with a timeout
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: removeAgentTimeout}
 	resp, err := client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %v", err)
+		return fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to remove agent: %d", resp.StatusCode)
+		return fmt.Errorf("failed to remove agent: %d", resp.StatusCode)
 	}
 
-	// Return success response
-	response := &RemoveAgentResponse{
-		Success: true,
-		AgentID: req.AgentID,
+	// The agent has left, so forget its session
+	if err := s.sessions.Delete(agentID); err != nil {
+		log.Printf("Warning: failed to delete session for agent %s: %v", agentID, err)
 	}
-
-	return response, nil
+	s.setAgentState(AgentEventLeft, agentID, "", AgentStateStopped, "removed", nil)
+	return nil
 }
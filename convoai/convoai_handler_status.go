@@ -0,0 +1,123 @@
+package convoai
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// HandleListAgents lists the agents Agora reports for the project, merged with local session metadata
+func (s *ConvoAIService) HandleListAgents(req ListAgentsRequest) (*ListAgentsResponse, error) {
+	agoraAgents, err := s.listAgoraAgents(req.ChannelName)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.sessions.List(SessionFilter{ChannelName: req.ChannelName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %v", err)
+	}
+	sessionsByID := make(map[string]AgentSession, len(sessions))
+	for _, session := range sessions {
+		sessionsByID[session.AgentID] = session
+	}
+
+	agents := make([]AgentStatusResponse, 0, len(agoraAgents))
+	for _, agoraAgent := range agoraAgents {
+		session, known := sessionsByID[agoraAgent.AgentID]
+
+		// Agents without local metadata can't be attributed to a requester
+		if req.RequesterID != "" && (!known || session.RequesterID != req.RequesterID) {
+			continue
+		}
+
+		status := newAgentStatusResponse(agoraAgent)
+		if known {
+			mergeSessionMetadata(&status, session)
+		} else if req.ChannelName != "" {
+			status.ChannelName = req.ChannelName
+		}
+		agents = append(agents, status)
+	}
+
+	response := &ListAgentsResponse{
+		Agents: agents,
+		Total:  len(agents),
+	}
+
+	return response, nil
+}
+
+// HandleGetAgent queries the status of a single agent, merged with local session metadata
+func (s *ConvoAIService) HandleGetAgent(agentID string) (*AgentStatusResponse, error) {
+	var agoraAgent AgoraAgentStatus
+	if err := s.agoraRequest("GET", "/agents/"+url.PathEscape(agentID), nil, &agoraAgent); err != nil {
+		return nil, err
+	}
+	if agoraAgent.AgentID == "" {
+		agoraAgent.AgentID = agentID
+	}
+
+	response := newAgentStatusResponse(agoraAgent)
+	session, ok, err := s.sessions.Get(agentID)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		mergeSessionMetadata(&response, session)
+	}
+
+	return &response, nil
+}
+
+// listAgoraAgents pages through the Agora list API, optionally filtered by channel
+func (s *ConvoAIService) listAgoraAgents(channelName string) ([]AgoraAgentStatus, error) {
+	var agents []AgoraAgentStatus
+	cursor := ""
+	for {
+		query := url.Values{}
+		if channelName != "" {
+			query.Set("channel", channelName)
+		}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+
+		path := "/agents"
+		if len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+
+		var page AgoraListResponse
+		if err := s.agoraRequest("GET", path, nil, &page); err != nil {
+			return nil, fmt.Errorf("failed to list agents: %v", err)
+		}
+		agents = append(agents, page.Data.List...)
+
+		// Stop when Agora has no further pages, or keeps returning the same cursor
+		if page.Meta.Cursor == "" || page.Meta.Cursor == cursor || len(page.Data.List) == 0 {
+			break
+		}
+		cursor = page.Meta.Cursor
+	}
+	return agents, nil
+}
+
+// newAgentStatusResponse converts an Agora agent status into the response type
+func newAgentStatusResponse(agent AgoraAgentStatus) AgentStatusResponse {
+	return AgentStatusResponse{
+		AgentID: agent.AgentID,
+		Status:  agent.Status,
+		StartTS: agent.StartTS,
+		StopTS:  agent.StopTS,
+	}
+}
+
+// mergeSessionMetadata adds the locally recorded metadata to an agent status
+func mergeSessionMetadata(status *AgentStatusResponse, session AgentSession) {
+	status.ChannelName = session.ChannelName
+	status.RequesterID = session.RequesterID
+	status.Profile = session.Profile
+	status.CreateTS = session.CreateTS
+	status.InputModalities = session.InputModalities
+	status.OutputModalities = session.OutputModalities
+}
@@ -0,0 +1,67 @@
+package convoai
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestListAndGetAgents(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	router := newTestRouter(service)
+
+	// An agent started outside this server has no local metadata
+	agora.addAgent("EXTERNAL")
+
+	var invited InviteAgentResponse
+	status := doJSON(t, router, "POST", "/agent/invite",
+		`{"requester_id": "user-1", "channel_name": "test-channel", "input_modalities": ["text"]}`, &invited)
+	if status != http.StatusOK {
+		t.Fatalf("invite returned status %d", status)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantTotal int
+	}{
+		{name: "All agents", query: "", wantTotal: 2},
+		{name: "Filter by requester", query: "?requester_id=user-1", wantTotal: 1},
+		{name: "Unknown requester", query: "?requester_id=user-2", wantTotal: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var list ListAgentsResponse
+			if status := doJSON(t, router, "GET", "/agent/list"+tt.query, "", &list); status != http.StatusOK {
+				t.Fatalf("list returned status %d", status)
+			}
+			if list.Total != tt.wantTotal || len(list.Agents) != tt.wantTotal {
+				t.Errorf("list returned %d agents, want %d", list.Total, tt.wantTotal)
+			}
+		})
+	}
+
+	var agent AgentStatusResponse
+	if status := doJSON(t, router, "GET", "/agent/"+invited.AgentID, "", &agent); status != http.StatusOK {
+		t.Fatalf("get returned status %d", status)
+	}
+	if agent.Status != "RUNNING" || agent.RequesterID != "user-1" || agent.ChannelName != "test-channel" {
+		t.Errorf("unexpected agent status: %+v", agent)
+	}
+	if len(agent.InputModalities) != 1 || agent.InputModalities[0] != "text" {
+		t.Errorf("unexpected input modalities: %v", agent.InputModalities)
+	}
+
+	if status := doJSON(t, router, "GET", "/agent/UNKNOWN", "", nil); status != http.StatusNotFound {
+		t.Errorf("get unknown agent returned status %d, want %d", status, http.StatusNotFound)
+	}
+
+	// Removing the agent clears its local metadata
+	if status := doJSON(t, router, "POST", "/agent/remove", `{"agent_id": "`+invited.AgentID+`"}`, nil); status != http.StatusOK {
+		t.Fatalf("remove returned status %d", status)
+	}
+	if _, ok, _ := service.sessions.Get(invited.AgentID); ok {
+		t.Errorf("session for %s still recorded after remove", invited.AgentID)
+	}
+}
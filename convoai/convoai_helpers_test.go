@@ -0,0 +1,244 @@
+package convoai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AgoraIO-Community/convo-ai-go-server/token_service"
+	"github.com/gin-gonic/gin"
+)
+
+const testAppID = "6ce46dd303d54056a52f9a34c13c547e"
+
+// fakeAgora is a local stand-in for the Agora Conversational AI API
+type fakeAgora struct {
+	mu      sync.Mutex
+	server  *httptest.Server
+	agents  map[string]*AgoraAgentStatus
+	joins   []AgoraStartRequest
+	leaves  []string
+	nextID  int
+	failFor map[string]int
+
+	// commands records the speak, interrupt and update calls made to running agents
+	commands []fakeAgentCommand
+
+	// historyDelay slows down the history responses, as a busy Agora would
+	historyDelay time.Duration
+}
+
+// fakeAgentCommand is a command sent to a running agent
+type fakeAgentCommand struct {
+	AgentID string
+	Action  string
+	Body    map[string]interface{}
+}
+
+// agentCommands returns the commands sent to running agents
+func (f *fakeAgora) agentCommands() []fakeAgentCommand {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fakeAgentCommand(nil), f.commands...)
+}
+
+// newFakeAgora starts a fake Agora server that is closed when the test ends
+func newFakeAgora(t *testing.T) *fakeAgora {
+	t.Helper()
+	f := &fakeAgora{
+		agents:  make(map[string]*AgoraAgentStatus),
+		failFor: make(map[string]int),
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// addAgent registers an agent as running without going through join
+func (f *fakeAgora) addAgent(agentID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.agents[agentID] = &AgoraAgentStatus{AgentID: agentID, Status: "RUNNING", StartTS: 1700000000}
+}
+
+// leftAgents returns the agent IDs that received a leave call
+func (f *fakeAgora) leftAgents() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.leaves...)
+}
+
+func (f *fakeAgora) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	historyDelay := f.historyDelay
+	f.mu.Unlock()
+	if strings.HasSuffix(r.URL.Path, "/history") {
+		time.Sleep(historyDelay)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	prefix := "/" + testAppID
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, prefix)
+	if status, ok := f.failFor[path]; ok {
+		w.WriteHeader(status)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && path == "/join":
+		var req AgoraStartRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.joins = append(f.joins, req)
+		f.nextID++
+		agentID := fmt.Sprintf("AGENT%04d", f.nextID)
+		f.agents[agentID] = &AgoraAgentStatus{AgentID: agentID, Status: "RUNNING", StartTS: 1700000000}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"agent_id":  agentID,
+			"create_ts": 1700000000,
+			"status":    "STARTING",
+		})
+
+	case r.Method == http.MethodGet && path == "/agents":
+		var resp AgoraListResponse
+		for _, agent := range f.agents {
+			if agent.Status == "RUNNING" {
+				resp.Data.List = append(resp.Data.List, *agent)
+			}
+		}
+		resp.Data.Count = len(resp.Data.List)
+		resp.Meta.Total = len(resp.Data.List)
+		resp.Status = "ok"
+		json.NewEncoder(w).Encode(resp)
+
+	case strings.HasPrefix(path, "/agents/"):
+		parts := strings.Split(strings.TrimPrefix(path, "/agents/"), "/")
+		agent, ok := f.agents[parts[0]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && len(parts) == 1:
+			json.NewEncoder(w).Encode(agent)
+		case r.Method == http.MethodGet && len(parts) == 2 && parts[1] == "history" && agent.Status == "RUNNING":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"agent_id": agent.AgentID,
+				"status":   agent.Status,
+				"contents": []map[string]string{
+					{"role": "user", "content": "Where is my order?"},
+					{"role": "assistant", "content": "It shipped today."},
+				},
+			})
+		case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "leave":
+			agent.Status = "STOPPED"
+			f.leaves = append(f.leaves, agent.AgentID)
+			w.Write([]byte("{}"))
+		case r.Method == http.MethodPost && len(parts) == 2 && (parts[1] == "speak" || parts[1] == "interrupt" || parts[1] == "update"):
+			if agent.Status != "RUNNING" {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			command := fakeAgentCommand{AgentID: agent.AgentID, Action: parts[1]}
+			if err := json.NewDecoder(r.Body).Decode(&command.Body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.commands = append(f.commands, command)
+			w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// newTestConfig returns a valid configuration pointing at the given base URL
+func newTestConfig(baseURL string) *ConvoAIConfig {
+	return &ConvoAIConfig{
+		AppID:          testAppID,
+		AppCertificate: "77be7e16f7482cef9fe796205b85831e",
+		CustomerID:     "customer",
+		CustomerSecret: "secret",
+		BaseURL:        baseURL,
+		AgentUID:       "agent-uid",
+		LLMProvider:    string(LLMProviderOpenAI),
+		LLM: map[LLMProviderName]LLMProviderConfig{
+			LLMProviderOpenAI: &OpenAILLMConfig{
+				URL:    "https://llm.example.com/v1/chat/completions",
+				APIKey: "llm-token",
+				Model:  "gpt-4o-mini",
+			},
+		},
+		TTSVendor: string(TTSVendorMicrosoft),
+		TTS: map[TTSVendor]TTSVendorConfig{
+			TTSVendorMicrosoft: &MicrosoftTTSConfig{
+				Key:       "ms-key",
+				Region:    "eastus",
+				VoiceName: "en-US-AndrewMultilingualNeural",
+				Rate:      "1.0",
+				Volume:    "100.0",
+			},
+		},
+	}
+}
+
+// newTestService creates a ConvoAIService backed by the fake Agora server, applying the
+// configure functions to the test configuration first. The service is closed when the
+// test ends, and retries its webhook deliveries without waiting.
+func newTestService(t *testing.T, agora *fakeAgora, configure ...func(*ConvoAIConfig)) *ConvoAIService {
+	t.Helper()
+	config := newTestConfig(agora.server.URL)
+	for _, fn := range configure {
+		fn(config)
+	}
+	tokenService := token_service.NewTokenService(config.AppID, config.AppCertificate)
+	service := NewConvoAIService(config, tokenService, NewMemorySessionStore())
+	service.webhooks.backoff = time.Millisecond
+	t.Cleanup(func() { service.Close() })
+	return service
+}
+
+// newTestRouter registers the service routes on a gin engine in test mode
+func newTestRouter(s *ConvoAIService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	s.RegisterRoutes(router)
+	return router
+}
+
+// doJSON performs a request against the router and decodes the JSON response
+func doJSON(t *testing.T, router *gin.Engine, method, path, body string, out interface{}) int {
+	t.Helper()
+	var req *http.Request
+	if body != "" {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if out != nil {
+		if err := json.Unmarshal(rr.Body.Bytes(), out); err != nil {
+			t.Fatalf("Error unmarshaling response %q: %v", rr.Body.String(), err)
+		}
+	}
+	return rr.Code
+}
@@ -0,0 +1,167 @@
+package convoai
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultLLMProvider is used when LLM_PROVIDER is not set
+const DefaultLLMProvider = LLMProviderOpenAI
+
+// Request styles the Agora engine uses to talk to an LLM
+const (
+	LLMStyleOpenAI    = "openai"
+	LLMStyleAnthropic = "anthropic"
+	LLMStyleGemini    = "gemini"
+)
+
+// LLMProvider describes an LLM provider the agent can use. Like TTS and ASR vendors,
+// providers register themselves with RegisterLLMProvider.
+type LLMProvider interface {
+	// Name returns the name used in LLM_PROVIDER, profiles and invites
+	Name() LLMProviderName
+	// LoadEnv reads the provider configuration from the environment, returning nil
+	// when the provider isn't configured
+	LoadEnv(getenv func(string) string) LLMProviderConfig
+	// NewConfig returns an empty configuration to decode profile params into
+	NewConfig() LLMProviderConfig
+}
+
+// LLMProviderConfig is the configuration of a single LLM provider
+type LLMProviderConfig interface {
+	// Validate reports missing or invalid settings
+	Validate() error
+	// Endpoint builds the URL, auth and request style the Agora engine uses to call
+	// the provider. An empty model selects the provider's configured model.
+	Endpoint(model string) (*LLMEndpoint, error)
+}
+
+// LLMEndpoint is how the Agora engine reaches an LLM provider
+type LLMEndpoint struct {
+	Provider LLMProviderName
+	URL      string
+	APIKey   string
+	Style    string
+	Headers  map[string]string
+	Model    string
+}
+
+// llmProviders holds the registered LLM providers
+var llmProviders = struct {
+	sync.RWMutex
+	byName map[LLMProviderName]LLMProvider
+}{byName: make(map[LLMProviderName]LLMProvider)}
+
+// RegisterLLMProvider makes an LLM provider available, replacing any provider
+// previously registered under the same name
+func RegisterLLMProvider(provider LLMProvider) {
+	llmProviders.Lock()
+	defer llmProviders.Unlock()
+	llmProviders.byName[provider.Name()] = provider
+}
+
+// GetLLMProvider returns the provider registered under the name
+func GetLLMProvider(name LLMProviderName) (LLMProvider, bool) {
+	llmProviders.RLock()
+	defer llmProviders.RUnlock()
+	provider, ok := llmProviders.byName[name]
+	return provider, ok
+}
+
+// LLMProviders returns the registered providers, sorted by name
+func LLMProviders() []LLMProvider {
+	llmProviders.RLock()
+	defer llmProviders.RUnlock()
+
+	providers := make([]LLMProvider, 0, len(llmProviders.byName))
+	for _, provider := range llmProviders.byName {
+		providers = append(providers, provider)
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name() < providers[j].Name() })
+	return providers
+}
+
+// LoadLLMConfigs reads the configuration of every registered provider from the environment
+func LoadLLMConfigs(getenv func(string) string) map[LLMProviderName]LLMProviderConfig {
+	configs := make(map[LLMProviderName]LLMProviderConfig)
+	for _, provider := range LLMProviders() {
+		if config := provider.LoadEnv(getenv); config != nil {
+			configs[provider.Name()] = config
+		}
+	}
+	return configs
+}
+
+// ResolveLLMConfig returns the endpoint and params for a profile. The provider is taken
+// from the invite when set, then the profile, then LLM_PROVIDER. The params are checked
+// against the provider's request style and converted to the form it expects.
+func ResolveLLMConfig(config *ConvoAIConfig, profile *AgentProfile, providerName string) (*LLMEndpoint, LLMParams, error) {
+	var params LLMParams
+	var overlay map[string]interface{}
+	name := LLMProviderName(config.LLMProvider)
+	if profile != nil {
+		params = profile.LLMParams
+		if profile.LLM != nil {
+			if profile.LLM.Provider != "" {
+				name = LLMProviderName(profile.LLM.Provider)
+			}
+			overlay = profile.LLM.Params
+		}
+	}
+	if providerName != "" {
+		name = LLMProviderName(providerName)
+	}
+	if name == "" {
+		name = DefaultLLMProvider
+	}
+
+	provider, ok := GetLLMProvider(name)
+	if !ok {
+		return nil, LLMParams{}, fmt.Errorf("unsupported LLM provider: %s", name)
+	}
+	base := config.LLM[name]
+	if base == nil {
+		return nil, LLMParams{}, fmt.Errorf("missing %s LLM configuration", name)
+	}
+
+	providerConfig, err := overlayVendorConfig(provider.NewConfig, base, overlay)
+	if err != nil {
+		return nil, LLMParams{}, fmt.Errorf("invalid %s LLM params: %v", name, err)
+	}
+
+	endpoint, err := providerConfig.Endpoint(params.Model)
+	if err != nil {
+		return nil, LLMParams{}, err
+	}
+	endpoint.Provider = name
+	params.Model = endpoint.Model
+
+	if err := adaptLLMParams(endpoint.Style, &params); err != nil {
+		return nil, LLMParams{}, fmt.Errorf("%s LLM: %v", name, err)
+	}
+	return endpoint, params, nil
+}
+
+// adaptLLMParams converts the OpenAI-style params into the request style of the provider
+func adaptLLMParams(style string, params *LLMParams) error {
+	switch style {
+	case LLMStyleOpenAI:
+		return nil
+	case LLMStyleAnthropic:
+		if params.ResponseFormat != nil || params.PresencePenalty != nil || params.FrequencyPenalty != nil {
+			return fmt.Errorf("response_format, presence_penalty and frequency_penalty are not supported with the %s style", style)
+		}
+		// Anthropic names the stop list stop_sequences
+		params.StopSequences = params.Stop
+		params.Stop = nil
+		return nil
+	case LLMStyleGemini:
+		if params.ResponseFormat != nil || params.PresencePenalty != nil || params.FrequencyPenalty != nil || len(params.Stop) > 0 {
+			return fmt.Errorf("response_format, stop, presence_penalty and frequency_penalty are not supported with the %s style", style)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported LLM style: %s", style)
+	}
+}
@@ -0,0 +1,69 @@
+package convoai
+
+import "errors"
+
+// Anthropic messages API endpoint and version
+const (
+	anthropicMessagesURL = "https://api.anthropic.com/v1/messages"
+	anthropicAPIVersion  = "2023-06-01"
+)
+
+func init() {
+	RegisterLLMProvider(anthropicLLMProvider{})
+}
+
+// AnthropicLLMConfig holds Anthropic specific configuration
+type AnthropicLLMConfig struct {
+	APIKey string `json:"api_key"`
+	Model  string `json:"model"`
+}
+
+// anthropicLLMProvider registers Anthropic
+type anthropicLLMProvider struct{}
+
+func (anthropicLLMProvider) Name() LLMProviderName {
+	return LLMProviderAnthropic
+}
+
+func (anthropicLLMProvider) LoadEnv(getenv func(string) string) LLMProviderConfig {
+	key := getenv("ANTHROPIC_API_KEY")
+	if key == "" {
+		return nil
+	}
+	return &AnthropicLLMConfig{
+		APIKey: key,
+		Model:  getenv("ANTHROPIC_MODEL"),
+	}
+}
+
+func (anthropicLLMProvider) NewConfig() LLMProviderConfig {
+	return &AnthropicLLMConfig{}
+}
+
+// Validate checks the Anthropic configuration is complete
+func (c *AnthropicLLMConfig) Validate() error {
+	if c.APIKey == "" || c.Model == "" {
+		return errors.New("anthropic LLM configuration is incomplete")
+	}
+	return nil
+}
+
+// Endpoint returns the messages endpoint, authenticated with the x-api-key header
+func (c *AnthropicLLMConfig) Endpoint(model string) (*LLMEndpoint, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if model == "" {
+		model = c.Model
+	}
+	return &LLMEndpoint{
+		URL:    anthropicMessagesURL,
+		APIKey: c.APIKey,
+		Style:  LLMStyleAnthropic,
+		Headers: map[string]string{
+			"x-api-key":         c.APIKey,
+			"anthropic-version": anthropicAPIVersion,
+		},
+		Model: model,
+	}, nil
+}
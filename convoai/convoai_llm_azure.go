@@ -0,0 +1,81 @@
+package convoai
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// defaultAzureOpenAIAPIVersion is used when AZURE_OPENAI_API_VERSION is not set
+const defaultAzureOpenAIAPIVersion = "2024-10-21"
+
+func init() {
+	RegisterLLMProvider(azureLLMProvider{})
+}
+
+// AzureLLMConfig holds Azure OpenAI specific configuration. Azure selects the model
+// through the deployment, so Model is only reported in the request params.
+type AzureLLMConfig struct {
+	BaseURL    string `json:"endpoint"`
+	APIKey     string `json:"api_key"`
+	Deployment string `json:"deployment"`
+	APIVersion string `json:"api_version,omitempty"`
+	Model      string `json:"model,omitempty"`
+}
+
+// azureLLMProvider registers Azure OpenAI
+type azureLLMProvider struct{}
+
+func (azureLLMProvider) Name() LLMProviderName {
+	return LLMProviderAzure
+}
+
+func (azureLLMProvider) LoadEnv(getenv func(string) string) LLMProviderConfig {
+	key := getenv("AZURE_OPENAI_API_KEY")
+	if key == "" {
+		return nil
+	}
+	return &AzureLLMConfig{
+		BaseURL:    getenv("AZURE_OPENAI_ENDPOINT"),
+		APIKey:     key,
+		Deployment: getenv("AZURE_OPENAI_DEPLOYMENT"),
+		APIVersion: getenv("AZURE_OPENAI_API_VERSION"),
+		Model:      getenv("AZURE_OPENAI_MODEL"),
+	}
+}
+
+func (azureLLMProvider) NewConfig() LLMProviderConfig {
+	return &AzureLLMConfig{}
+}
+
+// Validate checks the Azure OpenAI configuration is complete
+func (c *AzureLLMConfig) Validate() error {
+	if c.BaseURL == "" || c.APIKey == "" || c.Deployment == "" {
+		return errors.New("azure LLM configuration is incomplete")
+	}
+	return nil
+}
+
+// Endpoint returns the deployment's chat completions endpoint. Azure authenticates
+// with an api-key header rather than a bearer token.
+func (c *AzureLLMConfig) Endpoint(model string) (*LLMEndpoint, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	apiVersion := c.APIVersion
+	if apiVersion == "" {
+		apiVersion = defaultAzureOpenAIAPIVersion
+	}
+	if model == "" {
+		model = c.Model
+	}
+	return &LLMEndpoint{
+		URL: fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+			strings.TrimSuffix(c.BaseURL, "/"), url.PathEscape(c.Deployment), url.QueryEscape(apiVersion)),
+		APIKey:  c.APIKey,
+		Style:   LLMStyleOpenAI,
+		Headers: map[string]string{"api-key": c.APIKey},
+		Model:   model,
+	}, nil
+}
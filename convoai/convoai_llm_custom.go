@@ -0,0 +1,90 @@
+package convoai
+
+import (
+	"errors"
+	"fmt"
+)
+
+func init() {
+	RegisterLLMProvider(customLLMProvider{})
+}
+
+// CustomLLMConfig holds the configuration of a self-hosted or third-party endpoint
+// speaking one of the request styles the Agora engine supports
+type CustomLLMConfig struct {
+	URL        string `json:"url"`
+	APIKey     string `json:"api_key,omitempty"`
+	Model      string `json:"model,omitempty"`
+	Style      string `json:"style,omitempty"`
+	AuthHeader string `json:"auth_header,omitempty"`
+}
+
+// customLLMProvider registers custom endpoints
+type customLLMProvider struct{}
+
+func (customLLMProvider) Name() LLMProviderName {
+	return LLMProviderCustom
+}
+
+func (customLLMProvider) LoadEnv(getenv func(string) string) LLMProviderConfig {
+	url := getenv("CUSTOM_LLM_URL")
+	if url == "" {
+		return nil
+	}
+	return &CustomLLMConfig{
+		URL:        url,
+		APIKey:     getenv("CUSTOM_LLM_API_KEY"),
+		Model:      getenv("CUSTOM_LLM_MODEL"),
+		Style:      getenv("CUSTOM_LLM_STYLE"),
+		AuthHeader: getenv("CUSTOM_LLM_AUTH_HEADER"),
+	}
+}
+
+func (customLLMProvider) NewConfig() LLMProviderConfig {
+	return &CustomLLMConfig{}
+}
+
+// Validate checks the custom endpoint is set and its style is supported
+func (c *CustomLLMConfig) Validate() error {
+	if c.URL == "" {
+		return errors.New("custom LLM configuration is incomplete")
+	}
+	switch c.style() {
+	case LLMStyleOpenAI, LLMStyleAnthropic, LLMStyleGemini:
+	default:
+		return fmt.Errorf("unsupported custom LLM style: %s", c.Style)
+	}
+	if c.AuthHeader != "" && c.APIKey == "" {
+		return errors.New("custom LLM auth header requires an API key")
+	}
+	return nil
+}
+
+// Endpoint returns the configured endpoint. The key is sent as a bearer token, and
+// also in AuthHeader when the endpoint expects it in a header of its own.
+func (c *CustomLLMConfig) Endpoint(model string) (*LLMEndpoint, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if model == "" {
+		model = c.Model
+	}
+	endpoint := &LLMEndpoint{
+		URL:    c.URL,
+		APIKey: c.APIKey,
+		Style:  c.style(),
+		Model:  model,
+	}
+	if c.AuthHeader != "" {
+		endpoint.Headers = map[string]string{c.AuthHeader: c.APIKey}
+	}
+	return endpoint, nil
+}
+
+// style returns the request style, defaulting to OpenAI
+func (c *CustomLLMConfig) style() string {
+	if c.Style == "" {
+		return LLMStyleOpenAI
+	}
+	return c.Style
+}
@@ -0,0 +1,68 @@
+package convoai
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// geminiAPIBaseURL is the Gemini API the streaming endpoint is built from
+const geminiAPIBaseURL = "https://generativelanguage.googleapis.com/v1beta/models"
+
+func init() {
+	RegisterLLMProvider(geminiLLMProvider{})
+}
+
+// GeminiLLMConfig holds Google Gemini specific configuration
+type GeminiLLMConfig struct {
+	APIKey string `json:"api_key"`
+	Model  string `json:"model"`
+}
+
+// geminiLLMProvider registers Google Gemini
+type geminiLLMProvider struct{}
+
+func (geminiLLMProvider) Name() LLMProviderName {
+	return LLMProviderGemini
+}
+
+func (geminiLLMProvider) LoadEnv(getenv func(string) string) LLMProviderConfig {
+	key := getenv("GEMINI_API_KEY")
+	if key == "" {
+		return nil
+	}
+	return &GeminiLLMConfig{
+		APIKey: key,
+		Model:  getenv("GEMINI_MODEL"),
+	}
+}
+
+func (geminiLLMProvider) NewConfig() LLMProviderConfig {
+	return &GeminiLLMConfig{}
+}
+
+// Validate checks the Gemini configuration is complete
+func (c *GeminiLLMConfig) Validate() error {
+	if c.APIKey == "" || c.Model == "" {
+		return errors.New("gemini LLM configuration is incomplete")
+	}
+	return nil
+}
+
+// Endpoint returns the model's streaming endpoint. Gemini takes the model in the
+// URL and the key as a query parameter.
+func (c *GeminiLLMConfig) Endpoint(model string) (*LLMEndpoint, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if model == "" {
+		model = c.Model
+	}
+	return &LLMEndpoint{
+		URL: fmt.Sprintf("%s/%s:streamGenerateContent?alt=sse&key=%s",
+			geminiAPIBaseURL, url.PathEscape(model), url.QueryEscape(c.APIKey)),
+		APIKey: c.APIKey,
+		Style:  LLMStyleGemini,
+		Model:  model,
+	}, nil
+}
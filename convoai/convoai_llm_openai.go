@@ -0,0 +1,69 @@
+package convoai
+
+import "errors"
+
+// openAIChatCompletionsURL is the OpenAI endpoint used when LLM_URL is not set
+const openAIChatCompletionsURL = "https://api.openai.com/v1/chat/completions"
+
+func init() {
+	RegisterLLMProvider(openAILLMProvider{})
+}
+
+// OpenAILLMConfig holds the configuration of OpenAI, or any OpenAI-compatible
+// endpoint set through LLM_URL
+type OpenAILLMConfig struct {
+	URL    string `json:"url,omitempty"`
+	APIKey string `json:"api_key"`
+	Model  string `json:"model"`
+}
+
+// openAILLMProvider registers OpenAI
+type openAILLMProvider struct{}
+
+func (openAILLMProvider) Name() LLMProviderName {
+	return LLMProviderOpenAI
+}
+
+func (openAILLMProvider) LoadEnv(getenv func(string) string) LLMProviderConfig {
+	key := getenv("LLM_TOKEN")
+	if key == "" {
+		return nil
+	}
+	return &OpenAILLMConfig{
+		URL:    getenv("LLM_URL"),
+		APIKey: key,
+		Model:  getenv("LLM_MODEL"),
+	}
+}
+
+func (openAILLMProvider) NewConfig() LLMProviderConfig {
+	return &OpenAILLMConfig{}
+}
+
+// Validate checks the OpenAI configuration is complete
+func (c *OpenAILLMConfig) Validate() error {
+	if c.APIKey == "" {
+		return errors.New("openai LLM configuration is incomplete")
+	}
+	return nil
+}
+
+// Endpoint returns the chat completions endpoint with bearer auth
+func (c *OpenAILLMConfig) Endpoint(model string) (*LLMEndpoint, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	url := c.URL
+	if url == "" {
+		url = openAIChatCompletionsURL
+	}
+	if model == "" {
+		model = c.Model
+	}
+	return &LLMEndpoint{
+		URL:    url,
+		APIKey: c.APIKey,
+		Style:  LLMStyleOpenAI,
+		Model:  model,
+	}, nil
+}
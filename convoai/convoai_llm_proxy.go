@@ -0,0 +1,637 @@
+package convoai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LLMProxyPath is the route the agent's LLM URL points at when the proxy is enabled
+const LLMProxyPath = "/llm/chat/completions"
+
+// LLM proxy limits
+const (
+	llmProxyRateWindow     = time.Minute
+	maxLLMProxyRequestSize = 4 << 20
+	maxLLMProxyContextSize = 20
+	// llmProxyKeyLifetime bounds how long a leaked agent key stays usable, as Agora has
+	// no way to hand a running agent a new one
+	llmProxyKeyLifetime = 24 * time.Hour
+)
+
+// llmProxyClient forwards requests upstream. There is no overall timeout, as streamed
+// completions can run long; the request is cancelled when the agent disconnects.
+var llmProxyClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: 60 * time.Second,
+	},
+}
+
+// llmProxyClaims identify the agent calling the proxy. They are signed into the
+// agent's API key at invite, so the proxy needs no lookup to route a request. The key
+// is accepted only until it expires and while the agent's session exists, so it stops
+// working when the agent leaves.
+type llmProxyClaims struct {
+	AgentName   string `json:"n"`
+	ChannelName string `json:"c"`
+	Profile     string `json:"p,omitempty"`
+	Provider    string `json:"l,omitempty"`
+	Expires     int64  `json:"e"`
+}
+
+// LLMTurn is one request and reply relayed by the LLM proxy
+type LLMTurn struct {
+	AgentID       string
+	AgentName     string
+	ChannelName   string
+	Model         string
+	Retrieved     int
+	UserText      string
+	AssistantText string
+	ToolCalls     []string
+	StatusCode    int
+	Duration      time.Duration
+}
+
+// ChannelContextRequest sets the system messages injected into a channel's LLM requests
+type ChannelContextRequest struct {
+	SystemMessages []SystemMessage `json:"system_messages"`
+}
+
+// channelContexts holds the per-channel system messages injected by the proxy
+type channelContexts struct {
+	mu        sync.RWMutex
+	byChannel map[string][]SystemMessage
+}
+
+// get returns the context of the channel
+func (c *channelContexts) get(channel string) []SystemMessage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.byChannel[channel]
+}
+
+// set replaces the context of the channel, clearing it when messages is empty
+func (c *channelContexts) set(channel string, messages []SystemMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byChannel == nil {
+		c.byChannel = make(map[string][]SystemMessage)
+	}
+	if len(messages) == 0 {
+		delete(c.byChannel, channel)
+		return
+	}
+	c.byChannel[channel] = messages
+}
+
+// rateLimiter counts requests per key in fixed windows
+type rateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// allow records a request for key and reports whether it is within the limit.
+// A limit of zero or less allows everything.
+func (r *rateLimiter) allow(key string, limit int, window time.Duration, now time.Time) bool {
+	if limit <= 0 {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.windows == nil {
+		r.windows = make(map[string]*rateWindow)
+	}
+	w, ok := r.windows[key]
+	if !ok || now.Sub(w.start) >= window {
+		// Drop expired windows so channels that ended don't accumulate
+		for k, old := range r.windows {
+			if now.Sub(old.start) >= window {
+				delete(r.windows, k)
+			}
+		}
+		w = &rateWindow{start: now}
+		r.windows[key] = w
+	}
+	if w.count >= limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// llmProxyURL returns the URL the agent calls when the proxy is enabled
+func llmProxyURL(config *ConvoAIConfig) string {
+	return strings.TrimSuffix(config.LLMProxyURL, "/") + LLMProxyPath
+}
+
+// newLLMProxyKey signs the claims into the key the agent sends to the proxy
+func newLLMProxyKey(config *ConvoAIConfig, claims llmProxyClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal proxy claims: %v", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + signLLMProxyPayload(config, encoded), nil
+}
+
+// parseLLMProxyKey verifies the key and returns its claims, rejecting expired keys
+func parseLLMProxyKey(config *ConvoAIConfig, key string, now time.Time) (*llmProxyClaims, error) {
+	encoded, signature, ok := strings.Cut(key, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signLLMProxyPayload(config, encoded))) {
+		return nil, errors.New("invalid proxy key")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("invalid proxy key")
+	}
+	var claims llmProxyClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.ChannelName == "" {
+		return nil, errors.New("invalid proxy key")
+	}
+	if now.Unix() >= claims.Expires {
+		return nil, errors.New("expired proxy key")
+	}
+	return &claims, nil
+}
+
+// signLLMProxyPayload signs the encoded claims with the app certificate
+func signLLMProxyPayload(config *ConvoAIConfig, encoded string) string {
+	mac := hmac.New(sha256.New, []byte(config.AppCertificate))
+	mac.Write([]byte("llm-proxy:" + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// LLMChatCompletions relays an agent's chat completion request to the real provider,
+// injecting the channel context, running any tool calls, and streaming the reply back
+func (s *ConvoAIService) LLMChatCompletions(c *gin.Context) {
+	config := s.getConfig()
+
+	key := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	claims, err := parseLLMProxyKey(config, key, time.Now())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	agentID := s.proxiedAgentID(claims)
+	if current, _ := s.agentStates.get(agentID); agentID == "" || isAgentEnded(current.State) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "proxy key of an agent that is no longer running"})
+		return
+	}
+
+	if !s.llmRateLimiter.allow(claims.ChannelName, config.LLMProxyRateLimit, llmProxyRateWindow, time.Now()) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded for channel " + claims.ChannelName})
+		return
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(http.MaxBytesReader(c.Writer, c.Request.Body, maxLLMProxyRequestSize)).Decode(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+	profile, err := lookupProfile(config, claims.Profile)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	endpoint, _, err := ResolveLLMConfig(config, profile, claims.Provider)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Inject the channel context, then the knowledge base snippets for the latest user turn
+	messages, _ := body["messages"].([]interface{})
+	userText := lastUserText(messages)
+	retrieved := retrieveContext(c.Request.Context(), config, profile, userText)
+	inject := append(append([]SystemMessage(nil), s.channelContexts.get(claims.ChannelName)...), retrieved...)
+	body["messages"] = injectSystemMessages(messages, inject)
+
+	turn := LLMTurn{
+		AgentID:     agentID,
+		AgentName:   claims.AgentName,
+		ChannelName: claims.ChannelName,
+		Retrieved:   len(retrieved),
+		UserText:    userText,
+	}
+	turn.Model, _ = body["model"].(string)
+
+	// A new user turn means the agent finished speaking, or was interrupted
+	if current, ok := s.agentStates.get(turn.AgentID); ok && current.State == AgentStateSpeaking {
+		s.setAgentState(AgentEventListening, turn.AgentID, "", AgentStateRunning, "", nil)
+	}
+	start := time.Now()
+	defer func() {
+		turn.Duration = time.Since(start)
+		s.recordLLMTurn(turn)
+	}()
+
+	if err := s.forwardChatCompletion(c, endpoint, body, s.availableTools(config, profile), &turn); err != nil {
+		log.Printf("LLM proxy error for channel %s: %v", claims.ChannelName, err)
+	}
+}
+
+// completionReply is the assistant message of a completion, streamed or not
+type completionReply struct {
+	Content   string
+	ToolCalls []ToolCall
+}
+
+// assistantMessage returns the reply as a message to send back with the tool results
+func (r completionReply) assistantMessage() map[string]interface{} {
+	calls := make([]interface{}, 0, len(r.ToolCalls))
+	for _, call := range r.ToolCalls {
+		calls = append(calls, map[string]interface{}{
+			"id":   call.ID,
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":      call.Name,
+				"arguments": string(call.Arguments),
+			},
+		})
+	}
+	message := map[string]interface{}{"role": "assistant", "content": nil, "tool_calls": calls}
+	if r.Content != "" {
+		message["content"] = r.Content
+	}
+	return message
+}
+
+// forwardChatCompletion sends the request upstream and copies the reply to the agent.
+// When tools are available they are advertised in the request, and any tool calls in
+// the reply are run and sent back upstream until the LLM gives a final answer. Only
+// the final answer reaches the agent; the status, text and tools called are recorded
+// in the turn.
+func (s *ConvoAIService) forwardChatCompletion(c *gin.Context, endpoint *LLMEndpoint, body map[string]interface{}, tools map[string]Tool, turn *LLMTurn) error {
+	if endpoint.Style != LLMStyleOpenAI {
+		turn.StatusCode = http.StatusBadGateway
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("LLM proxy does not support the %s style", endpoint.Style)})
+		return nil
+	}
+	if len(tools) > 0 {
+		existing, _ := body["tools"].([]interface{})
+		body["tools"] = append(existing, toolDefinitions(tools)...)
+	}
+
+	streaming := false
+	var text strings.Builder
+	for round := 0; ; round++ {
+		// Tool calls are held back and run while rounds remain; the last round forbids them
+		hold := len(tools) > 0 && round < maxToolRounds
+		if len(tools) > 0 && !hold {
+			body["tool_choice"] = "none"
+		}
+
+		resp, err := sendChatCompletion(c.Request.Context(), endpoint, body)
+		if err != nil {
+			turn.StatusCode = http.StatusBadGateway
+			if !streaming {
+				c.JSON(http.StatusBadGateway, gin.H{"error": "LLM provider unavailable"})
+			}
+			return err
+		}
+		turn.StatusCode = resp.StatusCode
+
+		var reply completionReply
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+			// Pass the event stream through line by line, flushing so the agent can start speaking early
+			if !streaming {
+				c.Header("Content-Type", resp.Header.Get("Content-Type"))
+				c.Header("Cache-Control", "no-cache")
+				c.Status(resp.StatusCode)
+				streaming = true
+			}
+			reply, err = relayCompletionStream(c, resp.Body, hold)
+		} else {
+			var data []byte
+			data, err = io.ReadAll(resp.Body)
+			if err == nil {
+				reply = parseCompletion(data)
+				if streaming {
+					// The agent already has an event stream, so an upstream error can only end it
+					err = fmt.Errorf("LLM provider returned status %d after tool calls: %s", resp.StatusCode, data)
+				} else if !hold || len(reply.ToolCalls) == 0 {
+					c.Header("Content-Type", resp.Header.Get("Content-Type"))
+					c.Status(resp.StatusCode)
+					c.Writer.Write(data)
+				}
+			} else {
+				err = fmt.Errorf("failed to read LLM response: %v", err)
+			}
+		}
+		resp.Body.Close()
+
+		text.WriteString(reply.Content)
+		turn.AssistantText = text.String()
+		if err != nil {
+			return err
+		}
+		if !hold || len(reply.ToolCalls) == 0 {
+			return nil
+		}
+
+		messages, _ := body["messages"].([]interface{})
+		messages = append(messages, reply.assistantMessage())
+		for _, call := range reply.ToolCalls {
+			call.AgentName = turn.AgentName
+			call.ChannelName = turn.ChannelName
+			turn.ToolCalls = append(turn.ToolCalls, call.Name)
+			messages = append(messages, map[string]interface{}{
+				"role":         "tool",
+				"tool_call_id": call.ID,
+				"content":      runToolCall(c.Request.Context(), tools, call),
+			})
+		}
+		body["messages"] = messages
+	}
+}
+
+// sendChatCompletion posts the request to the provider
+func sendChatCompletion(ctx context.Context, endpoint *LLMEndpoint, body map[string]interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Providers with auth headers of their own, such as Azure's api-key, get only those
+	if len(endpoint.Headers) > 0 {
+		for name, value := range endpoint.Headers {
+			req.Header.Set(name, value)
+		}
+	} else if endpoint.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+endpoint.APIKey)
+	}
+
+	resp, err := llmProxyClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach LLM provider: %v", err)
+	}
+	return resp, nil
+}
+
+// relayCompletionStream copies a completion event stream to the agent and returns the
+// reply it carried. With hold set, the events carrying tool calls and the final [DONE]
+// are kept back, so the agent only sees the stream end once the final answer is done.
+func relayCompletionStream(c *gin.Context, stream io.Reader, hold bool) (completionReply, error) {
+	var reply completionReply
+	var content strings.Builder
+	var calls streamToolCalls
+	skipBlank := false
+
+	reader := bufio.NewReader(stream)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			forward := true
+			trimmed := bytes.TrimSpace(line)
+			if data, ok := bytes.CutPrefix(trimmed, []byte("data:")); ok {
+				data = bytes.TrimSpace(data)
+				if string(data) == "[DONE]" {
+					forward = !hold || len(calls) == 0
+				} else {
+					delta := parseStreamChunk(data)
+					content.WriteString(delta.Content)
+					calls.add(delta.ToolCalls)
+					forward = !hold || (len(delta.ToolCalls) == 0 && delta.FinishReason != "tool_calls")
+				}
+				skipBlank = !forward
+			} else if len(trimmed) == 0 && skipBlank {
+				// The blank line ending a held event
+				forward = false
+				skipBlank = false
+			}
+			if forward {
+				c.Writer.Write(line)
+				c.Writer.Flush()
+			}
+		}
+		if err != nil {
+			reply.Content = content.String()
+			reply.ToolCalls = newToolCalls(calls)
+			if err != io.EOF {
+				return reply, fmt.Errorf("failed to read LLM stream: %v", err)
+			}
+			return reply, nil
+		}
+	}
+}
+
+// completionToolCall is a tool call in the OpenAI completion format
+type completionToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// streamToolCalls assembles the tool calls spread over the chunks of a stream
+type streamToolCalls []completionToolCall
+
+// add merges the tool call fragments of one chunk, matched by index
+func (s *streamToolCalls) add(fragments []completionToolCall) {
+	for _, fragment := range fragments {
+		i := 0
+		for i < len(*s) && (*s)[i].Index != fragment.Index {
+			i++
+		}
+		if i == len(*s) {
+			*s = append(*s, completionToolCall{Index: fragment.Index})
+		}
+		call := &(*s)[i]
+		if fragment.ID != "" {
+			call.ID = fragment.ID
+		}
+		call.Function.Name += fragment.Function.Name
+		call.Function.Arguments += fragment.Function.Arguments
+	}
+}
+
+// newToolCalls converts tool calls from the completion format
+func newToolCalls(calls []completionToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	result := make([]ToolCall, 0, len(calls))
+	for _, call := range calls {
+		result = append(result, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: json.RawMessage(call.Function.Arguments),
+		})
+	}
+	return result
+}
+
+// parseCompletion returns the assistant message of a non-streamed completion
+func parseCompletion(data []byte) completionReply {
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content   string               `json:"content"`
+				ToolCalls []completionToolCall `json:"tool_calls"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if json.Unmarshal(data, &completion) != nil || len(completion.Choices) == 0 {
+		return completionReply{}
+	}
+	message := completion.Choices[0].Message
+	return completionReply{Content: message.Content, ToolCalls: newToolCalls(message.ToolCalls)}
+}
+
+// streamChunk is the part of a completion stream event the proxy reads
+type streamChunk struct {
+	Content      string
+	ToolCalls    []completionToolCall
+	FinishReason string
+}
+
+// parseStreamChunk returns the delta carried by the data of one stream event
+func parseStreamChunk(data []byte) streamChunk {
+	var chunk struct {
+		Choices []struct {
+			Delta struct {
+				Content   string               `json:"content"`
+				ToolCalls []completionToolCall `json:"tool_calls"`
+			} `json:"delta"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if json.Unmarshal(data, &chunk) != nil || len(chunk.Choices) == 0 {
+		return streamChunk{}
+	}
+	choice := chunk.Choices[0]
+	return streamChunk{Content: choice.Delta.Content, ToolCalls: choice.Delta.ToolCalls, FinishReason: choice.FinishReason}
+}
+
+// injectSystemMessages inserts messages after the leading system messages of a conversation
+func injectSystemMessages(messages []interface{}, inject []SystemMessage) []interface{} {
+	if len(inject) == 0 {
+		return messages
+	}
+	insertAt := 0
+	for insertAt < len(messages) {
+		message, _ := messages[insertAt].(map[string]interface{})
+		if message["role"] != "system" {
+			break
+		}
+		insertAt++
+	}
+
+	result := make([]interface{}, 0, len(messages)+len(inject))
+	result = append(result, messages[:insertAt]...)
+	for _, message := range inject {
+		result = append(result, map[string]interface{}{"role": message.Role, "content": message.Content})
+	}
+	return append(result, messages[insertAt:]...)
+}
+
+// lastUserText returns the text of the latest user message
+func lastUserText(messages []interface{}) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		message, _ := messages[i].(map[string]interface{})
+		if message["role"] == "user" {
+			text, _ := message["content"].(string)
+			return text
+		}
+	}
+	return ""
+}
+
+// proxiedAgentID returns the ID of the agent the proxy key was issued to. The key names
+// the agent, as its ID is only known once it joins, so the session is found by name. It
+// is empty if no session of the agent in the key's channel is recorded.
+func (s *ConvoAIService) proxiedAgentID(claims *llmProxyClaims) string {
+	session, ok, err := s.sessions.GetByName(claims.AgentName)
+	if err != nil || !ok || session.ChannelName != claims.ChannelName {
+		return ""
+	}
+	return session.AgentID
+}
+
+// recordLLMTurn logs a relayed turn and adds it to the agent's transcript. Only the length
+// of the conversation text is logged; the text itself stays in the transcript.
+func (s *ConvoAIService) recordLLMTurn(turn LLMTurn) {
+	s.recordTranscript(turn, time.Now())
+	if turn.StatusCode == 200 && turn.AssistantText != "" {
+		if current, ok := s.agentStates.get(turn.AgentID); ok && !isAgentEnded(current.State) {
+			s.setAgentState(AgentEventSpeaking, turn.AgentID, "", AgentStateSpeaking, "", nil)
+		}
+	}
+	log.Printf("LLM turn: agent=%s channel=%s model=%s status=%d duration=%s retrieved=%d tools=%v user_chars=%d assistant_chars=%d",
+		turn.AgentName, turn.ChannelName, turn.Model, turn.StatusCode, turn.Duration.Round(time.Millisecond),
+		turn.Retrieved, turn.ToolCalls, utf8.RuneCountInString(turn.UserText), utf8.RuneCountInString(turn.AssistantText))
+}
+
+// authorizeChannelContext lets through the context requests carrying the configured
+// context secret as a bearer token. Without a secret, the context routes are disabled.
+func (s *ConvoAIService) authorizeChannelContext(c *gin.Context) {
+	secret := s.getConfig().LLMProxyContextSecret
+	if secret == "" {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "channel context is not configured"})
+		return
+	}
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid context secret"})
+		return
+	}
+	c.Next()
+}
+
+// SetChannelContext handles the request to set the context injected into a channel's LLM requests
+func (s *ConvoAIService) SetChannelContext(c *gin.Context) {
+	var req ChannelContextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.SystemMessages) > maxLLMProxyContextSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("system_messages must list at most %d messages", maxLLMProxyContextSize)})
+		return
+	}
+	for _, message := range req.SystemMessages {
+		if message.Role != "system" || message.Content == "" || utf8.RuneCountInString(message.Content) > maxSystemMessageLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("system_messages must have the system role and between 1 and %d characters", maxSystemMessageLength)})
+			return
+		}
+	}
+
+	s.channelContexts.set(c.Param("channel_name"), req.SystemMessages)
+	c.JSON(http.StatusOK, req)
+}
+
+// ClearChannelContext handles the request to clear a channel's injected context
+func (s *ConvoAIService) ClearChannelContext(c *gin.Context) {
+	s.channelContexts.set(c.Param("channel_name"), nil)
+	c.Status(http.StatusNoContent)
+}
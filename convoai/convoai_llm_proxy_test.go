@@ -0,0 +1,328 @@
+package convoai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeLLMStream is the event stream returned by fakeLLM for streamed requests
+const fakeLLMStream = "data: {\"choices\":[{\"delta\":{\"content\":\"Your order \"}}]}\n\n" +
+	"data: {\"choices\":[{\"delta\":{\"content\":\"has shipped.\"}}]}\n\n" +
+	"data: [DONE]\n\n"
+
+// fakeLLM is a stand-in for an OpenAI-compatible provider
+type fakeLLM struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	requests []map[string]interface{}
+	auth     []string
+	apiKeys  []string
+}
+
+// newFakeLLM starts a fake provider that streams fakeLLMStream, or answers with a single completion
+func newFakeLLM(t *testing.T) *fakeLLM {
+	t.Helper()
+	f := &fakeLLM{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		f.mu.Lock()
+		f.requests = append(f.requests, body)
+		f.auth = append(f.auth, r.Header.Get("Authorization"))
+		f.apiKeys = append(f.apiKeys, r.Header.Get("api-key"))
+		f.mu.Unlock()
+
+		if stream, _ := body["stream"].(bool); stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, event := range strings.SplitAfter(fakeLLMStream, "\n\n") {
+				fmt.Fprint(w, event)
+				w.(http.Flusher).Flush()
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Your order has shipped."}}]}`)
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// withLLMProxy points the OpenAI provider at the fake LLM and enables the proxy
+func withLLMProxy(llm *fakeLLM, rateLimit int) func(*ConvoAIConfig) {
+	return func(config *ConvoAIConfig) {
+		config.LLM = map[LLMProviderName]LLMProviderConfig{
+			LLMProviderOpenAI: &OpenAILLMConfig{URL: llm.server.URL, APIKey: "llm-token", Model: "gpt-4o-mini"},
+		}
+		config.LLMProxyURL = "https://convo.example.com/"
+		config.LLMProxyRateLimit = rateLimit
+		config.LLMProxyContextSecret = "context-secret"
+	}
+}
+
+// inviteProxiedAgent invites an agent and returns the LLM configuration it was started with
+func inviteProxiedAgent(t *testing.T, router *gin.Engine, agora *fakeAgora) LLM {
+	t.Helper()
+	body := `{"requester_id": "user-1", "channel_name": "test-channel"}`
+	if status := doJSON(t, router, "POST", "/agent/invite", body, nil); status != http.StatusOK {
+		t.Fatalf("invite returned status %d", status)
+	}
+	return agora.joins[len(agora.joins)-1].Properties.LLM
+}
+
+// doContext sends a channel context request with the given secret
+func doContext(router *gin.Engine, secret, method, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/llm/context/test-channel", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+secret)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+// doProxy sends a chat completion request to the proxy with the given key
+func doProxy(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", LLMProxyPath, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestLLMProxy(t *testing.T) {
+	agora := newFakeAgora(t)
+	llm := newFakeLLM(t)
+	service := newTestService(t, agora, withLLMProxy(llm, 0))
+	router := newTestRouter(service)
+
+	agentLLM := inviteProxiedAgent(t, router, agora)
+	if agentLLM.URL != "https://convo.example.com"+LLMProxyPath {
+		t.Errorf("agent LLM URL = %q, want the proxy", agentLLM.URL)
+	}
+	if agentLLM.APIKey == "" || agentLLM.APIKey == "llm-token" {
+		t.Fatalf("agent was given the provider key")
+	}
+
+	context := `{"system_messages": [{"role": "system", "content": "Order 1234 shipped today."}]}`
+	if rr := doContext(router, "context-secret", "PUT", context); rr.Code != http.StatusOK {
+		t.Fatalf("set context returned status %d", rr.Code)
+	}
+
+	tests := []struct {
+		name            string
+		stream          bool
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "Streamed",
+			stream:          true,
+			wantContentType: "text/event-stream",
+			wantBody:        fakeLLMStream,
+		},
+		{
+			name:            "Not streamed",
+			wantContentType: "application/json",
+			wantBody:        `{"choices":[{"message":{"role":"assistant","content":"Your order has shipped."}}]}`,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"model": "gpt-4o-mini", "stream": %t, "messages": [
+				{"role": "system", "content": "You are a support agent."},
+				{"role": "user", "content": "Where is my order?"}]}`, tt.stream)
+			rr := doProxy(router, agentLLM.APIKey, body)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("proxy returned status %d: %s", rr.Code, rr.Body.String())
+			}
+			if got := rr.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if rr.Body.String() != tt.wantBody {
+				t.Errorf("body =\n%s\nwant\n%s", rr.Body.String(), tt.wantBody)
+			}
+
+			// The provider sees the real key and the channel context after the system prompt
+			if llm.auth[i] != "Bearer llm-token" {
+				t.Errorf("upstream Authorization = %q", llm.auth[i])
+			}
+			messages := llm.requests[i]["messages"].([]interface{})
+			roles := []string{}
+			for _, m := range messages {
+				roles = append(roles, m.(map[string]interface{})["role"].(string))
+			}
+			injected := messages[1].(map[string]interface{})["content"]
+			if strings.Join(roles, ",") != "system,system,user" || injected != "Order 1234 shipped today." {
+				t.Errorf("upstream messages = %v", messages)
+			}
+		})
+	}
+
+	// Keys that weren't issued by this server, or have expired, are rejected
+	expired, err := newLLMProxyKey(service.getConfig(), llmProxyClaims{
+		AgentName:   agora.joins[0].Name,
+		ChannelName: "test-channel",
+		Expires:     time.Now().Add(-time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "llm-token", agentLLM.APIKey + "x", expired} {
+		if rr := doProxy(router, key, `{"messages": []}`); rr.Code != http.StatusUnauthorized {
+			t.Errorf("proxy with key %q returned status %d, want %d", key, rr.Code, http.StatusUnauthorized)
+		}
+	}
+	if len(llm.requests) != len(tests) {
+		t.Errorf("upstream received %d requests, want %d", len(llm.requests), len(tests))
+	}
+
+	// The key stops working once its agent is removed
+	if status := doJSON(t, router, "POST", "/agent/remove", `{"agent_id": "AGENT0001"}`, nil); status != http.StatusOK {
+		t.Fatalf("remove returned status %d", status)
+	}
+	if rr := doProxy(router, agentLLM.APIKey, `{"messages": []}`); rr.Code != http.StatusUnauthorized {
+		t.Errorf("proxy with the key of a removed agent returned status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLLMProxyProviderOverride(t *testing.T) {
+	agora := newFakeAgora(t)
+	llm := newFakeLLM(t)
+	router := newTestRouter(newTestService(t, agora, withLLMProxy(llm, 0), func(config *ConvoAIConfig) {
+		config.LLM[LLMProviderAzure] = &AzureLLMConfig{BaseURL: llm.server.URL, APIKey: "azure-key", Deployment: "gpt-4o"}
+		config.LLM[LLMProviderAnthropic] = &AnthropicLLMConfig{APIKey: "anthropic-key", Model: "claude-3-5-haiku-latest"}
+	}))
+
+	// The proxy only relays the OpenAI style
+	body := `{"requester_id": "user-1", "channel_name": "test-channel", "overrides": {"llm_provider": "anthropic"}}`
+	if status := doJSON(t, router, "POST", "/agent/invite", body, nil); status != http.StatusBadRequest {
+		t.Errorf("invite with the anthropic provider returned status %d, want %d", status, http.StatusBadRequest)
+	}
+	if len(agora.joins) != 0 {
+		t.Errorf("join called for a rejected request")
+	}
+
+	// Azure is sent its own api-key header, without a bearer token
+	body = `{"requester_id": "user-1", "channel_name": "test-channel", "overrides": {"llm_provider": "azure"}}`
+	if status := doJSON(t, router, "POST", "/agent/invite", body, nil); status != http.StatusOK {
+		t.Fatalf("invite with the azure provider returned status %d", status)
+	}
+	key := agora.joins[0].Properties.LLM.APIKey
+	if rr := doProxy(router, key, `{"messages": [{"role": "user", "content": "Hello"}]}`); rr.Code != http.StatusOK {
+		t.Fatalf("proxy returned status %d: %s", rr.Code, rr.Body.String())
+	}
+	if llm.apiKeys[0] != "azure-key" || llm.auth[0] != "" {
+		t.Errorf("upstream api-key = %q, Authorization = %q, want only the api-key", llm.apiKeys[0], llm.auth[0])
+	}
+}
+
+func TestSetChannelContextValidation(t *testing.T) {
+	router := newTestRouter(newTestService(t, newFakeAgora(t), withLLMProxy(newFakeLLM(t), 0)))
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Valid", `{"system_messages": [{"role": "system", "content": "Order 1234 shipped today."}]}`, http.StatusOK},
+		{"User role", `{"system_messages": [{"role": "user", "content": "Hi"}]}`, http.StatusBadRequest},
+		{"Empty content", `{"system_messages": [{"role": "system", "content": ""}]}`, http.StatusBadRequest},
+		{"Content too long", `{"system_messages": [{"role": "system", "content": "` + strings.Repeat("a", maxSystemMessageLength+1) + `"}]}`, http.StatusBadRequest},
+		{"Too many messages", `{"system_messages": [` + strings.Repeat(`{"role": "system", "content": "a"},`, maxLLMProxyContextSize) + `{"role": "system", "content": "a"}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := doContext(router, "context-secret", "PUT", tt.body); rr.Code != tt.wantStatus {
+				t.Errorf("set context returned status %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestChannelContextAuth(t *testing.T) {
+	body := `{"system_messages": [{"role": "system", "content": "Order 1234 shipped today."}]}`
+
+	service := newTestService(t, newFakeAgora(t), withLLMProxy(newFakeLLM(t), 0))
+	router := newTestRouter(service)
+	for _, secret := range []string{"", "wrong-secret"} {
+		for _, method := range []string{"PUT", "DELETE"} {
+			if rr := doContext(router, secret, method, body); rr.Code != http.StatusUnauthorized {
+				t.Errorf("%s with secret %q returned status %d, want %d", method, secret, rr.Code, http.StatusUnauthorized)
+			}
+		}
+	}
+	if context := service.channelContexts.get("test-channel"); context != nil {
+		t.Errorf("context set without the secret: %v", context)
+	}
+	if rr := doContext(router, "context-secret", "DELETE", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("DELETE with the secret returned status %d, want %d", rr.Code, http.StatusNoContent)
+	}
+
+	// Without a configured secret the routes are disabled
+	router = newTestRouter(newTestService(t, newFakeAgora(t)))
+	if rr := doContext(router, "", "PUT", body); rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("PUT without a configured secret returned status %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestLLMProxyRateLimit(t *testing.T) {
+	agora := newFakeAgora(t)
+	llm := newFakeLLM(t)
+	router := newTestRouter(newTestService(t, agora, withLLMProxy(llm, 2)))
+	agentLLM := inviteProxiedAgent(t, router, agora)
+
+	body := `{"messages": [{"role": "user", "content": "Hello"}]}`
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if rr := doProxy(router, agentLLM.APIKey, body); rr.Code != want {
+			t.Errorf("request %d returned status %d, want %d", i+1, rr.Code, want)
+		}
+	}
+	if len(llm.requests) != 2 {
+		t.Errorf("upstream received %d requests, want 2", len(llm.requests))
+	}
+}
+
+func TestInjectSystemMessages(t *testing.T) {
+	inject := []SystemMessage{{Role: "system", Content: "context"}}
+	tests := []struct {
+		name      string
+		messages  string
+		wantRoles string
+	}{
+		{name: "After the system prompt", messages: `[{"role":"system"},{"role":"user"}]`, wantRoles: "system,system*,user"},
+		{name: "Without a system prompt", messages: `[{"role":"user"},{"role":"assistant"}]`, wantRoles: "system*,user,assistant"},
+		{name: "Empty conversation", messages: `[]`, wantRoles: "system*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var messages []interface{}
+			json.Unmarshal([]byte(tt.messages), &messages)
+
+			roles := []string{}
+			for _, m := range injectSystemMessages(messages, inject) {
+				message := m.(map[string]interface{})
+				role := message["role"].(string)
+				if message["content"] == "context" {
+					role += "*"
+				}
+				roles = append(roles, role)
+			}
+			if got := strings.Join(roles, ","); got != tt.wantRoles {
+				t.Errorf("roles = %s, want %s", got, tt.wantRoles)
+			}
+		})
+	}
+}
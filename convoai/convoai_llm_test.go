@@ -0,0 +1,152 @@
+package convoai
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestResolveLLMConfig(t *testing.T) {
+	env := map[string]string{
+		"LLM_TOKEN":               "openai-key",
+		"LLM_MODEL":               "gpt-4o-mini",
+		"AZURE_OPENAI_API_KEY":    "azure-key",
+		"AZURE_OPENAI_ENDPOINT":   "https://example.openai.azure.com/",
+		"AZURE_OPENAI_DEPLOYMENT": "gpt-4o",
+		"ANTHROPIC_API_KEY":       "anthropic-key",
+		"ANTHROPIC_MODEL":         "claude-3-5-haiku-latest",
+		"GEMINI_API_KEY":          "gemini-key",
+		"GEMINI_MODEL":            "gemini-2.0-flash",
+		"CUSTOM_LLM_URL":          "https://llm.example.com/v1/chat",
+		"CUSTOM_LLM_API_KEY":      "custom-key",
+		"CUSTOM_LLM_MODEL":        "llama-3.1-70b",
+		"CUSTOM_LLM_AUTH_HEADER":  "X-Api-Key",
+	}
+	config := &ConvoAIConfig{LLM: LoadLLMConfigs(func(key string) string { return env[key] })}
+
+	penalty := 0.5
+	extras := LLMParams{
+		MaxTokens:       256,
+		ResponseFormat:  map[string]interface{}{"type": "json_object"},
+		Stop:            []string{"END"},
+		PresencePenalty: &penalty,
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		profile  *AgentProfile
+		wantJSON string
+		wantErr  bool
+	}{
+		{
+			name:     "Default provider",
+			wantJSON: `{"url":"https://api.openai.com/v1/chat/completions","api_key":"openai-key","style":"openai","params":{"model":"gpt-4o-mini","max_tokens":0,"temperature":0,"top_p":0}}`,
+		},
+		{
+			name:     "OpenAI keeps the extra params",
+			provider: "openai",
+			profile:  &AgentProfile{LLMParams: extras},
+			wantJSON: `{"url":"https://api.openai.com/v1/chat/completions","api_key":"openai-key","style":"openai","params":{"model":"gpt-4o-mini","max_tokens":256,"temperature":0,"top_p":0,"response_format":{"type":"json_object"},"stop":["END"],"presence_penalty":0.5}}`,
+		},
+		{
+			name:     "Azure",
+			provider: "azure",
+			wantJSON: `{"url":"https://example.openai.azure.com/openai/deployments/gpt-4o/chat/completions?api-version=2024-10-21","api_key":"azure-key","style":"openai","headers":{"api-key":"azure-key"},"params":{"model":"","max_tokens":0,"temperature":0,"top_p":0}}`,
+		},
+		{
+			name:     "Anthropic renames stop",
+			provider: "anthropic",
+			profile:  &AgentProfile{LLMParams: LLMParams{MaxTokens: 256, Stop: []string{"END"}}},
+			wantJSON: `{"url":"https://api.anthropic.com/v1/messages","api_key":"anthropic-key","style":"anthropic","headers":{"anthropic-version":"2023-06-01","x-api-key":"anthropic-key"},"params":{"model":"claude-3-5-haiku-latest","max_tokens":256,"temperature":0,"top_p":0,"stop_sequences":["END"]}}`,
+		},
+		{
+			name:     "Anthropic rejects OpenAI-only params",
+			provider: "anthropic",
+			profile:  &AgentProfile{LLMParams: extras},
+			wantErr:  true,
+		},
+		{
+			name:     "Gemini",
+			provider: "gemini",
+			wantJSON: `{"url":"https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:streamGenerateContent?alt=sse\u0026key=gemini-key","api_key":"gemini-key","style":"gemini","params":{"model":"gemini-2.0-flash","max_tokens":0,"temperature":0,"top_p":0}}`,
+		},
+		{
+			name:     "Custom with an auth header",
+			provider: "custom",
+			wantJSON: `{"url":"https://llm.example.com/v1/chat","api_key":"custom-key","style":"openai","headers":{"X-Api-Key":"custom-key"},"params":{"model":"llama-3.1-70b","max_tokens":0,"temperature":0,"top_p":0}}`,
+		},
+		{
+			name:     "Profile selects the provider and deployment",
+			profile:  &AgentProfile{LLM: &ProfileLLM{Provider: "azure", Params: map[string]interface{}{"deployment": "gpt-4o-mini"}}},
+			wantJSON: `{"url":"https://example.openai.azure.com/openai/deployments/gpt-4o-mini/chat/completions?api-version=2024-10-21","api_key":"azure-key","style":"openai","headers":{"api-key":"azure-key"},"params":{"model":"","max_tokens":0,"temperature":0,"top_p":0}}`,
+		},
+		{
+			name:     "Invite provider wins over the profile",
+			provider: "gemini",
+			profile:  &AgentProfile{LLM: &ProfileLLM{Provider: "azure"}},
+			wantJSON: `{"url":"https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:streamGenerateContent?alt=sse\u0026key=gemini-key","api_key":"gemini-key","style":"gemini","params":{"model":"gemini-2.0-flash","max_tokens":0,"temperature":0,"top_p":0}}`,
+		},
+		{
+			name:     "Unregistered provider",
+			provider: "unknown",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint, params, err := ResolveLLMConfig(config, tt.profile, tt.provider)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveLLMConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// Only the fields the provider controls
+			data, err := json.Marshal(struct {
+				URL     string            `json:"url"`
+				APIKey  string            `json:"api_key"`
+				Style   string            `json:"style,omitempty"`
+				Headers map[string]string `json:"headers,omitempty"`
+				Params  LLMParams         `json:"params"`
+			}{endpoint.URL, endpoint.APIKey, endpoint.Style, endpoint.Headers, params})
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(data) != tt.wantJSON {
+				t.Errorf("LLM JSON =\n%s\nwant\n%s", data, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func TestInviteAgentLLMProvider(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora, func(config *ConvoAIConfig) {
+		config.LLM = map[LLMProviderName]LLMProviderConfig{
+			LLMProviderOpenAI:    config.LLM[LLMProviderOpenAI],
+			LLMProviderAnthropic: &AnthropicLLMConfig{APIKey: "anthropic-key", Model: "claude-3-5-haiku-latest"},
+		}
+	})
+	router := newTestRouter(service)
+
+	body := `{"requester_id": "user-1", "channel_name": "test-channel", "overrides": {"llm_provider": "anthropic"}}`
+	if status := doJSON(t, router, "POST", "/agent/invite", body, nil); status != http.StatusOK {
+		t.Fatalf("invite returned status %d, want %d", status, http.StatusOK)
+	}
+	llm := agora.joins[0].Properties.LLM
+	if llm.Style != LLMStyleAnthropic || llm.URL != anthropicMessagesURL || llm.Params.Model != "claude-3-5-haiku-latest" {
+		t.Errorf("unexpected LLM config: %+v", llm)
+	}
+
+	// Providers without configuration are rejected before the agent is started
+	body = `{"requester_id": "user-1", "channel_name": "test-channel", "overrides": {"llm_provider": "gemini"}}`
+	if status := doJSON(t, router, "POST", "/agent/invite", body, nil); status != http.StatusBadRequest {
+		t.Errorf("invite with unconfigured provider returned status %d, want %d", status, http.StatusBadRequest)
+	}
+	if len(agora.joins) != 1 {
+		t.Errorf("join called for a rejected request")
+	}
+}
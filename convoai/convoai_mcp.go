@@ -0,0 +1,572 @@
+package convoai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MCP client settings
+const (
+	mcpProtocolVersion = "2025-03-26"
+	mcpConnectTimeout  = 30 * time.Second
+	mcpStopTimeout     = 2 * time.Second
+	mcpSessionHeader   = "Mcp-Session-Id"
+)
+
+// mcpServerNamePattern leaves room for the tool name in the names given to the LLM
+var mcpServerNamePattern = regexp.MustCompile(`^[a-zA-Z0-9-]{1,32}$`)
+
+// MCPServerConfig declares a Model Context Protocol server whose tools are offered to
+// agents. Stdio servers are started with Command; HTTP servers are reached at URL.
+type MCPServerConfig struct {
+	Name    string            `json:"name" yaml:"name"`
+	Command string            `json:"command,omitempty" yaml:"command,omitempty"`
+	Args    []string          `json:"args,omitempty" yaml:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	URL     string            `json:"url,omitempty" yaml:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+}
+
+// Validate checks the server has a usable name and exactly one transport
+func (m MCPServerConfig) Validate() error {
+	if !mcpServerNamePattern.MatchString(m.Name) {
+		return fmt.Errorf("invalid MCP server name %q", m.Name)
+	}
+	switch {
+	case m.Command != "" && m.URL != "":
+		return fmt.Errorf("MCP server %s must set either command or url, not both", m.Name)
+	case m.Command == "" && m.URL == "":
+		return fmt.Errorf("MCP server %s requires a command or url", m.Name)
+	case m.URL != "" && !(strings.HasPrefix(m.URL, "http://") || strings.HasPrefix(m.URL, "https://")):
+		return fmt.Errorf("MCP server %s url must be an http(s) URL", m.Name)
+	}
+	return nil
+}
+
+// jsonRPCRequest is a JSON-RPC 2.0 request, or a notification when ID is nil
+type jsonRPCRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      *int64      `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// jsonRPCMessage is any JSON-RPC 2.0 message received from a server
+type jsonRPCMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+}
+
+// jsonRPCError is the error of a failed JSON-RPC request
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *jsonRPCError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// mcpTransport carries JSON-RPC messages to an MCP server
+type mcpTransport interface {
+	// roundTrip sends the request and returns its response. Notifications return nil.
+	roundTrip(ctx context.Context, request jsonRPCRequest) (*jsonRPCMessage, error)
+	close() error
+}
+
+// MCPClient is a connection to an MCP server
+type MCPClient struct {
+	name      string
+	transport mcpTransport
+	nextID    atomic.Int64
+}
+
+// MCPTool is a tool listed by an MCP server
+type MCPTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+// ConnectMCPServer starts or connects to the server and completes the MCP handshake
+func ConnectMCPServer(ctx context.Context, config MCPServerConfig) (*MCPClient, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var transport mcpTransport
+	if config.Command != "" {
+		stdio, err := newMCPStdioTransport(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start MCP server %s: %v", config.Name, err)
+		}
+		transport = stdio
+	} else {
+		transport = &mcpHTTPTransport{url: config.URL, headers: config.Headers}
+	}
+
+	client := &MCPClient{name: config.Name, transport: transport}
+	params := map[string]interface{}{
+		"protocolVersion": mcpProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "convo-ai-go-server", "version": "1.0.0"},
+	}
+	if err := client.call(ctx, "initialize", params, nil); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to initialize MCP server %s: %v", config.Name, err)
+	}
+	if _, err := transport.roundTrip(ctx, jsonRPCRequest{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to initialize MCP server %s: %v", config.Name, err)
+	}
+	return client, nil
+}
+
+// call sends a request and decodes its result into result, when not nil
+func (c *MCPClient) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	id := c.nextID.Add(1)
+	response, err := c.transport.roundTrip(ctx, jsonRPCRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("invalid %s result: %v", method, err)
+	}
+	return nil
+}
+
+// ListTools returns every tool the server offers, following pagination
+func (c *MCPClient) ListTools(ctx context.Context) ([]MCPTool, error) {
+	var tools []MCPTool
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []MCPTool `json:"tools"`
+			NextCursor string    `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("failed to list tools of MCP server %s: %v", c.name, err)
+		}
+		tools = append(tools, page.Tools...)
+
+		if page.NextCursor == "" || page.NextCursor == cursor {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool calls a tool and returns the text it produced. A result flagged as an
+// error by the server is returned as an error.
+func (c *MCPClient) CallTool(ctx context.Context, name string, arguments json.RawMessage) (string, error) {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	var result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	params := map[string]interface{}{"name": name, "arguments": arguments}
+	if err := c.call(ctx, "tools/call", params, &result); err != nil {
+		return "", fmt.Errorf("MCP server %s failed to call %s: %v", c.name, name, err)
+	}
+
+	var texts []string
+	for _, content := range result.Content {
+		if content.Type == "text" {
+			texts = append(texts, content.Text)
+		}
+	}
+	text := strings.Join(texts, "\n")
+	if result.IsError {
+		return "", errors.New(text)
+	}
+	return text, nil
+}
+
+// Tool returns an MCP tool as a Tool, named after the server so tools of different
+// servers don't clash
+func (c *MCPClient) Tool(tool MCPTool) Tool {
+	return Tool{
+		Name:        mcpToolName(c.name, tool.Name),
+		Description: tool.Description,
+		Parameters:  tool.InputSchema,
+		Handler: func(ctx context.Context, call ToolCall) (string, error) {
+			return c.CallTool(ctx, tool.Name, call.Arguments)
+		},
+	}
+}
+
+// Close disconnects from the server, stopping it if it was started by the client
+func (c *MCPClient) Close() error {
+	return c.transport.close()
+}
+
+// mcpToolName returns the name an MCP tool is offered to the LLM under
+func mcpToolName(server, tool string) string {
+	name := []byte(server + "_" + tool)
+	for i, ch := range name {
+		if !(ch == '_' || ch == '-' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z') {
+			name[i] = '_'
+		}
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return string(name)
+}
+
+// ConnectMCPServers connects to the configured MCP servers and registers their tools.
+// Servers that can't be reached are skipped, so they don't stop the server starting;
+// the returned error lists them.
+func (s *ConvoAIService) ConnectMCPServers(ctx context.Context) error {
+	var errs []error
+	for _, server := range s.getConfig().MCPServers {
+		connectCtx, cancel := context.WithTimeout(ctx, mcpConnectTimeout)
+		count, err := s.connectMCPServer(connectCtx, server)
+		cancel()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("Connected to MCP server %s with %d tools", server.Name, count)
+	}
+	return errors.Join(errs...)
+}
+
+// connectMCPServer connects to one server and registers its tools
+func (s *ConvoAIService) connectMCPServer(ctx context.Context, server MCPServerConfig) (int, error) {
+	client, err := ConnectMCPServer(ctx, server)
+	if err != nil {
+		return 0, err
+	}
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		client.Close()
+		return 0, err
+	}
+	for _, tool := range tools {
+		if err := s.RegisterTool(client.Tool(tool)); err != nil {
+			log.Printf("Warning: skipping tool %s of MCP server %s: %v", tool.Name, server.Name, err)
+		}
+	}
+
+	s.mcpMu.Lock()
+	s.mcpClients = append(s.mcpClients, client)
+	s.mcpMu.Unlock()
+	return len(tools), nil
+}
+
+// closeMCPClients disconnects from every connected MCP server
+func (s *ConvoAIService) closeMCPClients() error {
+	s.mcpMu.Lock()
+	clients := s.mcpClients
+	s.mcpClients = nil
+	s.mcpMu.Unlock()
+
+	var errs []error
+	for _, client := range clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close MCP server %s: %v", client.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// mcpStdioTransport exchanges newline-delimited JSON-RPC messages with a server process
+type mcpStdioTransport struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *jsonRPCMessage
+	done    chan struct{}
+	err     error
+}
+
+// newMCPStdioTransport starts the server process
+func newMCPStdioTransport(config MCPServerConfig) (*mcpStdioTransport, error) {
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = os.Environ()
+	for name, value := range config.Env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	t := &mcpStdioTransport{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[string]chan *jsonRPCMessage),
+		done:    make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+	return t, nil
+}
+
+// readLoop delivers responses to the waiting requests until the process exits
+func (t *mcpStdioTransport) readLoop(stdout io.Reader) {
+	reader := bufio.NewReader(stdout)
+	var err error
+	for {
+		var line []byte
+		line, err = reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			t.handleMessage(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	t.mu.Lock()
+	if err == io.EOF {
+		err = errors.New("MCP server exited")
+	}
+	t.err = err
+	t.mu.Unlock()
+	close(t.done)
+}
+
+// handleMessage routes a message from the server
+func (t *mcpStdioTransport) handleMessage(line []byte) {
+	var message jsonRPCMessage
+	if err := json.Unmarshal(line, &message); err != nil {
+		log.Printf("Warning: invalid message from MCP server: %v", err)
+		return
+	}
+
+	switch {
+	case message.Method != "" && len(message.ID) > 0:
+		// Requests from the server: answer pings, and refuse anything else
+		reply := map[string]interface{}{"jsonrpc": "2.0", "id": message.ID}
+		if message.Method == "ping" {
+			reply["result"] = map[string]interface{}{}
+		} else {
+			reply["error"] = jsonRPCError{Code: -32601, Message: "method not found"}
+		}
+		t.write(reply)
+	case message.Method != "":
+		// Notifications need no reply
+	default:
+		t.mu.Lock()
+		ch, ok := t.pending[string(message.ID)]
+		delete(t.pending, string(message.ID))
+		t.mu.Unlock()
+		if ok {
+			ch <- &message
+		}
+	}
+}
+
+// write sends one message to the server
+func (t *mcpStdioTransport) write(message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *mcpStdioTransport) roundTrip(ctx context.Context, request jsonRPCRequest) (*jsonRPCMessage, error) {
+	if request.ID == nil {
+		return nil, t.write(request)
+	}
+
+	key := strconv.FormatInt(*request.ID, 10)
+	ch := make(chan *jsonRPCMessage, 1)
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, t.err
+	}
+	t.pending[key] = ch
+	t.mu.Unlock()
+
+	if err := t.write(request); err != nil {
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+		return nil, err
+	}
+
+	select {
+	case response := <-ch:
+		return response, nil
+	case <-t.done:
+		return nil, t.err
+	case <-ctx.Done():
+		t.mu.Lock()
+		delete(t.pending, key)
+		t.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// close ends the server's input so it can exit, killing it if it doesn't
+func (t *mcpStdioTransport) close() error {
+	t.stdin.Close()
+	select {
+	case <-t.done:
+	case <-time.After(mcpStopTimeout):
+		t.cmd.Process.Kill()
+		<-t.done
+	}
+	t.cmd.Wait()
+	return nil
+}
+
+// mcpHTTPTransport posts JSON-RPC messages to a server using the streamable HTTP
+// transport, which answers with JSON or an event stream
+type mcpHTTPTransport struct {
+	url     string
+	headers map[string]string
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func (t *mcpHTTPTransport) roundTrip(ctx context.Context, request jsonRPCRequest) (*jsonRPCMessage, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.send(ctx, "POST", payload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if session := resp.Header.Get(mcpSessionHeader); session != "" {
+		t.mu.Lock()
+		t.sessionID = session
+		t.mu.Unlock()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("MCP server returned status %d: %s", resp.StatusCode, body)
+	}
+	if request.ID == nil {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readMCPEventStream(resp.Body, strconv.FormatInt(*request.ID, 10))
+	}
+	var message jsonRPCMessage
+	if err := json.NewDecoder(resp.Body).Decode(&message); err != nil {
+		return nil, fmt.Errorf("invalid MCP response: %v", err)
+	}
+	return &message, nil
+}
+
+// send makes a request carrying the session ID
+func (t *mcpHTTPTransport) send(ctx context.Context, method string, payload []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(mcpSessionHeader, t.sessionID)
+	}
+	t.mu.Unlock()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach MCP server: %v", err)
+	}
+	return resp, nil
+}
+
+// close ends the session, if the server started one
+func (t *mcpHTTPTransport) close() error {
+	t.mu.Lock()
+	session := t.sessionID
+	t.mu.Unlock()
+	if session == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcpStopTimeout)
+	defer cancel()
+	resp, err := t.send(ctx, "DELETE", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// readMCPEventStream returns the response with the given ID from an event stream
+func readMCPEventStream(stream io.Reader, id string) (*jsonRPCMessage, error) {
+	reader := bufio.NewReader(stream)
+	var data bytes.Buffer
+	for {
+		line, err := reader.ReadBytes('\n')
+		trimmed := bytes.TrimRight(line, "\r\n")
+		if value, ok := bytes.CutPrefix(trimmed, []byte("data:")); ok {
+			data.Write(bytes.TrimPrefix(value, []byte(" ")))
+		} else if len(trimmed) == 0 && data.Len() > 0 {
+			var message jsonRPCMessage
+			if json.Unmarshal(data.Bytes(), &message) == nil && message.Method == "" && string(message.ID) == id {
+				return &message, nil
+			}
+			data.Reset()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("MCP event stream ended without a response: %v", err)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package convoai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// mcpStandInEnv makes the test binary act as a stdio MCP server
+const mcpStandInEnv = "CONVOAI_MCP_STAND_IN"
+
+// TestMCPStandIn is not a test: run by the MCP client tests as a subprocess, it serves
+// answerFakeMCP over stdin and stdout
+func TestMCPStandIn(t *testing.T) {
+	if os.Getenv(mcpStandInEnv) != "1" {
+		return
+	}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			os.Exit(0)
+		}
+		if response := answerFakeMCP(line); response != nil {
+			// Interleave a notification, which the client must ignore
+			fmt.Println(`{"jsonrpc":"2.0","method":"notifications/message","params":{"level":"info"}}`)
+			fmt.Println(string(response))
+		}
+	}
+}
+
+// answerFakeMCP answers a message to the fake MCP server, which lists two tools over
+// two pages. Notifications get no answer.
+func answerFakeMCP(message []byte) []byte {
+	var request struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			Cursor    string            `json:"cursor"`
+			Name      string            `json:"name"`
+			Arguments map[string]string `json:"arguments"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(message, &request) != nil || len(request.ID) == 0 {
+		return nil
+	}
+
+	var result interface{}
+	switch {
+	case request.Method == "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": mcpProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": "fake", "version": "1.0.0"},
+		}
+	case request.Method == "tools/list" && request.Params.Cursor == "":
+		result = map[string]interface{}{
+			"tools": []interface{}{map[string]interface{}{
+				"name":        "lookup_order",
+				"description": "Look up an order",
+				"inputSchema": map[string]interface{}{"type": "object", "properties": map[string]interface{}{"order_id": map[string]interface{}{"type": "string"}}},
+			}},
+			"nextCursor": "page-2",
+		}
+	case request.Method == "tools/list":
+		result = map[string]interface{}{
+			"tools": []interface{}{map[string]interface{}{"name": "create.ticket", "description": "Open a ticket"}},
+		}
+	case request.Method == "tools/call" && request.Params.Name == "lookup_order":
+		result = map[string]interface{}{
+			"content": []interface{}{map[string]interface{}{"type": "text", "text": "Order " + request.Params.Arguments["order_id"] + " shipped"}},
+		}
+	case request.Method == "tools/call":
+		result = map[string]interface{}{
+			"content": []interface{}{map[string]interface{}{"type": "text", "text": "ticket system down"}},
+			"isError": true,
+		}
+	}
+
+	response := map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "result": result}
+	if result == nil {
+		response = map[string]interface{}{"jsonrpc": "2.0", "id": request.ID, "error": map[string]interface{}{"code": -32601, "message": "method not found"}}
+	}
+	data, _ := json.Marshal(response)
+	return data
+}
+
+// fakeMCPHTTPServer serves answerFakeMCP over the streamable HTTP transport, answering
+// tool calls with an event stream and recording the session ID of each request
+type fakeMCPHTTPServer struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	sessions []string
+	deleted  bool
+}
+
+func newFakeMCPHTTPServer(t *testing.T) *fakeMCPHTTPServer {
+	t.Helper()
+	f := &fakeMCPHTTPServer{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if r.Method == "DELETE" {
+			f.deleted = r.Header.Get(mcpSessionHeader) == "session-1"
+			return
+		}
+
+		var body json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+		f.sessions = append(f.sessions, r.Header.Get(mcpSessionHeader))
+
+		response := answerFakeMCP(body)
+		switch {
+		case response == nil:
+			w.WriteHeader(http.StatusAccepted)
+		case strings.Contains(string(body), `"tools/call"`):
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", response)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			if strings.Contains(string(body), `"initialize"`) {
+				w.Header().Set(mcpSessionHeader, "session-1")
+			}
+			w.Write(response)
+		}
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func TestMCPClient(t *testing.T) {
+	httpServer := newFakeMCPHTTPServer(t)
+
+	tests := []struct {
+		name   string
+		config MCPServerConfig
+	}{
+		{
+			name: "Stdio",
+			config: MCPServerConfig{
+				Name:    "orders",
+				Command: os.Args[0],
+				Args:    []string{"-test.run=^TestMCPStandIn$"},
+				Env:     map[string]string{mcpStandInEnv: "1"},
+			},
+		},
+		{
+			name:   "HTTP",
+			config: MCPServerConfig{Name: "orders", URL: httpServer.server.URL},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			client, err := ConnectMCPServer(ctx, tt.config)
+			if err != nil {
+				t.Fatalf("ConnectMCPServer failed: %v", err)
+			}
+			defer client.Close()
+
+			tools, err := client.ListTools(ctx)
+			if err != nil {
+				t.Fatalf("ListTools failed: %v", err)
+			}
+			if len(tools) != 2 || tools[0].Name != "lookup_order" || tools[1].Name != "create.ticket" {
+				t.Fatalf("tools = %+v", tools)
+			}
+
+			// Tools are offered under the server name, with characters LLMs reject replaced
+			lookup, ticket := client.Tool(tools[0]), client.Tool(tools[1])
+			if lookup.Name != "orders_lookup_order" || ticket.Name != "orders_create_ticket" {
+				t.Errorf("tool names = %s, %s", lookup.Name, ticket.Name)
+			}
+			if err := lookup.validate(); err != nil {
+				t.Errorf("tool is not valid: %v", err)
+			}
+
+			call := ToolCall{Name: lookup.Name, Arguments: json.RawMessage(`{"order_id":"1234"}`)}
+			if result, err := lookup.Handler(ctx, call); err != nil || result != "Order 1234 shipped" {
+				t.Errorf("lookup_order = %q, %v", result, err)
+			}
+			if _, err := ticket.Handler(ctx, ToolCall{Name: ticket.Name}); err == nil || err.Error() != "ticket system down" {
+				t.Errorf("create.ticket error = %v, want the tool error", err)
+			}
+		})
+	}
+
+	// Requests after initialize carry the session, which is ended on close
+	for i, session := range httpServer.sessions {
+		if want := map[bool]string{true: "", false: "session-1"}[i == 0]; session != want {
+			t.Errorf("request %d session = %q, want %q", i, session, want)
+		}
+	}
+	if !httpServer.deleted {
+		t.Errorf("HTTP session was not ended on close")
+	}
+}
+
+func TestConnectMCPServers(t *testing.T) {
+	httpServer := newFakeMCPHTTPServer(t)
+	service := newTestService(t, newFakeAgora(t), func(config *ConvoAIConfig) {
+		config.MCPServers = []MCPServerConfig{
+			{Name: "crm", URL: httpServer.server.URL},
+			{Name: "down", Command: "/nonexistent/mcp-server"},
+		}
+	})
+
+	// The unreachable server is reported without stopping the others
+	err := service.ConnectMCPServers(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "down") {
+		t.Errorf("ConnectMCPServers error = %v, want the unreachable server", err)
+	}
+
+	var names []string
+	for _, tool := range service.tools.List() {
+		names = append(names, tool.Name)
+	}
+	if strings.Join(names, ",") != "crm_create_ticket,crm_lookup_order" {
+		t.Errorf("registered tools = %v", names)
+	}
+
+	// Profiles choose which of them the agent is offered
+	profile := DefaultAgentProfile()
+	profile.Tools = []string{"crm_lookup_*"}
+	tools := service.availableTools(service.getConfig(), profile)
+	if _, ok := tools["crm_lookup_order"]; len(tools) != 1 || !ok {
+		t.Errorf("tools allowed by the profile = %v", tools)
+	}
+}
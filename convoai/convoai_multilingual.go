@@ -0,0 +1,156 @@
+package convoai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Bounds on the number of languages in multilingual mode
+const (
+	minMultilingualLanguages = 2
+	maxMultilingualLanguages = 4
+)
+
+// languageNames are the display names of the supported ASR languages, used in
+// the language-aware system message
+var languageNames = map[string]string{
+	"ar-SA": "Arabic", "de-DE": "German", "en-GB": "English", "en-IN": "English",
+	"en-US": "English", "es-ES": "Spanish", "es-MX": "Spanish", "fr-FR": "French",
+	"hi-IN": "Hindi", "id-ID": "Indonesian", "it-IT": "Italian", "ja-JP": "Japanese",
+	"ko-KR": "Korean", "nl-NL": "Dutch", "pt-BR": "Portuguese", "pt-PT": "Portuguese",
+	"ru-RU": "Russian", "th-TH": "Thai", "tr-TR": "Turkish", "vi-VN": "Vietnamese",
+	"zh-CN": "Chinese", "zh-HK": "Cantonese", "zh-TW": "Chinese",
+}
+
+// MultilingualASRVendorConfig is implemented by ASR vendors that can recognize
+// several languages in one session
+type MultilingualASRVendorConfig interface {
+	// MultilingualParams builds the vendor params for recognizing any of the languages
+	MultilingualParams(languages []string) (interface{}, error)
+}
+
+// MultilingualTTSVendorConfig is implemented by TTS vendors that can select a voice per language
+type MultilingualTTSVendorConfig interface {
+	// VoiceFor returns the voice configured for the locale
+	VoiceFor(locale string) (string, error)
+	// WithVoice returns a copy of the configuration using the given voice
+	WithVoice(voice string) TTSVendorConfig
+}
+
+// MultilingualConfig is the resolved agent configuration for multilingual mode
+type MultilingualConfig struct {
+	ASR           *ASR
+	TTS           *TTSConfig
+	SystemMessage SystemMessage
+}
+
+// validateMultilingual checks the requested languages against the server-side allow-list
+func validateMultilingual(m *MultilingualRequest, o *AgentOverrides) error {
+	if m == nil {
+		return nil
+	}
+	if o != nil && o.ASRLanguage != nil {
+		return errors.New("overrides.asr_language cannot be combined with multilingual mode")
+	}
+	if len(m.Languages) < minMultilingualLanguages || len(m.Languages) > maxMultilingualLanguages {
+		return fmt.Errorf("multilingual.languages must list between %d and %d languages", minMultilingualLanguages, maxMultilingualLanguages)
+	}
+
+	seen := make(map[string]bool, len(m.Languages))
+	for _, language := range m.Languages {
+		if !allowedASRLanguages[language] {
+			return fmt.Errorf("multilingual.languages %q is not supported", language)
+		}
+		if seen[language] {
+			return fmt.Errorf("multilingual.languages lists %q more than once", language)
+		}
+		seen[language] = true
+	}
+	return nil
+}
+
+// ResolveMultilingualConfig resolves the ASR vendor, the voice and the language-aware system
+// message. Every language must have a voice. The first language is the primary one: the
+// agent speaks with its voice, and falls back to it when the user's language is unclear.
+func ResolveMultilingualConfig(config *ConvoAIConfig, profile *AgentProfile, languages []string) (*MultilingualConfig, error) {
+	if len(languages) == 0 {
+		return nil, errors.New("multilingual mode requires at least one language")
+	}
+	primary := languages[0]
+
+	// ASR must recognize every language in the same session
+	asrVendor, asrConfig, err := resolveASRVendorConfig(config, profile)
+	if err != nil {
+		return nil, err
+	}
+	multilingualASR, ok := asrConfig.(MultilingualASRVendorConfig)
+	if !ok {
+		return nil, fmt.Errorf("%s ASR does not support multilingual mode", asrVendor)
+	}
+	asrParams, err := multilingualASR.MultilingualParams(languages)
+	if err != nil {
+		return nil, err
+	}
+
+	// TTS must have a voice for every language
+	ttsVendor, ttsConfig, err := resolveTTSVendorConfig(config, profile)
+	if err != nil {
+		return nil, err
+	}
+	multilingualTTS, ok := ttsConfig.(MultilingualTTSVendorConfig)
+	if !ok {
+		return nil, fmt.Errorf("%s TTS does not support multilingual mode", ttsVendor)
+	}
+	var voice string
+	for _, language := range languages {
+		languageVoice, err := multilingualTTS.VoiceFor(language)
+		if err != nil {
+			return nil, err
+		}
+		if language == primary {
+			voice = languageVoice
+		}
+	}
+	ttsParams, err := multilingualTTS.WithVoice(voice).Params()
+	if err != nil {
+		return nil, err
+	}
+
+	return &MultilingualConfig{
+		ASR: &ASR{
+			Language: primary,
+			Task:     "conversation",
+			Vendor:   asrVendor,
+			Params:   asrParams,
+		},
+		TTS: &TTSConfig{
+			Vendor: ttsVendor,
+			Params: ttsParams,
+		},
+		SystemMessage: multilingualSystemMessage(languages),
+	}, nil
+}
+
+// multilingualSystemMessage tells the LLM which languages to expect and how to reply
+func multilingualSystemMessage(languages []string) SystemMessage {
+	names := make([]string, len(languages))
+	for i, language := range languages {
+		names[i] = fmt.Sprintf("%s (%s)", languageName(language), language)
+	}
+	return SystemMessage{
+		Role: "system",
+		Content: fmt.Sprintf("The user may speak any of these languages: %s. "+
+			"Always reply in the language of the user's latest message. "+
+			"If you cannot tell which language the user is speaking, reply in %s.",
+			strings.Join(names, ", "), languageName(languages[0])),
+	}
+}
+
+// languageName returns the display name of a locale, or the locale itself if unknown
+func languageName(locale string) string {
+	if name, ok := languageNames[locale]; ok {
+		return name
+	}
+	return locale
+}
@@ -0,0 +1,148 @@
+package convoai
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// withMultilingualVoices configures Deepgram ASR with the model, the Microsoft voice, and
+// Microsoft voices for Spanish and Japanese
+func withMultilingualVoices(model, voice string) func(*ConvoAIConfig) {
+	return func(config *ConvoAIConfig) {
+		config.ASRVendor = string(ASRVendorDeepgram)
+		config.ASR = map[ASRVendor]ASRVendorConfig{
+			ASRVendorDeepgram: &DeepgramASRConfig{Key: "dg-key", Model: model},
+		}
+		microsoft := *config.TTS[TTSVendorMicrosoft].(*MicrosoftTTSConfig)
+		microsoft.VoiceName = voice
+		microsoft.Voices = map[string]string{
+			"es-ES": "es-ES-AlvaroNeural",
+			"ja-JP": "ja-JP-KeitaNeural",
+		}
+		config.TTS = map[TTSVendor]TTSVendorConfig{TTSVendorMicrosoft: &microsoft}
+	}
+}
+
+func TestInviteAgentMultilingual(t *testing.T) {
+	tests := []struct {
+		name           string
+		model          string
+		voice          string
+		body           string
+		wantStatusCode int
+		wantVoice      string
+	}{
+		{
+			name:           "Primary language voice from the configured voice",
+			model:          "nova-3",
+			body:           `"multilingual": {"languages": ["en-US", "es-ES", "ja-JP"]}`,
+			wantStatusCode: http.StatusOK,
+			wantVoice:      "en-US-JennyNeural",
+		},
+		{
+			name:           "Primary language voice from the voice map",
+			model:          "nova-3",
+			body:           `"multilingual": {"languages": ["es-ES", "en-US"]}, "overrides": {"system_message": "You are a support bot."}`,
+			wantStatusCode: http.StatusOK,
+			wantVoice:      "es-ES-AlvaroNeural",
+		},
+		{
+			name:           "Multilingual voice for a language without a voice",
+			model:          "nova-3",
+			voice:          "en-US-AndrewMultilingualNeural",
+			body:           `"multilingual": {"languages": ["fr-FR", "es-ES"]}`,
+			wantStatusCode: http.StatusOK,
+			wantVoice:      "en-US-AndrewMultilingualNeural",
+		},
+		{
+			name:           "Single language",
+			model:          "nova-3",
+			body:           `"multilingual": {"languages": ["en-US"]}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Duplicate language",
+			model:          "nova-3",
+			body:           `"multilingual": {"languages": ["en-US", "en-US"]}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Language not allowed",
+			model:          "nova-3",
+			body:           `"multilingual": {"languages": ["en-US", "xx-XX"]}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Language without a voice",
+			model:          "nova-3",
+			body:           `"multilingual": {"languages": ["en-US", "fr-FR"]}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Combined with an ASR language override",
+			model:          "nova-3",
+			body:           `"multilingual": {"languages": ["en-US", "es-ES"]}, "overrides": {"asr_language": "es-ES"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "ASR model without multilingual support",
+			model:          "nova-2",
+			body:           `"multilingual": {"languages": ["en-US", "es-ES"]}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agora := newFakeAgora(t)
+			voice := tt.voice
+			if voice == "" {
+				voice = "en-US-JennyNeural"
+			}
+			router := newTestRouter(newTestService(t, agora, withMultilingualVoices(tt.model, voice)))
+
+			body := `{"requester_id": "user-1", "channel_name": "test-channel", ` + tt.body + `}`
+			if status := doJSON(t, router, "POST", "/agent/invite", body, nil); status != tt.wantStatusCode {
+				t.Fatalf("invite returned status %d, want %d", status, tt.wantStatusCode)
+			}
+			if tt.wantStatusCode != http.StatusOK {
+				if len(agora.joins) != 0 {
+					t.Errorf("join called for a rejected request")
+				}
+				return
+			}
+
+			props := agora.joins[0].Properties
+			asrParams, _ := props.ASR.Params.(map[string]interface{})
+			if props.ASR.Vendor != ASRVendorDeepgram || asrParams["language"] != "multi" {
+				t.Errorf("ASR not multilingual: %+v", props.ASR)
+			}
+			ttsParams, _ := props.TTS.Params.(map[string]interface{})
+			if ttsParams["voice_name"] != tt.wantVoice {
+				t.Errorf("voice_name = %v, want %s", ttsParams["voice_name"], tt.wantVoice)
+			}
+
+			messages := props.LLM.SystemMessages
+			last := messages[len(messages)-1].Content
+			if len(messages) != 2 || !strings.Contains(last, "Spanish (es-ES)") {
+				t.Errorf("language-aware system message missing: %+v", messages)
+			}
+		})
+	}
+}
+
+func TestInviteAgentMultilingualUnsupportedASR(t *testing.T) {
+	agora := newFakeAgora(t)
+	router := newTestRouter(newTestService(t, agora))
+
+	// The default Agora ASR recognizes one language per session
+	body := `{"requester_id": "user-1", "channel_name": "test-channel", "multilingual": {"languages": ["en-US", "es-ES"]}}`
+	var resp map[string]string
+	if status := doJSON(t, router, "POST", "/agent/invite", body, &resp); status != http.StatusBadRequest {
+		t.Fatalf("invite returned status %d, want %d", status, http.StatusBadRequest)
+	}
+	if !strings.Contains(resp["error"], "ares ASR does not support multilingual mode") {
+		t.Errorf("unexpected error: %q", resp["error"])
+	}
+}
@@ -0,0 +1,115 @@
+package convoai
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Defaults used for the agent when the request does not override them
+const (
+	defaultAgentSystemPrompt = "You are a helpful assistant. Pretend that the text input is audio, and you are responding to it. Speak fast, clearly, and concisely."
+	defaultAgentGreeting     = "Hello! How can I assist you today?"
+	defaultAgentFailure      = "Please wait a moment."
+	defaultAgentMaxHistory   = 10
+	defaultAgentMaxTokens    = 1024
+	defaultAgentTemperature  = 0.7
+	defaultAgentTopP         = 0.95
+	defaultAgentASRLanguage  = "en-US"
+	defaultAgentIdleTimeout  = 30
+)
+
+// Bounds enforced on per-request agent overrides, so clients can't request
+// settings that are expensive or that the Agora engine would reject
+const (
+	maxSystemMessageLength = 4000
+	maxShortMessageLength  = 500
+	minOverrideMaxHistory  = 1
+	maxOverrideMaxHistory  = 50
+	minOverrideMaxTokens   = 1
+	maxOverrideMaxTokens   = 4096
+	minOverrideTemperature = 0.0
+	maxOverrideTemperature = 2.0
+	minOverrideTopP        = 0.0
+	maxOverrideTopP        = 1.0
+	minOverrideIdleTimeout = 1
+	maxOverrideIdleTimeout = 3600
+)
+
+// allowedASRLanguages lists the ASR languages clients may select
+var allowedASRLanguages = map[string]bool{
+	"ar-SA": true, "de-DE": true, "en-GB": true, "en-IN": true, "en-US": true,
+	"es-ES": true, "es-MX": true, "fr-FR": true, "hi-IN": true, "id-ID": true,
+	"it-IT": true, "ja-JP": true, "ko-KR": true, "nl-NL": true, "pt-BR": true,
+	"pt-PT": true, "ru-RU": true, "th-TH": true, "tr-TR": true, "vi-VN": true,
+	"zh-CN": true, "zh-HK": true, "zh-TW": true,
+}
+
+// validateOverrides checks the per-request overrides against the server-side bounds
+func validateOverrides(o *AgentOverrides) error {
+	if o == nil {
+		return nil
+	}
+
+	if o.SystemMessage != nil && (*o.SystemMessage == "" || utf8.RuneCountInString(*o.SystemMessage) > maxSystemMessageLength) {
+		return fmt.Errorf("overrides.system_message must be between 1 and %d characters", maxSystemMessageLength)
+	}
+	if o.GreetingMessage != nil && utf8.RuneCountInString(*o.GreetingMessage) > maxShortMessageLength {
+		return fmt.Errorf("overrides.greeting_message must be at most %d characters", maxShortMessageLength)
+	}
+	if o.FailureMessage != nil && utf8.RuneCountInString(*o.FailureMessage) > maxShortMessageLength {
+		return fmt.Errorf("overrides.failure_message must be at most %d characters", maxShortMessageLength)
+	}
+	if o.MaxHistory != nil && (*o.MaxHistory < minOverrideMaxHistory || *o.MaxHistory > maxOverrideMaxHistory) {
+		return fmt.Errorf("overrides.max_history must be between %d and %d", minOverrideMaxHistory, maxOverrideMaxHistory)
+	}
+	if o.MaxTokens != nil && (*o.MaxTokens < minOverrideMaxTokens || *o.MaxTokens > maxOverrideMaxTokens) {
+		return fmt.Errorf("overrides.max_tokens must be between %d and %d", minOverrideMaxTokens, maxOverrideMaxTokens)
+	}
+	if o.Temperature != nil && (*o.Temperature < minOverrideTemperature || *o.Temperature > maxOverrideTemperature) {
+		return fmt.Errorf("overrides.temperature must be between %.1f and %.1f", minOverrideTemperature, maxOverrideTemperature)
+	}
+	if o.TopP != nil && (*o.TopP < minOverrideTopP || *o.TopP > maxOverrideTopP) {
+		return fmt.Errorf("overrides.top_p must be between %.1f and %.1f", minOverrideTopP, maxOverrideTopP)
+	}
+	if o.ASRLanguage != nil && !allowedASRLanguages[*o.ASRLanguage] {
+		return fmt.Errorf("overrides.asr_language %q is not supported", *o.ASRLanguage)
+	}
+	if o.IdleTimeout != nil && (*o.IdleTimeout < minOverrideIdleTimeout || *o.IdleTimeout > maxOverrideIdleTimeout) {
+		return fmt.Errorf("overrides.idle_timeout must be between %d and %d seconds", minOverrideIdleTimeout, maxOverrideIdleTimeout)
+	}
+
+	return nil
+}
+
+// applyOverrides replaces the agent properties with the values set in the overrides.
+// The ASR language is not applied here, as it is resolved along with the vendor params.
+func applyOverrides(props *Properties, o *AgentOverrides) {
+	if o == nil {
+		return
+	}
+
+	if o.SystemMessage != nil {
+		props.LLM.SystemMessages = []SystemMessage{{Role: "system", Content: *o.SystemMessage}}
+	}
+	if o.GreetingMessage != nil {
+		props.LLM.GreetingMessage = *o.GreetingMessage
+	}
+	if o.FailureMessage != nil {
+		props.LLM.FailureMessage = *o.FailureMessage
+	}
+	if o.MaxHistory != nil {
+		props.LLM.MaxHistory = *o.MaxHistory
+	}
+	if o.MaxTokens != nil {
+		props.LLM.Params.MaxTokens = *o.MaxTokens
+	}
+	if o.Temperature != nil {
+		props.LLM.Params.Temperature = *o.Temperature
+	}
+	if o.TopP != nil {
+		props.LLM.Params.TopP = *o.TopP
+	}
+	if o.IdleTimeout != nil {
+		props.IdleTimeout = *o.IdleTimeout
+	}
+}
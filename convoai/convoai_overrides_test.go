@@ -0,0 +1,83 @@
+package convoai
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestInviteAgentOverrides(t *testing.T) {
+	tests := []struct {
+		name           string
+		overrides      string
+		wantStatusCode int
+	}{
+		{
+			name:           "No overrides",
+			overrides:      ``,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "Valid overrides",
+			overrides:      `, "overrides": {"system_message": "You are a support bot.", "greeting_message": "Hi!", "max_tokens": 256, "temperature": 0.2, "asr_language": "es-ES", "idle_timeout": 120}`,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "Max tokens above bound",
+			overrides:      `, "overrides": {"max_tokens": 100000}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Temperature above bound",
+			overrides:      `, "overrides": {"temperature": 3.5}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Language not allowed",
+			overrides:      `, "overrides": {"asr_language": "xx-XX"}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "Empty system message",
+			overrides:      `, "overrides": {"system_message": ""}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agora := newFakeAgora(t)
+			router := newTestRouter(newTestService(t, agora))
+
+			body := `{"requester_id": "user-1", "channel_name": "test-channel"` + tt.overrides + `}`
+			if status := doJSON(t, router, "POST", "/agent/invite", body, nil); status != tt.wantStatusCode {
+				t.Fatalf("invite returned status %d, want %d", status, tt.wantStatusCode)
+			}
+			if tt.wantStatusCode != http.StatusOK {
+				if len(agora.joins) != 0 {
+					t.Errorf("join called for a rejected request")
+				}
+				return
+			}
+
+			props := agora.joins[0].Properties
+			if tt.overrides == "" {
+				if props.LLM.Params.MaxTokens != defaultAgentMaxTokens || props.ASR.Language != defaultAgentASRLanguage {
+					t.Errorf("defaults not applied: %+v", props)
+				}
+				return
+			}
+			if props.LLM.SystemMessages[0].Content != "You are a support bot." ||
+				props.LLM.GreetingMessage != "Hi!" ||
+				props.LLM.Params.MaxTokens != 256 ||
+				props.LLM.Params.Temperature != 0.2 ||
+				props.ASR.Language != "es-ES" ||
+				props.IdleTimeout != 120 {
+				t.Errorf("overrides not applied: %+v", props)
+			}
+			// Fields without an override keep their defaults
+			if props.LLM.MaxHistory != defaultAgentMaxHistory || props.LLM.FailureMessage != defaultAgentFailure {
+				t.Errorf("defaults lost: %+v", props.LLM)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package convoai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProfileName is the profile used when an invite does not name one
+const DefaultProfileName = "default"
+
+// AgentProfile is a named set of agent settings loaded from the profiles directory.
+// Fields missing from a profile file keep the values of DefaultAgentProfile.
+type AgentProfile struct {
+	Name             string          `json:"name" yaml:"name"`
+	SystemMessages   []SystemMessage `json:"system_messages" yaml:"system_messages"`
+	GreetingMessage  string          `json:"greeting_message" yaml:"greeting_message"`
+	FailureMessage   string          `json:"failure_message" yaml:"failure_message"`
+	MaxHistory       int             `json:"max_history" yaml:"max_history"`
+	LLMParams        LLMParams       `json:"llm_params" yaml:"llm_params"`
+	LLM              *ProfileLLM     `json:"llm,omitempty" yaml:"llm,omitempty"`
+	TTS              *ProfileTTS     `json:"tts,omitempty" yaml:"tts,omitempty"`
+	VAD              VAD             `json:"vad" yaml:"vad"`
+	ASR              *ProfileASR     `json:"asr,omitempty" yaml:"asr,omitempty"`
+	ASRLanguage      string          `json:"asr_language" yaml:"asr_language"`
+	IdleTimeout      int             `json:"idle_timeout" yaml:"idle_timeout"`
+	AdvancedFeatures Features        `json:"advanced_features" yaml:"advanced_features"`
+	RetrievalTopK    *int            `json:"retrieval_top_k,omitempty" yaml:"retrieval_top_k,omitempty"`
+	// Tools lists the tools offered to the agent, by name or by a prefix ending in "*".
+	// All tools are offered when it is not set.
+	Tools []string `json:"tools,omitempty" yaml:"tools,omitempty"`
+}
+
+// ProfileTTS selects the TTS vendor for a profile. Params are laid over the vendor
+// configuration from the environment, so a profile only needs to set what differs,
+// e.g. the voice.
+type ProfileTTS struct {
+	Vendor string                 `json:"vendor" yaml:"vendor"`
+	Params map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// ProfileLLM selects the LLM provider for a profile. Params are laid over the provider
+// configuration from the environment, e.g. to use another Azure deployment.
+type ProfileLLM struct {
+	Provider string                 `json:"provider" yaml:"provider"`
+	Params   map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// ProfileASR selects the ASR vendor for a profile. Like ProfileTTS, params are laid
+// over the vendor configuration from the environment.
+type ProfileASR struct {
+	Vendor string                 `json:"vendor" yaml:"vendor"`
+	Params map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// DefaultAgentProfile returns the settings used when no profile overrides them
+func DefaultAgentProfile() *AgentProfile {
+	return &AgentProfile{
+		Name: DefaultProfileName,
+		SystemMessages: []SystemMessage{
+			{Role: "system", Content: defaultAgentSystemPrompt},
+		},
+		GreetingMessage: defaultAgentGreeting,
+		FailureMessage:  defaultAgentFailure,
+		MaxHistory:      defaultAgentMaxHistory,
+		LLMParams: LLMParams{
+			MaxTokens:   defaultAgentMaxTokens,
+			Temperature: defaultAgentTemperature,
+			TopP:        defaultAgentTopP,
+		},
+		VAD: VAD{
+			SilenceDurationMS:   480,
+			SpeechDurationMS:    15000,
+			Threshold:           0.5,
+			InterruptDurationMS: 160,
+			PrefixPaddingMS:     300,
+		},
+		ASRLanguage: defaultAgentASRLanguage,
+		IdleTimeout: defaultAgentIdleTimeout,
+		AdvancedFeatures: Features{
+			EnableAIVAD: false,
+			EnableBHVS:  false,
+		},
+	}
+}
+
+// LoadProfiles reads every .yaml, .yml and .json file in dir as an agent profile.
+// The profile name defaults to the file name without its extension. The default
+// profile is always present, and may itself be overridden by a "default" file.
+func LoadProfiles(dir string) (map[string]*AgentProfile, error) {
+	profiles := map[string]*AgentProfile{
+		DefaultProfileName: DefaultAgentProfile(),
+	}
+	if dir == "" {
+		return profiles, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profiles directory %s: %v", dir, err)
+	}
+
+	// Sorted so duplicate names are reported deterministically
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	loaded := map[string]string{}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		profile, err := loadProfileFile(path, ext)
+		if err != nil {
+			return nil, err
+		}
+		if profile.Name == "" {
+			profile.Name = strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		}
+		if previous, ok := loaded[profile.Name]; ok {
+			return nil, fmt.Errorf("profile %q is defined in both %s and %s", profile.Name, previous, path)
+		}
+
+		loaded[profile.Name] = path
+		profiles[profile.Name] = profile
+	}
+
+	return profiles, nil
+}
+
+// loadProfileFile decodes a single profile file on top of the default profile
+func loadProfileFile(path, ext string) (*AgentProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile %s: %v", path, err)
+	}
+
+	profile := DefaultAgentProfile()
+	profile.Name = ""
+	if ext == ".json" {
+		err = json.Unmarshal(data, profile)
+	} else {
+		err = yaml.Unmarshal(data, profile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse profile %s: %v", path, err)
+	}
+	return profile, nil
+}
+
+// getProfile returns the named profile from the active configuration
+func (s *ConvoAIService) getProfile(name string) (*AgentProfile, error) {
+	return lookupProfile(s.getConfig(), name)
+}
+
+// lookupProfile returns the named profile, or the default profile when name is empty
+func lookupProfile(config *ConvoAIConfig, name string) (*AgentProfile, error) {
+	if name == "" {
+		name = DefaultProfileName
+	}
+	if profile, ok := config.Profiles[name]; ok {
+		return profile, nil
+	}
+	if name == DefaultProfileName {
+		return DefaultAgentProfile(), nil
+	}
+	return nil, fmt.Errorf("unknown profile: %s", name)
+}
+
+// overlayParams decodes the profile params over an existing configuration
+func overlayParams(dst interface{}, params map[string]interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// overlayVendorConfig lays profile params over a copy of a vendor or provider configuration,
+// created with newConfig, and validates the result. Without params, base is returned as is.
+func overlayVendorConfig[C interface{ Validate() error }](newConfig func() C, base C, params map[string]interface{}) (C, error) {
+	if len(params) == 0 {
+		return base, nil
+	}
+
+	var zero C
+	data, err := json.Marshal(base)
+	if err != nil {
+		return zero, err
+	}
+	vendorConfig := newConfig()
+	if err := json.Unmarshal(data, vendorConfig); err != nil {
+		return zero, err
+	}
+	if err := overlayParams(vendorConfig, params); err != nil {
+		return zero, err
+	}
+	if err := vendorConfig.Validate(); err != nil {
+		return zero, err
+	}
+	return vendorConfig, nil
+}
@@ -0,0 +1,118 @@
+package convoai
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testSupportProfile = `
+system_messages:
+  - role: system
+    content: You are a support agent for Example Inc.
+greeting_message: Hi, this is Example support.
+llm_params:
+  max_tokens: 512
+  temperature: 0.3
+  top_p: 0.9
+tts:
+  vendor: microsoft
+  params:
+    voice_name: en-US-JennyNeural
+asr_language: en-GB
+`
+
+const testSalesProfile = `{
+  "name": "sales",
+  "greeting_message": "Hello from sales!",
+  "idle_timeout": 60
+}`
+
+// writeTestProfiles writes the test profiles into a temporary directory
+func writeTestProfiles(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"support.yaml": testSupportProfile,
+		"sales.json":   testSalesProfile,
+		"README.md":    "not a profile",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	return dir
+}
+
+func TestLoadProfiles(t *testing.T) {
+	profiles, err := LoadProfiles(writeTestProfiles(t))
+	if err != nil {
+		t.Fatalf("LoadProfiles() error = %v", err)
+	}
+	if len(profiles) != 3 {
+		t.Fatalf("LoadProfiles() returned %d profiles, want default, support and sales", len(profiles))
+	}
+
+	support := profiles["support"]
+	if support == nil || support.LLMParams.MaxTokens != 512 || support.ASRLanguage != "en-GB" {
+		t.Errorf("unexpected support profile: %+v", support)
+	}
+	// Fields missing from the file keep their defaults
+	if support.MaxHistory != defaultAgentMaxHistory || support.VAD.SilenceDurationMS != 480 {
+		t.Errorf("support profile lost its defaults: %+v", support)
+	}
+
+	sales := profiles["sales"]
+	if sales == nil || sales.IdleTimeout != 60 || sales.SystemMessages[0].Content != defaultAgentSystemPrompt {
+		t.Errorf("unexpected sales profile: %+v", sales)
+	}
+}
+
+func TestLoadProfilesRejectsDuplicateNames(t *testing.T) {
+	dir := writeTestProfiles(t)
+	os.WriteFile(filepath.Join(dir, "sales-copy.yaml"), []byte("name: sales\n"), 0600)
+
+	if _, err := LoadProfiles(dir); err == nil {
+		t.Errorf("LoadProfiles() accepted two profiles named sales")
+	}
+}
+
+func TestInviteAgentWithProfile(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	profiles, err := LoadProfiles(writeTestProfiles(t))
+	if err != nil {
+		t.Fatalf("LoadProfiles() error = %v", err)
+	}
+	service.getConfig().Profiles = profiles
+	router := newTestRouter(service)
+
+	body := `{"requester_id": "user-1", "channel_name": "test-channel", "profile": "support", "overrides": {"temperature": 0.5}}`
+	if status := doJSON(t, router, "POST", "/agent/invite", body, nil); status != http.StatusOK {
+		t.Fatalf("invite returned status %d", status)
+	}
+
+	props := agora.joins[0].Properties
+	if props.LLM.GreetingMessage != "Hi, this is Example support." || props.ASR.Language != "en-GB" {
+		t.Errorf("profile not applied: %+v", props)
+	}
+	// Overrides still apply on top of the profile
+	if props.LLM.Params.Temperature != 0.5 || props.LLM.Params.MaxTokens != 512 {
+		t.Errorf("unexpected LLM params: %+v", props.LLM.Params)
+	}
+	// The model falls back to the configured one
+	if props.LLM.Params.Model != "gpt-4o-mini" {
+		t.Errorf("model = %q, want %q", props.LLM.Params.Model, "gpt-4o-mini")
+	}
+	params := props.TTS.Params.(map[string]interface{})
+	if params["voice_name"] != "en-US-JennyNeural" || params["key"] != "ms-key" {
+		t.Errorf("TTS params not resolved per profile: %v", params)
+	}
+
+	body = `{"requester_id": "user-1", "channel_name": "test-channel", "profile": "unknown"}`
+	if status := doJSON(t, router, "POST", "/agent/invite", body, nil); status != http.StatusBadRequest {
+		t.Errorf("invite with unknown profile returned status %d, want %d", status, http.StatusBadRequest)
+	}
+}
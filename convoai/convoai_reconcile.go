@@ -0,0 +1,104 @@
+package convoai
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// ReconcileSessions compares the recorded sessions against the agents Agora reports as running.
+// Sessions whose agent is no longer running are cleared. Running agents handed off by a previous
+// instance are adopted by this one. Running agents whose owning server instance is gone, or whose
+// session has expired, are removed through the same leave flow as HandleRemoveAgent. Agents
+// without a recorded session are left untouched, as they may belong to another server sharing
+// the same AppID.
+func (s *ConvoAIService) ReconcileSessions() (*ReconcileResult, error) {
+	sessions, err := s.sessions.List(SessionFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %v", err)
+	}
+
+	result := &ReconcileResult{
+		Removed: []string{},
+		Adopted: []string{},
+		Cleared: []string{},
+		Kept:    []string{},
+		Failed:  map[string]string{},
+	}
+	if len(sessions) == 0 {
+		return result, nil
+	}
+
+	agoraAgents, err := s.listAgoraAgents("")
+	if err != nil {
+		return nil, err
+	}
+	running := make(map[string]bool, len(agoraAgents))
+	for _, agent := range agoraAgents {
+		if isAgentActive(agent.Status) {
+			running[agent.AgentID] = true
+		}
+	}
+
+	now := time.Now().Unix()
+	for _, session := range sessions {
+		switch {
+		case !running[session.AgentID]:
+			if err := s.sessions.Delete(session.AgentID); err != nil {
+				result.Failed[session.AgentID] = err.Error()
+				continue
+			}
+			result.Cleared = append(result.Cleared, session.AgentID)
+
+		case session.InstanceID == s.instanceID && (session.ExpireTS == 0 || session.ExpireTS > now):
+			result.Kept = append(result.Kept, session.AgentID)
+
+		case session.HandedOff && (session.ExpireTS == 0 || session.ExpireTS > now):
+			session.InstanceID = s.instanceID
+			session.HandedOff = false
+			if err := s.sessions.Save(session); err != nil {
+				result.Failed[session.AgentID] = err.Error()
+				continue
+			}
+			result.Adopted = append(result.Adopted, session.AgentID)
+
+		default:
+			if _, err := s.HandleRemoveAgent(RemoveAgentRequest{AgentID: session.AgentID}); err != nil {
+				// Keep the session so the next reconciliation retries the removal
+				result.Failed[session.AgentID] = err.Error()
+				continue
+			}
+			result.Removed = append(result.Removed, session.AgentID)
+		}
+	}
+
+	return result, nil
+}
+
+// LogReconcileResult logs the outcome of a reconciliation, one line per agent acted on
+func LogReconcileResult(result *ReconcileResult) {
+	for _, agentID := range result.Removed {
+		log.Printf("- removed orphaned agent %s", agentID)
+	}
+	for _, agentID := range result.Adopted {
+		log.Printf("- adopted handed off agent %s", agentID)
+	}
+	for _, agentID := range result.Cleared {
+		log.Printf("- cleared session for stopped agent %s", agentID)
+	}
+	for agentID, reason := range result.Failed {
+		log.Printf("- failed to reconcile agent %s: %s", agentID, reason)
+	}
+	log.Printf("Reconciliation completed: %d removed, %d adopted, %d cleared, %d kept, %d failed",
+		len(result.Removed), len(result.Adopted), len(result.Cleared), len(result.Kept), len(result.Failed))
+}
+
+// isAgentActive reports whether an Agora agent status means the agent may still be in its channel
+func isAgentActive(status string) bool {
+	switch status {
+	case "STOPPED", "FAILED":
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,84 @@
+package convoai
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestReconcileSessions(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+
+	now := time.Now().Unix()
+	sessions := []AgentSession{
+		// Started by a previous run of the server and still running
+		{AgentID: "ORPHANED", InstanceID: "previous-instance", ExpireTS: now + 600},
+		// Recorded, but the agent already stopped on Agora's side
+		{AgentID: "STOPPED", InstanceID: "previous-instance", ExpireTS: now + 600},
+		// Started by this instance and still within its token lifetime
+		{AgentID: "CURRENT", InstanceID: service.instanceID, ExpireTS: now + 600},
+		// Started by this instance but its session has expired
+		{AgentID: "EXPIRED", InstanceID: service.instanceID, ExpireTS: now - 60},
+	}
+	for _, session := range sessions {
+		if err := service.sessions.Save(session); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	agora.addAgent("ORPHANED")
+	agora.addAgent("CURRENT")
+	agora.addAgent("EXPIRED")
+	// Running but unknown to this server, e.g. started by another deployment
+	agora.addAgent("EXTERNAL")
+
+	result, err := service.ReconcileSessions()
+	if err != nil {
+		t.Fatalf("ReconcileSessions() error = %v", err)
+	}
+
+	sort.Strings(result.Removed)
+	if len(result.Removed) != 2 || result.Removed[0] != "EXPIRED" || result.Removed[1] != "ORPHANED" {
+		t.Errorf("Removed = %v, want [EXPIRED ORPHANED]", result.Removed)
+	}
+	if len(result.Cleared) != 1 || result.Cleared[0] != "STOPPED" {
+		t.Errorf("Cleared = %v, want [STOPPED]", result.Cleared)
+	}
+	if len(result.Kept) != 1 || result.Kept[0] != "CURRENT" {
+		t.Errorf("Kept = %v, want [CURRENT]", result.Kept)
+	}
+	if len(result.Failed) != 0 {
+		t.Errorf("Failed = %v, want none", result.Failed)
+	}
+
+	left := agora.leftAgents()
+	sort.Strings(left)
+	if len(left) != 2 || left[0] != "EXPIRED" || left[1] != "ORPHANED" {
+		t.Errorf("leave called for %v, want [EXPIRED ORPHANED]", left)
+	}
+
+	remaining, _ := service.sessions.List(SessionFilter{})
+	if len(remaining) != 1 || remaining[0].AgentID != "CURRENT" {
+		t.Errorf("remaining sessions = %+v, want only CURRENT", remaining)
+	}
+}
+
+func TestReconcileSessionsKeepsSessionWhenLeaveFails(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+
+	service.sessions.Save(AgentSession{AgentID: "ORPHANED", InstanceID: "previous-instance"})
+	agora.addAgent("ORPHANED")
+	agora.failFor["/agents/ORPHANED/leave"] = 500
+
+	result, err := service.ReconcileSessions()
+	if err != nil {
+		t.Fatalf("ReconcileSessions() error = %v", err)
+	}
+	if _, failed := result.Failed["ORPHANED"]; !failed {
+		t.Errorf("Failed = %v, want ORPHANED", result.Failed)
+	}
+	if _, ok, _ := service.sessions.Get("ORPHANED"); !ok {
+		t.Errorf("session was deleted although the leave call failed")
+	}
+}
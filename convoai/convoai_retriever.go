@@ -0,0 +1,270 @@
+package convoai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Retrieval defaults and limits
+const (
+	defaultRetrievalTopK = 3
+	MaxRetrievalTopK     = 10
+	maxSnippetLength     = 800
+)
+
+// BM25 tuning parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Retriever finds knowledge base snippets relevant to a user turn
+type Retriever interface {
+	// Retrieve returns up to limit snippets for the query, best match first
+	Retrieve(ctx context.Context, query string, limit int) ([]Snippet, error)
+}
+
+// Snippet is a passage returned by a Retriever
+type Snippet struct {
+	Source string  `json:"source"`
+	Text   string  `json:"text"`
+	Score  float64 `json:"score"`
+}
+
+// BM25Index is an in-memory BM25 index over the text documents of a directory.
+// Documents are split into paragraph-sized snippets, each scored on its own.
+type BM25Index struct {
+	// Digest identifies the indexed content, so reloads pick up changed documents
+	Digest string
+
+	snippets  []Snippet
+	lengths   []int
+	avgLength float64
+	postings  map[string][]bm25Posting
+}
+
+// bm25Posting is the frequency of a term in one snippet
+type bm25Posting struct {
+	snippet int
+	count   int
+}
+
+// LoadBM25Index indexes every .md and .txt file under dir
+func LoadBM25Index(dir string) (*BM25Index, error) {
+	index := &BM25Index{postings: make(map[string][]bm25Posting)}
+	digest := sha256.New()
+
+	err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if entry.IsDir() || (ext != ".md" && ext != ".txt") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		source, _ := filepath.Rel(dir, path)
+		digest.Write([]byte(source))
+		digest.Write(data)
+		for _, text := range splitSnippets(string(data)) {
+			index.add(Snippet{Source: source, Text: text})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index documents in %s: %v", dir, err)
+	}
+
+	total := 0
+	for _, length := range index.lengths {
+		total += length
+	}
+	if len(index.lengths) > 0 {
+		index.avgLength = float64(total) / float64(len(index.lengths))
+	}
+	index.Digest = hex.EncodeToString(digest.Sum(nil))[:12]
+	return index, nil
+}
+
+// add indexes a snippet
+func (x *BM25Index) add(snippet Snippet) {
+	terms := tokenize(snippet.Text)
+	if len(terms) == 0 {
+		return
+	}
+	id := len(x.snippets)
+	x.snippets = append(x.snippets, snippet)
+	x.lengths = append(x.lengths, len(terms))
+
+	counts := make(map[string]int)
+	for _, term := range terms {
+		counts[term]++
+	}
+	for term, count := range counts {
+		x.postings[term] = append(x.postings[term], bm25Posting{snippet: id, count: count})
+	}
+}
+
+// Len returns the number of indexed snippets
+func (x *BM25Index) Len() int {
+	return len(x.snippets)
+}
+
+// Retrieve scores the snippets against the query with BM25
+func (x *BM25Index) Retrieve(ctx context.Context, query string, limit int) ([]Snippet, error) {
+	if limit <= 0 || len(x.snippets) == 0 {
+		return nil, nil
+	}
+
+	scores := make(map[int]float64)
+	n := float64(len(x.snippets))
+	seen := make(map[string]bool)
+	for _, term := range tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		postings := x.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			tf := float64(p.count)
+			norm := 1 - bm25B + bm25B*float64(x.lengths[p.snippet])/x.avgLength
+			scores[p.snippet] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+
+	results := make([]Snippet, 0, len(scores))
+	for id, score := range scores {
+		snippet := x.snippets[id]
+		snippet.Score = score
+		results = append(results, snippet)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].Source < results[j].Source
+		}
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// splitSnippets splits a document into paragraphs, merging short ones and
+// splitting long ones so every snippet stays under maxSnippetLength
+func splitSnippets(text string) []string {
+	var snippets []string
+	var current strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			snippets = append(snippets, s)
+		}
+		current.Reset()
+	}
+
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		if current.Len() > 0 && current.Len()+len(paragraph)+2 > maxSnippetLength {
+			flush()
+		}
+		for len(paragraph) > maxSnippetLength {
+			cut := strings.LastIndexAny(paragraph[:maxSnippetLength], " \n")
+			if cut <= 0 {
+				cut = maxSnippetLength
+			}
+			current.WriteString(paragraph[:cut])
+			flush()
+			paragraph = strings.TrimSpace(paragraph[cut:])
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(paragraph)
+	}
+	flush()
+	return snippets
+}
+
+// stopWords are left out of the index and queries
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "do": true, "for": true, "from": true, "how": true, "i": true, "in": true,
+	"is": true, "it": true, "me": true, "my": true, "of": true, "on": true, "or": true,
+	"that": true, "the": true, "this": true, "to": true, "was": true, "what": true,
+	"when": true, "where": true, "with": true, "you": true, "your": true,
+}
+
+// tokenize lowercases text and splits it into words, dropping stop words. Plural
+// endings are trimmed so "refund" matches "refunds".
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := words[:0]
+	for _, word := range words {
+		if stopWords[word] {
+			continue
+		}
+		if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			word = word[:len(word)-1]
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// retrievalMessages turns snippets into the system messages injected before the conversation
+func retrievalMessages(snippets []Snippet) []SystemMessage {
+	messages := make([]SystemMessage, 0, len(snippets))
+	for _, snippet := range snippets {
+		messages = append(messages, SystemMessage{
+			Role:    "system",
+			Content: fmt.Sprintf("Knowledge base excerpt from %s. Use it to answer if it is relevant:\n%s", snippet.Source, snippet.Text),
+		})
+	}
+	return messages
+}
+
+// retrieveContext returns the knowledge base messages for the user turn, using the
+// profile's snippet count when it sets one. Retrieval errors are logged, not returned,
+// so the agent still answers without the knowledge base.
+func retrieveContext(ctx context.Context, config *ConvoAIConfig, profile *AgentProfile, query string) []SystemMessage {
+	if config.Retriever == nil || strings.TrimSpace(query) == "" {
+		return nil
+	}
+	topK := config.RetrievalTopK
+	if topK == 0 {
+		topK = defaultRetrievalTopK
+	}
+	if profile != nil && profile.RetrievalTopK != nil {
+		topK = *profile.RetrievalTopK
+	}
+
+	snippets, err := config.Retriever.Retrieve(ctx, query, topK)
+	if err != nil {
+		log.Printf("Warning: knowledge base retrieval failed: %v", err)
+		return nil
+	}
+	return retrievalMessages(snippets)
+}
@@ -0,0 +1,147 @@
+package convoai
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeKnowledgeBase writes a small knowledge base to a temporary directory
+func writeKnowledgeBase(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"shipping.md": "# Shipping\n\nStandard shipping takes 3 to 5 business days.\n\n" +
+			"Express shipping delivers the next business day for orders placed before noon.",
+		"returns.txt":    "Returns are accepted within 30 days of delivery. Refunds are issued to the original payment method.",
+		"faq/hours.md":   "Our support team is available Monday to Friday, 9am to 6pm.",
+		"notes/skip.pdf": "Express shipping is free for members.",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestBM25Index(t *testing.T) {
+	index, err := LoadBM25Index(writeKnowledgeBase(t))
+	if err != nil {
+		t.Fatalf("LoadBM25Index() error = %v", err)
+	}
+	// Short paragraphs of a document are merged, and only .md and .txt files are indexed
+	if index.Len() != 3 {
+		t.Errorf("Len() = %d, want 3 snippets", index.Len())
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		limit      int
+		wantSource string
+		wantText   string
+		wantCount  int
+	}{
+		{
+			name:       "Best match first",
+			query:      "How many days until my order is delivered?",
+			limit:      3,
+			wantSource: "shipping.md",
+			wantText:   "Express shipping delivers",
+			wantCount:  2,
+		},
+		{
+			name:       "Nested documents",
+			query:      "When is support available?",
+			limit:      3,
+			wantSource: filepath.Join("faq", "hours.md"),
+			wantText:   "Monday to Friday",
+			wantCount:  1,
+		},
+		{
+			name:       "Limit",
+			query:      "What is the refund policy for a delivery?",
+			limit:      1,
+			wantSource: "returns.txt",
+			wantText:   "Refunds",
+			wantCount:  1,
+		},
+		{
+			name:      "No match",
+			query:     "What is the meaning of life?",
+			limit:     3,
+			wantCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippets, err := index.Retrieve(context.Background(), tt.query, tt.limit)
+			if err != nil {
+				t.Fatalf("Retrieve() error = %v", err)
+			}
+			if len(snippets) != tt.wantCount {
+				t.Fatalf("Retrieve() returned %d snippets, want %d: %+v", len(snippets), tt.wantCount, snippets)
+			}
+			if tt.wantCount == 0 {
+				return
+			}
+			if snippets[0].Source != tt.wantSource || !strings.Contains(snippets[0].Text, tt.wantText) {
+				t.Errorf("best snippet = %+v, want %s containing %q", snippets[0], tt.wantSource, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestSplitSnippets(t *testing.T) {
+	long := strings.Repeat("word ", 400)
+	snippets := splitSnippets("Short one.\n\nShort two.\n\n" + long)
+	if len(snippets) < 3 || snippets[0] != "Short one.\n\nShort two." {
+		t.Fatalf("unexpected snippets: %q", snippets)
+	}
+	for _, snippet := range snippets {
+		if len(snippet) > maxSnippetLength {
+			t.Errorf("snippet of %d characters exceeds %d", len(snippet), maxSnippetLength)
+		}
+	}
+}
+
+func TestLLMProxyRetrieval(t *testing.T) {
+	index, err := LoadBM25Index(writeKnowledgeBase(t))
+	if err != nil {
+		t.Fatalf("LoadBM25Index() error = %v", err)
+	}
+
+	agora := newFakeAgora(t)
+	llm := newFakeLLM(t)
+	service := newTestService(t, agora, withLLMProxy(llm, 0), func(config *ConvoAIConfig) {
+		config.Retriever = index
+		config.RetrievalTopK = 1
+	})
+	router := newTestRouter(service)
+	agentLLM := inviteProxiedAgent(t, router, agora)
+
+	body := `{"messages": [
+		{"role": "system", "content": "You are a support agent."},
+		{"role": "user", "content": "Can I get a refund?"}]}`
+	if rr := doProxy(router, agentLLM.APIKey, body); rr.Code != http.StatusOK {
+		t.Fatalf("proxy returned status %d: %s", rr.Code, rr.Body.String())
+	}
+
+	messages := llm.requests[0]["messages"].([]interface{})
+	if len(messages) != 3 {
+		t.Fatalf("upstream messages = %v, want the snippet after the system prompt", messages)
+	}
+	snippet := messages[1].(map[string]interface{})
+	if snippet["role"] != "system" || !strings.Contains(snippet["content"].(string), "Refunds are issued") {
+		t.Errorf("unexpected snippet message: %v", snippet)
+	}
+}
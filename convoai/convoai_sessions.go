@@ -0,0 +1,328 @@
+package convoai
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// SessionStore types supported by NewSessionStore
+const (
+	SessionStoreMemory = "memory"
+	SessionStoreBolt   = "bolt"
+)
+
+// SessionStore persists the agents invited by this server so they can be
+// listed, queried and reconciled after a restart
+type SessionStore interface {
+	// Save records or replaces the session for session.AgentID
+	Save(session AgentSession) error
+	// Get returns the session for the agent, and false if it isn't recorded
+	Get(agentID string) (AgentSession, bool, error)
+	// GetByName returns the session of the agent with the given name, and false if it isn't recorded
+	GetByName(name string) (AgentSession, bool, error)
+	// Delete forgets the session for the agent; deleting an unknown agent is not an error
+	Delete(agentID string) error
+	// List returns the recorded sessions matching the filter, oldest first
+	List(filter SessionFilter) ([]AgentSession, error)
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// SessionFilter narrows the sessions returned by SessionStore.List. Empty fields match everything.
+type SessionFilter struct {
+	Name        string
+	ChannelName string
+	RequesterID string
+	InstanceID  string
+}
+
+// matches reports whether the session passes the filter
+func (f SessionFilter) matches(session AgentSession) bool {
+	if f.Name != "" && session.Name != f.Name {
+		return false
+	}
+	if f.ChannelName != "" && session.ChannelName != f.ChannelName {
+		return false
+	}
+	if f.RequesterID != "" && session.RequesterID != f.RequesterID {
+		return false
+	}
+	if f.InstanceID != "" && session.InstanceID != f.InstanceID {
+		return false
+	}
+	return true
+}
+
+// NewSessionStore creates the session store selected in the configuration
+func NewSessionStore(config *ConvoAIConfig) (SessionStore, error) {
+	switch config.SessionStore {
+	case "", SessionStoreMemory:
+		return NewMemorySessionStore(), nil
+	case SessionStoreBolt:
+		return NewBoltSessionStore(config.SessionStorePath)
+	default:
+		return nil, fmt.Errorf("unsupported session store: %s", config.SessionStore)
+	}
+}
+
+// sortSessions orders sessions oldest first so listings are stable between calls
+func sortSessions(sessions []AgentSession) {
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].CreateTS == sessions[j].CreateTS {
+			return sessions[i].AgentID < sessions[j].AgentID
+		}
+		return sessions[i].CreateTS < sessions[j].CreateTS
+	})
+}
+
+// MemorySessionStore keeps sessions in process memory; they are lost on restart
+type MemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]AgentSession
+	names    map[string]string // agent IDs by agent name
+}
+
+// NewMemorySessionStore creates an empty in-memory session store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: make(map[string]AgentSession),
+		names:    make(map[string]string),
+	}
+}
+
+// Save records a session
+func (m *MemorySessionStore) Save(session AgentSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unindexName(session.AgentID)
+	m.sessions[session.AgentID] = session
+	if session.Name != "" {
+		m.names[session.Name] = session.AgentID
+	}
+	return nil
+}
+
+// Get returns the session recorded for the given agent
+func (m *MemorySessionStore) Get(agentID string) (AgentSession, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, ok := m.sessions[agentID]
+	return session, ok, nil
+}
+
+// GetByName returns the session recorded for the agent with the given name
+func (m *MemorySessionStore) GetByName(name string) (AgentSession, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, ok := m.sessions[m.names[name]]
+	return session, ok, nil
+}
+
+// Delete forgets the session for the given agent
+func (m *MemorySessionStore) Delete(agentID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.unindexName(agentID)
+	delete(m.sessions, agentID)
+	return nil
+}
+
+// unindexName drops the name of the agent's recorded session from the name index
+func (m *MemorySessionStore) unindexName(agentID string) {
+	if session, ok := m.sessions[agentID]; ok && m.names[session.Name] == agentID {
+		delete(m.names, session.Name)
+	}
+}
+
+// List returns the sessions matching the filter
+func (m *MemorySessionStore) List(filter SessionFilter) ([]AgentSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessions := make([]AgentSession, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		if filter.matches(session) {
+			sessions = append(sessions, session)
+		}
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+// Close is a no-op for the in-memory store
+func (m *MemorySessionStore) Close() error {
+	return nil
+}
+
+// BoltDB buckets holding the JSON encoded sessions, and the agent IDs by agent name
+var (
+	boltSessionsBucket     = []byte("agent_sessions")
+	boltSessionNamesBucket = []byte("agent_session_names")
+)
+
+// BoltSessionStore keeps sessions in a BoltDB file so they survive restarts
+type BoltSessionStore struct {
+	db *bolt.DB
+}
+
+// NewBoltSessionStore opens (or creates) the BoltDB file at path
+func NewBoltSessionStore(path string) (*BoltSessionStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("session store path is required for the %s store", SessionStoreBolt)
+	}
+
+	// Fail fast instead of blocking forever if another process holds the file lock
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session store %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		sessions, err := tx.CreateBucketIfNotExists(boltSessionsBucket)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists(boltSessionNamesBucket); err != nil {
+			return err
+		}
+		// Index the sessions recorded before the name index existed
+		return sessions.ForEach(func(key, data []byte) error {
+			var session AgentSession
+			if err := json.Unmarshal(data, &session); err != nil {
+				return fmt.Errorf("failed to decode session %s: %v", key, err)
+			}
+			return indexBoltSessionName(tx, session)
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize session store: %v", err)
+	}
+
+	return &BoltSessionStore{db: db}, nil
+}
+
+// Save records a session
+func (b *BoltSessionStore) Save(session AgentSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %v", err)
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := unindexBoltSessionName(tx, session.AgentID); err != nil {
+			return err
+		}
+		if err := tx.Bucket(boltSessionsBucket).Put([]byte(session.AgentID), data); err != nil {
+			return err
+		}
+		return indexBoltSessionName(tx, session)
+	})
+}
+
+// Get returns the session recorded for the given agent
+func (b *BoltSessionStore) Get(agentID string) (AgentSession, bool, error) {
+	var session AgentSession
+	var found bool
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltSessionsBucket).Get([]byte(agentID))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &session)
+	})
+	if err != nil {
+		return AgentSession{}, false, fmt.Errorf("failed to read session %s: %v", agentID, err)
+	}
+	return session, found, nil
+}
+
+// GetByName returns the session recorded for the agent with the given name
+func (b *BoltSessionStore) GetByName(name string) (AgentSession, bool, error) {
+	var session AgentSession
+	var found bool
+	err := b.db.View(func(tx *bolt.Tx) error {
+		agentID := tx.Bucket(boltSessionNamesBucket).Get([]byte(name))
+		if agentID == nil {
+			return nil
+		}
+		data := tx.Bucket(boltSessionsBucket).Get(agentID)
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &session)
+	})
+	if err != nil {
+		return AgentSession{}, false, fmt.Errorf("failed to read session of agent %s: %v", name, err)
+	}
+	return session, found, nil
+}
+
+// Delete forgets the session for the given agent
+func (b *BoltSessionStore) Delete(agentID string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := unindexBoltSessionName(tx, agentID); err != nil {
+			return err
+		}
+		return tx.Bucket(boltSessionsBucket).Delete([]byte(agentID))
+	})
+}
+
+// indexBoltSessionName records the session's agent ID under its agent name
+func indexBoltSessionName(tx *bolt.Tx, session AgentSession) error {
+	if session.Name == "" {
+		return nil
+	}
+	return tx.Bucket(boltSessionNamesBucket).Put([]byte(session.Name), []byte(session.AgentID))
+}
+
+// unindexBoltSessionName drops the name of the agent's recorded session from the name index
+func unindexBoltSessionName(tx *bolt.Tx, agentID string) error {
+	data := tx.Bucket(boltSessionsBucket).Get([]byte(agentID))
+	if data == nil {
+		return nil
+	}
+	var session AgentSession
+	if err := json.Unmarshal(data, &session); err != nil || session.Name == "" {
+		// An unreadable session has no usable index entry
+		return nil
+	}
+	names := tx.Bucket(boltSessionNamesBucket)
+	if string(names.Get([]byte(session.Name))) != agentID {
+		return nil
+	}
+	return names.Delete([]byte(session.Name))
+}
+
+// List returns the sessions matching the filter
+func (b *BoltSessionStore) List(filter SessionFilter) ([]AgentSession, error) {
+	sessions := []AgentSession{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltSessionsBucket).ForEach(func(key, data []byte) error {
+			var session AgentSession
+			if err := json.Unmarshal(data, &session); err != nil {
+				return fmt.Errorf("failed to decode session %s: %v", key, err)
+			}
+			if filter.matches(session) {
+				sessions = append(sessions, session)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+// Close closes the underlying BoltDB file
+func (b *BoltSessionStore) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,123 @@
+package convoai
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionStores(t *testing.T) {
+	tests := []struct {
+		name     string
+		newStore func(t *testing.T) SessionStore
+	}{
+		{
+			name: "Memory store",
+			newStore: func(t *testing.T) SessionStore {
+				return NewMemorySessionStore()
+			},
+		},
+		{
+			name: "Bolt store",
+			newStore: func(t *testing.T) SessionStore {
+				store, err := NewBoltSessionStore(filepath.Join(t.TempDir(), "sessions.db"))
+				if err != nil {
+					t.Fatalf("NewBoltSessionStore() error = %v", err)
+				}
+				return store
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.newStore(t)
+			defer store.Close()
+
+			sessions := []AgentSession{
+				{AgentID: "agent-2", Name: "name-2", ChannelName: "channel-a", RequesterID: "user-2", CreateTS: 200},
+				{AgentID: "agent-1", Name: "name-1", ChannelName: "channel-a", RequesterID: "user-1", CreateTS: 100},
+				{AgentID: "agent-3", Name: "name-3", ChannelName: "channel-b", RequesterID: "user-1", CreateTS: 300},
+			}
+			for _, session := range sessions {
+				if err := store.Save(session); err != nil {
+					t.Fatalf("Save() error = %v", err)
+				}
+			}
+
+			got, ok, err := store.Get("agent-1")
+			if err != nil || !ok || got.RequesterID != "user-1" {
+				t.Errorf("Get() = %+v, %v, %v", got, ok, err)
+			}
+
+			got, ok, err = store.GetByName("name-2")
+			if err != nil || !ok || got.AgentID != "agent-2" {
+				t.Errorf("GetByName() = %+v, %v, %v", got, ok, err)
+			}
+
+			// Saving a session again under another name moves it in the name index
+			renamed := sessions[0]
+			renamed.Name = "name-2b"
+			store.Save(renamed)
+			if _, ok, _ := store.GetByName("name-2"); ok {
+				t.Errorf("GetByName() found a session by its previous name")
+			}
+			if got, ok, _ := store.GetByName("name-2b"); !ok || got.AgentID != "agent-2" {
+				t.Errorf("GetByName() of the new name = %+v, %v", got, ok)
+			}
+
+			all, err := store.List(SessionFilter{})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if len(all) != 3 || all[0].AgentID != "agent-1" || all[2].AgentID != "agent-3" {
+				t.Errorf("List() returned %+v, want 3 sessions oldest first", all)
+			}
+
+			filtered, _ := store.List(SessionFilter{ChannelName: "channel-a", RequesterID: "user-1"})
+			if len(filtered) != 1 || filtered[0].AgentID != "agent-1" {
+				t.Errorf("List(filter) returned %+v", filtered)
+			}
+
+			if err := store.Delete("agent-1"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, ok, _ := store.Get("agent-1"); ok {
+				t.Errorf("Get() found a deleted session")
+			}
+			if _, ok, _ := store.GetByName("name-1"); ok {
+				t.Errorf("GetByName() found a deleted session")
+			}
+			if err := store.Delete("unknown"); err != nil {
+				t.Errorf("Delete() of an unknown agent error = %v", err)
+			}
+		})
+	}
+}
+
+func TestBoltSessionStoreSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+
+	store, err := NewBoltSessionStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltSessionStore() error = %v", err)
+	}
+	if err := store.Save(AgentSession{AgentID: "agent-1", Name: "name-1", ChannelName: "channel-a"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	store.Close()
+
+	reopened, err := NewBoltSessionStore(path)
+	if err != nil {
+		t.Fatalf("NewBoltSessionStore() error = %v", err)
+	}
+	defer reopened.Close()
+
+	session, ok, err := reopened.Get("agent-1")
+	if err != nil || !ok || session.ChannelName != "channel-a" {
+		t.Errorf("Get() after reopen = %+v, %v, %v", session, ok, err)
+	}
+	session, ok, err = reopened.GetByName("name-1")
+	if err != nil || !ok || session.AgentID != "agent-1" {
+		t.Errorf("GetByName() after reopen = %+v, %v, %v", session, ok, err)
+	}
+}
@@ -0,0 +1,135 @@
+package convoai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Shutdown policies applied to the agents this instance started
+const (
+	// ShutdownPolicyLeave leaves agents running; the next startup reconciliation stops them
+	ShutdownPolicyLeave = "leave"
+	// ShutdownPolicyRemove removes every agent before the server exits
+	ShutdownPolicyRemove = "remove"
+	// ShutdownPolicyHandoff leaves agents running and marks them for adoption by the next instance
+	ShutdownPolicyHandoff = "handoff"
+)
+
+// defaultShutdownConcurrency bounds the parallel leave calls when none is configured
+const defaultShutdownConcurrency = 5
+
+// ShutdownTimeout returns how long ShutdownAgents may need for the agents this instance
+// started, allowing one leave call per agent in batches of the configured concurrency
+func (s *ConvoAIService) ShutdownTimeout() time.Duration {
+	if s.getConfig().ShutdownPolicy != ShutdownPolicyRemove {
+		return 0
+	}
+
+	sessions, err := s.sessions.List(SessionFilter{InstanceID: s.instanceID})
+	if err != nil || len(sessions) == 0 {
+		return 0
+	}
+
+	concurrency := s.shutdownConcurrency()
+	batches := (len(sessions) + concurrency - 1) / concurrency
+	return time.Duration(batches) * removeAgentTimeout
+}
+
+// ShutdownAgents applies the configured shutdown policy to every agent this instance started.
+// With the remove policy, leave calls run concurrently up to the configured limit; agents
+// still waiting for a slot when ctx is done are reported with the context error. The
+// history capture of HandleRemoveAgent is skipped: it would not fit in ShutdownTimeout, and
+// the transcripts it keeps in memory are lost when the server exits anyway.
+func (s *ConvoAIService) ShutdownAgents(ctx context.Context) []ShutdownResult {
+	sessions, err := s.sessions.List(SessionFilter{InstanceID: s.instanceID})
+	if err != nil {
+		log.Println("Warning: failed to list agent sessions for shutdown:", err)
+		return nil
+	}
+
+	results := make([]ShutdownResult, len(sessions))
+	switch s.getConfig().ShutdownPolicy {
+	case ShutdownPolicyRemove:
+		sem := make(chan struct{}, s.shutdownConcurrency())
+		var wg sync.WaitGroup
+		for i, session := range sessions {
+			results[i] = ShutdownResult{AgentID: session.AgentID, Action: ShutdownPolicyRemove}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i].Err = ctx.Err()
+				continue
+			}
+
+			wg.Add(1)
+			go func(i int, agentID string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				results[i].Err = s.leaveAgent(agentID)
+			}(i, session.AgentID)
+		}
+		wg.Wait()
+
+	case ShutdownPolicyHandoff:
+		for i, session := range sessions {
+			session.HandedOff = true
+			results[i] = ShutdownResult{
+				AgentID: session.AgentID,
+				Action:  ShutdownPolicyHandoff,
+				Err:     s.sessions.Save(session),
+			}
+		}
+
+	default:
+		for i, session := range sessions {
+			results[i] = ShutdownResult{AgentID: session.AgentID, Action: ShutdownPolicyLeave}
+		}
+	}
+
+	return results
+}
+
+// LogShutdownResults logs the outcome of ShutdownAgents, one line per agent
+func LogShutdownResults(results []ShutdownResult) {
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			log.Printf("- agent %s: %s failed: %v", result.AgentID, result.Action, result.Err)
+			continue
+		}
+		log.Printf("- agent %s: %s", result.AgentID, result.Action)
+	}
+	log.Printf("Agent shutdown completed: %d agent(s), %d failed", len(results), failed)
+}
+
+// shutdownConcurrency returns the configured number of parallel leave calls
+func (s *ConvoAIService) shutdownConcurrency() int {
+	if concurrency := s.getConfig().ShutdownConcurrency; concurrency > 0 {
+		return concurrency
+	}
+	return defaultShutdownConcurrency
+}
+
+// CloseStreams ends the open agent event streams and sockets. Register it with
+// http.Server.RegisterOnShutdown, so a graceful shutdown doesn't wait for their clients.
+func (s *ConvoAIService) CloseStreams() {
+	s.closeStreams.Do(func() { close(s.streamsDone) })
+}
+
+// Close releases the resources held by the service
+func (s *ConvoAIService) Close() error {
+	s.CloseStreams()
+	s.webhooks.close()
+	if err := s.closeMCPClients(); err != nil {
+		return err
+	}
+	if err := s.sessions.Close(); err != nil {
+		return fmt.Errorf("failed to close session store: %v", err)
+	}
+	return nil
+}
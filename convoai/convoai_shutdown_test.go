@@ -0,0 +1,112 @@
+package convoai
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestShutdownAgents(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		wantLeft  int
+		wantSaved bool
+	}{
+		{name: "Leave policy", policy: ShutdownPolicyLeave, wantLeft: 0},
+		{name: "Remove policy", policy: ShutdownPolicyRemove, wantLeft: 3},
+		{name: "Handoff policy", policy: ShutdownPolicyHandoff, wantLeft: 0, wantSaved: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agora := newFakeAgora(t)
+			service := newTestService(t, agora)
+			service.getConfig().ShutdownPolicy = tt.policy
+			service.getConfig().ShutdownConcurrency = 2
+
+			for _, agentID := range []string{"AGENT1", "AGENT2", "AGENT3"} {
+				agora.addAgent(agentID)
+				service.sessions.Save(AgentSession{AgentID: agentID, InstanceID: service.instanceID})
+			}
+			// Started by another instance, so never touched by this one
+			agora.addAgent("OTHER")
+			service.sessions.Save(AgentSession{AgentID: "OTHER", InstanceID: "other-instance"})
+
+			results := service.ShutdownAgents(context.Background())
+			if len(results) != 3 {
+				t.Fatalf("ShutdownAgents() returned %d results, want 3", len(results))
+			}
+			for _, result := range results {
+				if result.Err != nil || result.Action != tt.policy {
+					t.Errorf("unexpected result %+v", result)
+				}
+			}
+
+			left := agora.leftAgents()
+			sort.Strings(left)
+			if len(left) != tt.wantLeft {
+				t.Errorf("leave called for %v, want %d agents", left, tt.wantLeft)
+			}
+
+			session, ok, _ := service.sessions.Get("AGENT1")
+			if tt.policy == ShutdownPolicyRemove && ok {
+				t.Errorf("session still recorded after remove")
+			}
+			if tt.wantSaved && (!ok || !session.HandedOff) {
+				t.Errorf("session not marked as handed off: %+v", session)
+			}
+		})
+	}
+}
+
+func TestShutdownSkipsHistoryCapture(t *testing.T) {
+	agora := newFakeAgora(t)
+	agora.historyDelay = removeAgentTimeout
+	service := newTestService(t, agora)
+	service.getConfig().ShutdownPolicy = ShutdownPolicyRemove
+
+	agora.addAgent("AGENT1")
+	service.sessions.Save(AgentSession{AgentID: "AGENT1", InstanceID: service.instanceID})
+
+	// A slow history endpoint would use up the leave call's share of the shutdown timeout
+	ctx, cancel := context.WithTimeout(context.Background(), service.ShutdownTimeout())
+	defer cancel()
+	start := time.Now()
+	results := service.ShutdownAgents(ctx)
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("ShutdownAgents() = %+v", results)
+	}
+	if elapsed := time.Since(start); elapsed >= removeAgentTimeout/2 {
+		t.Errorf("ShutdownAgents() took %v, waiting for the history", elapsed)
+	}
+	if left := agora.leftAgents(); len(left) != 1 {
+		t.Errorf("leave called for %v, want AGENT1", left)
+	}
+}
+
+func TestHandedOffAgentsAreAdopted(t *testing.T) {
+	agora := newFakeAgora(t)
+	previous := newTestService(t, agora)
+	previous.getConfig().ShutdownPolicy = ShutdownPolicyHandoff
+
+	agora.addAgent("AGENT1")
+	previous.sessions.Save(AgentSession{AgentID: "AGENT1", InstanceID: previous.instanceID})
+	previous.ShutdownAgents(context.Background())
+
+	// The next instance shares the same store
+	next := NewConvoAIService(previous.getConfig(), previous.tokenService, previous.sessions)
+	result, err := next.ReconcileSessions()
+	if err != nil {
+		t.Fatalf("ReconcileSessions() error = %v", err)
+	}
+	if len(result.Adopted) != 1 || len(result.Removed) != 0 {
+		t.Errorf("ReconcileSessions() = %+v, want AGENT1 adopted", result)
+	}
+
+	session, _, _ := next.sessions.Get("AGENT1")
+	if session.InstanceID != next.instanceID || session.HandedOff {
+		t.Errorf("adopted session = %+v", session)
+	}
+}
@@ -0,0 +1,49 @@
+package convoai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultAgentTokenRefreshInterval is the number of seconds between checks for agent tokens to refresh
+const DefaultAgentTokenRefreshInterval = 300
+
+// RefreshAgentTokens pushes fresh RTC tokens to the agents this instance started until
+// ctx is done, so long calls don't drop when the token issued on invite expires
+func (s *ConvoAIService) RefreshAgentTokens(ctx context.Context) {
+	runPeriodically(ctx, func() int { return s.getConfig().AgentTokenRefreshInterval }, DefaultAgentTokenRefreshInterval, func(interval int) {
+		if err := s.refreshAgentTokens(interval); err != nil {
+			log.Println("Warning: failed to refresh agent tokens:", err)
+		}
+	})
+}
+
+// refreshAgentTokens refreshes the tokens of this instance's agents that expire within two
+// intervals, so a failed refresh is retried once before the token expires
+func (s *ConvoAIService) refreshAgentTokens(interval int) error {
+	sessions, err := s.sessions.List(SessionFilter{InstanceID: s.instanceID})
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %v", err)
+	}
+
+	deadline := time.Now().Unix() + 2*int64(interval)
+	var errs []error
+	for _, session := range sessions {
+		if session.ExpireTS == 0 || session.ExpireTS > deadline {
+			continue
+		}
+		if current, _ := s.agentStates.get(session.AgentID); isAgentEnded(current.State) {
+			continue
+		}
+
+		if _, err := s.HandleUpdateAgent(session.AgentID, UpdateAgentRequest{RefreshToken: true}); err != nil {
+			errs = append(errs, fmt.Errorf("failed to refresh the token of agent %s: %v", session.AgentID, err))
+			continue
+		}
+		log.Printf("Refreshed the token of agent %s", session.AgentID)
+	}
+	return errors.Join(errs...)
+}
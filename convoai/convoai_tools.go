@@ -0,0 +1,373 @@
+package convoai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tool limits
+const (
+	defaultToolTimeout = 10 * time.Second
+	maxToolResultSize  = 16 << 10
+	maxToolRounds      = 5
+)
+
+// toolNamePattern matches the function names LLM providers accept
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ToolHandler runs a tool call and returns the result passed back to the LLM
+type ToolHandler func(ctx context.Context, call ToolCall) (string, error)
+
+// Tool is a function the agent's LLM can call through the LLM proxy
+type Tool struct {
+	Name        string
+	Description string
+	// Parameters is the JSON schema of the arguments
+	Parameters json.RawMessage
+	Handler    ToolHandler
+}
+
+// ToolCall is a single call of a tool by the LLM
+type ToolCall struct {
+	ID          string
+	Name        string
+	Arguments   json.RawMessage
+	AgentName   string
+	ChannelName string
+}
+
+// validate checks the tool can be advertised to an LLM
+func (t Tool) validate() error {
+	if !toolNamePattern.MatchString(t.Name) {
+		return fmt.Errorf("invalid tool name %q", t.Name)
+	}
+	if t.Handler == nil {
+		return fmt.Errorf("tool %s has no handler", t.Name)
+	}
+	if len(t.Parameters) > 0 {
+		var schema map[string]interface{}
+		if err := json.Unmarshal(t.Parameters, &schema); err != nil {
+			return fmt.Errorf("tool %s parameters must be a JSON schema object: %v", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// definition returns the tool in the OpenAI tools format
+func (t Tool) definition() map[string]interface{} {
+	parameters := t.Parameters
+	if len(parameters) == 0 {
+		parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return map[string]interface{}{
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":        t.Name,
+			"description": t.Description,
+			"parameters":  parameters,
+		},
+	}
+}
+
+// ToolRegistry holds the tools registered in code
+type ToolRegistry struct {
+	mu     sync.RWMutex
+	byName map[string]Tool
+}
+
+// NewToolRegistry creates an empty tool registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{byName: make(map[string]Tool)}
+}
+
+// Register adds a tool, replacing any tool registered under the same name
+func (r *ToolRegistry) Register(tool Tool) error {
+	if err := tool.validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byName[tool.Name] = tool
+	return nil
+}
+
+// Unregister removes a tool
+func (r *ToolRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.byName, name)
+}
+
+// List returns the registered tools, sorted by name
+func (r *ToolRegistry) List() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]Tool, 0, len(r.byName))
+	for _, tool := range r.byName {
+		tools = append(tools, tool)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// RegisterTool makes a tool available to agents using the LLM proxy
+func (s *ConvoAIService) RegisterTool(tool Tool) error {
+	return s.tools.Register(tool)
+}
+
+// availableTools returns the tools registered in code followed by the configured
+// webhook tools, keyed by name and limited to those the profile allows
+func (s *ConvoAIService) availableTools(config *ConvoAIConfig, profile *AgentProfile) map[string]Tool {
+	tools := make(map[string]Tool)
+	for _, tool := range s.tools.List() {
+		if toolAllowed(profile.Tools, tool.Name) {
+			tools[tool.Name] = tool
+		}
+	}
+	for _, webhook := range config.WebhookTools {
+		if _, ok := tools[webhook.Name]; !ok && toolAllowed(profile.Tools, webhook.Name) {
+			tools[webhook.Name] = webhook.Tool()
+		}
+	}
+	return tools
+}
+
+// toolAllowed reports whether the allow-list includes the tool. A nil list allows every tool.
+func toolAllowed(allow []string, name string) bool {
+	if allow == nil {
+		return true
+	}
+	for _, pattern := range allow {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidToolPattern reports whether a profile allow-list entry is a tool name, or a
+// prefix of tool names ending in "*"
+func ValidToolPattern(pattern string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return prefix == "" || toolNamePattern.MatchString(prefix)
+	}
+	return toolNamePattern.MatchString(pattern)
+}
+
+// toolDefinitions returns the tools in the OpenAI tools format, sorted by name
+func toolDefinitions(tools map[string]Tool) []interface{} {
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	definitions := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		definitions = append(definitions, tools[name].definition())
+	}
+	return definitions
+}
+
+// runToolCall runs a tool call with a timeout. Failures are returned to the LLM as
+// the tool result, so it can tell the user rather than the turn failing.
+func runToolCall(ctx context.Context, tools map[string]Tool, call ToolCall) string {
+	tool, ok := tools[call.Name]
+	if !ok {
+		return fmt.Sprintf("error: unknown tool %s", call.Name)
+	}
+	if len(call.Arguments) == 0 {
+		call.Arguments = json.RawMessage("{}")
+	}
+	if !json.Valid(call.Arguments) {
+		return "error: tool arguments are not valid JSON"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultToolTimeout)
+	defer cancel()
+	result, err := tool.Handler(ctx, call)
+	if err != nil {
+		return fmt.Sprintf("error: %v", err)
+	}
+	return truncateToolResult(result)
+}
+
+// truncateToolResult cuts a result longer than maxToolResultSize at the last rune
+// boundary within the limit, so the LLM isn't sent invalid UTF-8
+func truncateToolResult(result string) string {
+	if len(result) <= maxToolResultSize {
+		return result
+	}
+	cut := maxToolResultSize
+	for cut > 0 && !utf8.RuneStart(result[cut]) {
+		cut--
+	}
+	return result[:cut]
+}
+
+// WebhookToolConfig declares a tool handled by an HTTP endpoint. The endpoint receives
+// a POST with the tool name, arguments, agent and channel, and its response body is
+// passed back to the LLM.
+type WebhookToolConfig struct {
+	Name           string            `json:"name" yaml:"name"`
+	Description    string            `json:"description" yaml:"description"`
+	Parameters     json.RawMessage   `json:"parameters,omitempty" yaml:"-"`
+	URL            string            `json:"url" yaml:"url"`
+	Headers        map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty" yaml:"timeout_seconds,omitempty"`
+}
+
+// webhookToolRequest is the body posted to a webhook tool
+type webhookToolRequest struct {
+	Name        string          `json:"name"`
+	Arguments   json.RawMessage `json:"arguments"`
+	AgentName   string          `json:"agent_name"`
+	ChannelName string          `json:"channel_name"`
+}
+
+// Validate checks the webhook tool is complete
+func (w WebhookToolConfig) Validate() error {
+	if w.URL == "" || !(strings.HasPrefix(w.URL, "http://") || strings.HasPrefix(w.URL, "https://")) {
+		return fmt.Errorf("tool %s url must be an http(s) URL", w.Name)
+	}
+	if w.TimeoutSeconds < 0 {
+		return fmt.Errorf("tool %s timeout_seconds must not be negative", w.Name)
+	}
+	return w.Tool().validate()
+}
+
+// Tool returns the webhook as a Tool
+func (w WebhookToolConfig) Tool() Tool {
+	return Tool{
+		Name:        w.Name,
+		Description: w.Description,
+		Parameters:  w.Parameters,
+		Handler:     w.call,
+	}
+}
+
+// call posts the tool call to the webhook
+func (w WebhookToolConfig) call(ctx context.Context, call ToolCall) (string, error) {
+	if w.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(w.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	payload, err := json.Marshal(webhookToolRequest{
+		Name:        call.Name,
+		Arguments:   call.Arguments,
+		AgentName:   call.AgentName,
+		ChannelName: call.ChannelName,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range w.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("tool webhook failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Read a byte past the limit, so the cut can tell whether it splits a rune
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxToolResultSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read tool webhook response: %v", err)
+	}
+	result := truncateToolResult(string(body))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("tool webhook returned status %d: %s", resp.StatusCode, result)
+	}
+	return result, nil
+}
+
+// ToolsFile is the format of the tools file, declaring webhook tools and MCP servers
+type ToolsFile struct {
+	Tools      []WebhookToolConfig `json:"tools"`
+	MCPServers []MCPServerConfig   `json:"mcp_servers"`
+}
+
+// toolsFileYAML decodes the tools file from YAML, where the tool schemas are YAML objects
+type toolsFileYAML struct {
+	Tools []struct {
+		WebhookToolConfig `yaml:",inline"`
+		Schema            map[string]interface{} `yaml:"parameters"`
+	} `yaml:"tools"`
+	MCPServers []MCPServerConfig `yaml:"mcp_servers"`
+}
+
+// LoadToolsFile reads the webhook tools and MCP servers declared in a .yaml, .yml or .json file
+func LoadToolsFile(path string) (*ToolsFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tools file %s: %v", path, err)
+	}
+
+	var file ToolsFile
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(data, &file)
+	} else {
+		var decoded toolsFileYAML
+		if err = yaml.Unmarshal(data, &decoded); err == nil {
+			file.MCPServers = decoded.MCPServers
+			for _, entry := range decoded.Tools {
+				tool := entry.WebhookToolConfig
+				if entry.Schema != nil {
+					if tool.Parameters, err = json.Marshal(entry.Schema); err != nil {
+						return nil, fmt.Errorf("tool %s parameters: %v", tool.Name, err)
+					}
+				}
+				file.Tools = append(file.Tools, tool)
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tools file %s: %v", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, tool := range file.Tools {
+		if seen[tool.Name] {
+			return nil, errors.New("tool " + tool.Name + " is declared more than once")
+		}
+		seen[tool.Name] = true
+	}
+	seen = make(map[string]bool)
+	for _, server := range file.MCPServers {
+		if seen[server.Name] {
+			return nil, errors.New("MCP server " + server.Name + " is declared more than once")
+		}
+		seen[server.Name] = true
+	}
+	return &file, nil
+}
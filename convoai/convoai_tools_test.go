@@ -0,0 +1,369 @@
+package convoai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+// newToolCallingLLM starts a fake provider that calls the named tool until it receives a
+// tool result, then answers. With always set it never stops calling the tool.
+func newToolCallingLLM(t *testing.T, tool, arguments string, always bool) *fakeLLM {
+	t.Helper()
+	f := &fakeLLM{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		f.mu.Lock()
+		f.requests = append(f.requests, body)
+		f.mu.Unlock()
+
+		messages, _ := body["messages"].([]interface{})
+		last, _ := messages[len(messages)-1].(map[string]interface{})
+		callTool := always || last["role"] != "tool"
+		if choice, _ := body["tool_choice"].(string); choice == "none" {
+			callTool = false
+		}
+		encodedArgs, _ := json.Marshal(arguments)
+
+		if stream, _ := body["stream"].(bool); stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			if callTool {
+				// Arguments arrive split over two chunks, as real providers send them
+				half := len(arguments) / 2
+				first, _ := json.Marshal(arguments[:half])
+				second, _ := json.Marshal(arguments[half:])
+				fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":%q,\"arguments\":%s}}]}}]}\n\n", tool, first)
+				fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":%s}}]}}]}\n\n", second)
+				fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"tool_calls\"}]}\n\n")
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				return
+			}
+			fmt.Fprint(w, fakeLLMStream)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if callTool {
+			fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":%q,"arguments":%s}}]},"finish_reason":"tool_calls"}]}`, tool, encodedArgs)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Your order has shipped."}}]}`)
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func TestLLMProxyToolCalls(t *testing.T) {
+	tests := []struct {
+		name     string
+		stream   bool
+		wantBody string
+	}{
+		{
+			name:     "Streamed",
+			stream:   true,
+			wantBody: fakeLLMStream,
+		},
+		{
+			name:     "Not streamed",
+			wantBody: `{"choices":[{"message":{"role":"assistant","content":"Your order has shipped."}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agora := newFakeAgora(t)
+			llm := newToolCallingLLM(t, "get_order_status", `{"order_id":"1234"}`, false)
+			service := newTestService(t, agora, withLLMProxy(llm, 0))
+			router := newTestRouter(service)
+
+			var mu sync.Mutex
+			var calls []ToolCall
+			err := service.RegisterTool(Tool{
+				Name:        "get_order_status",
+				Description: "Look up the status of an order",
+				Parameters:  json.RawMessage(`{"type":"object","properties":{"order_id":{"type":"string"}},"required":["order_id"]}`),
+				Handler: func(ctx context.Context, call ToolCall) (string, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					calls = append(calls, call)
+					return `{"status":"shipped"}`, nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("RegisterTool failed: %v", err)
+			}
+
+			agentLLM := inviteProxiedAgent(t, router, agora)
+			body := fmt.Sprintf(`{"model": "gpt-4o-mini", "stream": %t, "messages": [
+				{"role": "user", "content": "Where is order 1234?"}]}`, tt.stream)
+			rr := doProxy(router, agentLLM.APIKey, body)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("proxy returned status %d: %s", rr.Code, rr.Body.String())
+			}
+			// The agent only sees the final answer
+			if rr.Body.String() != tt.wantBody {
+				t.Errorf("body =\n%s\nwant\n%s", rr.Body.String(), tt.wantBody)
+			}
+
+			if len(calls) != 1 || string(calls[0].Arguments) != `{"order_id":"1234"}` || calls[0].ChannelName != "test-channel" {
+				t.Fatalf("tool calls = %+v", calls)
+			}
+			if len(llm.requests) != 2 {
+				t.Fatalf("upstream received %d requests, want 2", len(llm.requests))
+			}
+
+			tools, _ := llm.requests[0]["tools"].([]interface{})
+			if len(tools) != 1 {
+				t.Fatalf("upstream tools = %v", llm.requests[0]["tools"])
+			}
+			function := tools[0].(map[string]interface{})["function"].(map[string]interface{})
+			if function["name"] != "get_order_status" || function["parameters"] == nil {
+				t.Errorf("advertised tool = %v", function)
+			}
+
+			// The second request carries the tool call and its result
+			messages := llm.requests[1]["messages"].([]interface{})
+			assistant := messages[len(messages)-2].(map[string]interface{})
+			result := messages[len(messages)-1].(map[string]interface{})
+			if assistant["role"] != "assistant" || assistant["tool_calls"] == nil {
+				t.Errorf("assistant message = %v", assistant)
+			}
+			if result["role"] != "tool" || result["tool_call_id"] != "call_1" || result["content"] != `{"status":"shipped"}` {
+				t.Errorf("tool result message = %v", result)
+			}
+		})
+	}
+}
+
+func TestLLMProxyToolRoundLimit(t *testing.T) {
+	agora := newFakeAgora(t)
+	llm := newToolCallingLLM(t, "get_order_status", `{}`, true)
+	service := newTestService(t, agora, withLLMProxy(llm, 0))
+	router := newTestRouter(service)
+	service.RegisterTool(Tool{
+		Name:    "get_order_status",
+		Handler: func(ctx context.Context, call ToolCall) (string, error) { return "unknown", nil },
+	})
+
+	agentLLM := inviteProxiedAgent(t, router, agora)
+	rr := doProxy(router, agentLLM.APIKey, `{"messages": [{"role": "user", "content": "Where is my order?"}]}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("proxy returned status %d: %s", rr.Code, rr.Body.String())
+	}
+	if len(llm.requests) != maxToolRounds+1 {
+		t.Fatalf("upstream received %d requests, want %d", len(llm.requests), maxToolRounds+1)
+	}
+	if choice := llm.requests[maxToolRounds]["tool_choice"]; choice != "none" {
+		t.Errorf("last request tool_choice = %v, want none", choice)
+	}
+}
+
+func TestLLMProxyWithoutTools(t *testing.T) {
+	agora := newFakeAgora(t)
+	llm := newFakeLLM(t)
+	router := newTestRouter(newTestService(t, agora, withLLMProxy(llm, 0)))
+	agentLLM := inviteProxiedAgent(t, router, agora)
+
+	rr := doProxy(router, agentLLM.APIKey, `{"messages": [{"role": "user", "content": "Hello"}]}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("proxy returned status %d", rr.Code)
+	}
+	if _, ok := llm.requests[0]["tools"]; ok {
+		t.Errorf("upstream request has tools without any registered: %v", llm.requests[0]["tools"])
+	}
+}
+
+func TestRunToolCall(t *testing.T) {
+	tools := map[string]Tool{
+		"echo": {Name: "echo", Handler: func(ctx context.Context, call ToolCall) (string, error) {
+			return string(call.Arguments), nil
+		}},
+		"broken": {Name: "broken", Handler: func(ctx context.Context, call ToolCall) (string, error) {
+			return "", errors.New("backend down")
+		}},
+	}
+
+	tests := []struct {
+		name string
+		call ToolCall
+		want string
+	}{
+		{name: "Arguments", call: ToolCall{Name: "echo", Arguments: json.RawMessage(`{"a":1}`)}, want: `{"a":1}`},
+		{name: "No arguments", call: ToolCall{Name: "echo"}, want: `{}`},
+		{name: "Invalid arguments", call: ToolCall{Name: "echo", Arguments: json.RawMessage(`{"a":`)}, want: "error: tool arguments are not valid JSON"},
+		{name: "Handler error", call: ToolCall{Name: "broken"}, want: "error: backend down"},
+		{name: "Unknown tool", call: ToolCall{Name: "missing"}, want: "error: unknown tool missing"},
+		{
+			// The two-byte é straddles the size limit, so it is cut entirely
+			name: "Result cut at a rune boundary",
+			call: ToolCall{Name: "echo", Arguments: json.RawMessage(`"` + strings.Repeat("a", maxToolResultSize-2) + `é"`)},
+			want: `"` + strings.Repeat("a", maxToolResultSize-2),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runToolCall(context.Background(), tools, tt.call); got != tt.want {
+				t.Errorf("runToolCall = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolRegistryRegister(t *testing.T) {
+	handler := func(ctx context.Context, call ToolCall) (string, error) { return "", nil }
+	tests := []struct {
+		name    string
+		tool    Tool
+		wantErr bool
+	}{
+		{name: "Valid", tool: Tool{Name: "book_slot", Parameters: json.RawMessage(`{"type":"object"}`), Handler: handler}},
+		{name: "Invalid name", tool: Tool{Name: "book slot", Handler: handler}, wantErr: true},
+		{name: "No handler", tool: Tool{Name: "book_slot"}, wantErr: true},
+		{name: "Schema not an object", tool: Tool{Name: "book_slot", Parameters: json.RawMessage(`[]`), Handler: handler}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewToolRegistry().Register(tt.tool)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Register error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookTool(t *testing.T) {
+	var got webhookToolRequest
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		if got.Name == "fail" {
+			http.Error(w, "no slots", http.StatusConflict)
+			return
+		}
+		if got.Name == "long" {
+			io.WriteString(w, strings.Repeat("a", maxToolResultSize-1)+"é")
+			return
+		}
+		io.WriteString(w, `{"booked":"10:00"}`)
+	}))
+	t.Cleanup(server.Close)
+
+	webhook := WebhookToolConfig{
+		Name:    "book_slot",
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer tool-secret"},
+	}
+	if err := webhook.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	call := ToolCall{Name: "book_slot", Arguments: json.RawMessage(`{"time":"10:00"}`), AgentName: "agent-1", ChannelName: "test-channel"}
+	result, err := webhook.Tool().Handler(context.Background(), call)
+	if err != nil || result != `{"booked":"10:00"}` {
+		t.Fatalf("webhook result = %q, %v", result, err)
+	}
+	if auth != "Bearer tool-secret" || string(got.Arguments) != `{"time":"10:00"}` || got.ChannelName != "test-channel" || got.AgentName != "agent-1" {
+		t.Errorf("webhook received %+v with Authorization %q", got, auth)
+	}
+
+	call.Name = "fail"
+	if _, err := webhook.Tool().Handler(context.Background(), call); err == nil || !strings.Contains(err.Error(), "409") {
+		t.Errorf("webhook error = %v, want the 409 status", err)
+	}
+
+	// A response over the size limit is cut before the rune straddling it
+	call.Name = "long"
+	if result, err := webhook.Tool().Handler(context.Background(), call); err != nil || result != strings.Repeat("a", maxToolResultSize-1) {
+		t.Errorf("long webhook result has %d bytes, valid UTF-8 %t, %v", len(result), utf8.ValidString(result), err)
+	}
+}
+
+func TestLoadToolsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tools.yaml")
+	content := `tools:
+  - name: book_slot
+    description: Book an appointment slot
+    url: https://tools.example.com/book
+    timeout_seconds: 5
+    parameters:
+      type: object
+      properties:
+        time:
+          type: string
+mcp_servers:
+  - name: crm
+    url: https://crm.example.com/mcp
+  - name: files
+    command: mcp-files
+    args: [--root, /srv/docs]
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := LoadToolsFile(path)
+	if err != nil {
+		t.Fatalf("LoadToolsFile failed: %v", err)
+	}
+	tools := file.Tools
+	if len(tools) != 1 || tools[0].Name != "book_slot" || tools[0].TimeoutSeconds != 5 {
+		t.Fatalf("tools = %+v", tools)
+	}
+	want := `{"properties":{"time":{"type":"string"}},"type":"object"}`
+	if string(tools[0].Parameters) != want {
+		t.Errorf("parameters = %s, want %s", tools[0].Parameters, want)
+	}
+	if err := tools[0].Validate(); err != nil {
+		t.Errorf("Validate failed: %v", err)
+	}
+
+	servers := file.MCPServers
+	if len(servers) != 2 || servers[0].URL != "https://crm.example.com/mcp" || strings.Join(servers[1].Args, " ") != "--root /srv/docs" {
+		t.Fatalf("MCP servers = %+v", servers)
+	}
+	for _, server := range servers {
+		if err := server.Validate(); err != nil {
+			t.Errorf("Validate %s failed: %v", server.Name, err)
+		}
+	}
+}
+
+func TestToolAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		tool  string
+		want  bool
+	}{
+		{name: "No allow-list", allow: nil, tool: "crm_lookup", want: true},
+		{name: "Empty allow-list", allow: []string{}, tool: "crm_lookup", want: false},
+		{name: "Exact name", allow: []string{"crm_lookup"}, tool: "crm_lookup", want: true},
+		{name: "Other name", allow: []string{"crm_lookup"}, tool: "crm_delete", want: false},
+		{name: "Prefix", allow: []string{"book_slot", "crm_*"}, tool: "crm_delete", want: true},
+		{name: "Prefix of another server", allow: []string{"crm_*"}, tool: "files_read", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toolAllowed(tt.allow, tt.tool); got != tt.want {
+				t.Errorf("toolAllowed(%v, %q) = %v, want %v", tt.allow, tt.tool, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package convoai
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Transcript roles
+const (
+	TranscriptRoleUser      = "user"
+	TranscriptRoleAssistant = "assistant"
+)
+
+// Transcript sources
+const (
+	TranscriptSourceLLMProxy = "llm_proxy"
+	TranscriptSourceAgora    = "agora"
+)
+
+// Transcript export formats
+const (
+	TranscriptFormatJSON   = "json"
+	TranscriptFormatNDJSON = "ndjson"
+	TranscriptFormatText   = "text"
+)
+
+// Default transcript retention limits
+const (
+	defaultTranscriptMaxEntries = 500
+	defaultTranscriptMaxAgents  = 1000
+	defaultTranscriptRetention  = 24 * time.Hour
+)
+
+// TranscriptEntry is one utterance of a conversation
+type TranscriptEntry struct {
+	Role      string `json:"role"`
+	Text      string `json:"text"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Source    string `json:"source"`
+}
+
+// AgentHistoryRequest represents the query parameters for an agent's history
+type AgentHistoryRequest struct {
+	Format string `form:"format"`
+}
+
+// AgentHistoryResponse is the transcript of an agent's conversation
+type AgentHistoryResponse struct {
+	AgentID string            `json:"agent_id"`
+	Entries []TranscriptEntry `json:"entries"`
+	Total   int               `json:"total"`
+}
+
+// transcriptLimits bound the transcripts kept in memory
+type transcriptLimits struct {
+	maxEntries int
+	maxAgents  int
+	retention  time.Duration
+}
+
+// transcriptLimits returns the retention limits of the configuration, with defaults for unset limits
+func (config *ConvoAIConfig) transcriptLimits() transcriptLimits {
+	limits := transcriptLimits{
+		maxEntries: config.TranscriptMaxEntries,
+		maxAgents:  config.TranscriptMaxAgents,
+		retention:  time.Duration(config.TranscriptRetentionHours) * time.Hour,
+	}
+	if limits.maxEntries <= 0 {
+		limits.maxEntries = defaultTranscriptMaxEntries
+	}
+	if limits.maxAgents <= 0 {
+		limits.maxAgents = defaultTranscriptMaxAgents
+	}
+	if limits.retention <= 0 {
+		limits.retention = defaultTranscriptRetention
+	}
+	return limits
+}
+
+// TranscriptStore keeps the transcripts of recent agents in memory, within retention limits
+type TranscriptStore struct {
+	mu      sync.Mutex
+	byAgent map[string]*transcript
+}
+
+type transcript struct {
+	entries []TranscriptEntry
+	updated time.Time
+}
+
+// NewTranscriptStore creates an empty transcript store
+func NewTranscriptStore() *TranscriptStore {
+	return &TranscriptStore{byAgent: make(map[string]*transcript)}
+}
+
+// append adds entries to the agent's transcript, dropping the oldest entries over the
+// limit, then expires transcripts outside the retention limits
+func (t *TranscriptStore) append(agentID string, limits transcriptLimits, now time.Time, entries ...TranscriptEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	current, ok := t.byAgent[agentID]
+	if !ok {
+		current = &transcript{}
+		t.byAgent[agentID] = current
+	}
+	current.entries = append(current.entries, entries...)
+	if over := len(current.entries) - limits.maxEntries; over > 0 {
+		current.entries = append([]TranscriptEntry(nil), current.entries[over:]...)
+	}
+	current.updated = now
+
+	t.prune(limits, now)
+}
+
+// prune drops transcripts not updated within the retention period, then the least
+// recently updated transcripts over the agent limit
+func (t *TranscriptStore) prune(limits transcriptLimits, now time.Time) {
+	for agentID, current := range t.byAgent {
+		if now.Sub(current.updated) > limits.retention {
+			delete(t.byAgent, agentID)
+		}
+	}
+	if len(t.byAgent) <= limits.maxAgents {
+		return
+	}
+
+	agentIDs := make([]string, 0, len(t.byAgent))
+	for agentID := range t.byAgent {
+		agentIDs = append(agentIDs, agentID)
+	}
+	sort.Slice(agentIDs, func(i, j int) bool {
+		return t.byAgent[agentIDs[i]].updated.Before(t.byAgent[agentIDs[j]].updated)
+	})
+	for _, agentID := range agentIDs[:len(agentIDs)-limits.maxAgents] {
+		delete(t.byAgent, agentID)
+	}
+}
+
+// get returns a copy of the agent's transcript, and false if none is kept
+func (t *TranscriptStore) get(agentID string, limits transcriptLimits, now time.Time) ([]TranscriptEntry, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(limits, now)
+	current, ok := t.byAgent[agentID]
+	if !ok {
+		return nil, false
+	}
+	return append([]TranscriptEntry(nil), current.entries...), true
+}
+
+// recordTranscript adds the user and assistant text of a relayed turn to the transcript
+// of the agent the proxy found by the name and channel its key was issued for
+func (s *ConvoAIService) recordTranscript(turn LLMTurn, now time.Time) {
+	if turn.StatusCode != 200 || (turn.UserText == "" && turn.AssistantText == "") {
+		return
+	}
+
+	if turn.AgentID == "" {
+		log.Printf("Warning: no session for agent %s in channel %s, turn not added to the transcript", turn.AgentName, turn.ChannelName)
+		return
+	}
+
+	var entries []TranscriptEntry
+	if turn.UserText != "" {
+		entries = append(entries, TranscriptEntry{Role: TranscriptRoleUser, Text: turn.UserText, Timestamp: now.Add(-turn.Duration).UnixMilli(), Source: TranscriptSourceLLMProxy})
+	}
+	if turn.AssistantText != "" {
+		entries = append(entries, TranscriptEntry{Role: TranscriptRoleAssistant, Text: turn.AssistantText, Timestamp: now.UnixMilli(), Source: TranscriptSourceLLMProxy})
+	}
+	s.transcripts.append(turn.AgentID, s.getConfig().transcriptLimits(), now, entries...)
+	for _, entry := range entries {
+		s.publishAgentEvent(AgentEventTranscript, turn.AgentID, turn.ChannelName, "", entry)
+	}
+}
+
+// formatTranscriptNDJSON returns the entries as newline-delimited JSON
+func formatTranscriptNDJSON(entries []TranscriptEntry) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		encoder.Encode(entry)
+	}
+	return buf.Bytes()
+}
+
+// formatTranscriptText returns the entries as lines of "[time] role: text"
+func formatTranscriptText(entries []TranscriptEntry) []byte {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		if entry.Timestamp != 0 {
+			buf.WriteString("[" + time.UnixMilli(entry.Timestamp).UTC().Format(time.RFC3339) + "] ")
+		}
+		buf.WriteString(entry.Role + ": " + entry.Text + "\n")
+	}
+	return buf.Bytes()
+}
+
+// AgoraHistoryResponse is the conversation history Agora keeps for a running agent
+type AgoraHistoryResponse struct {
+	AgentID  string `json:"agent_id"`
+	StartTS  int64  `json:"start_ts"`
+	Status   string `json:"status"`
+	Contents []struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	} `json:"contents"`
+}
+
+// fetchAgoraHistory returns the conversation history of a running agent from Agora
+func (s *ConvoAIService) fetchAgoraHistory(agentID string) ([]TranscriptEntry, error) {
+	var history AgoraHistoryResponse
+	if err := s.agoraRequest("GET", "/agents/"+url.PathEscape(agentID)+"/history", nil, &history); err != nil {
+		return nil, err
+	}
+
+	entries := make([]TranscriptEntry, 0, len(history.Contents))
+	for _, content := range history.Contents {
+		entries = append(entries, TranscriptEntry{Role: content.Role, Text: content.Content, Source: TranscriptSourceAgora})
+	}
+	return entries, nil
+}
+
+// captureAgoraHistory keeps the Agora history of an agent about to leave, unless the
+// LLM proxy already recorded its transcript. Agora discards the history once the agent leaves.
+func (s *ConvoAIService) captureAgoraHistory(agentID string) {
+	if _, ok := s.transcripts.get(agentID, s.getConfig().transcriptLimits(), time.Now()); ok {
+		return
+	}
+	entries, err := s.fetchAgoraHistory(agentID)
+	if err != nil {
+		log.Printf("Warning: failed to capture history of agent %s: %v", agentID, err)
+		return
+	}
+	s.keepAgoraHistory(agentID, entries)
+}
+
+// keepAgoraHistory stores history read from Agora as the agent's transcript, unless a
+// transcript is already kept
+func (s *ConvoAIService) keepAgoraHistory(agentID string, entries []TranscriptEntry) {
+	limits := s.getConfig().transcriptLimits()
+	if _, ok := s.transcripts.get(agentID, limits, time.Now()); ok || len(entries) == 0 {
+		return
+	}
+	s.transcripts.append(agentID, limits, time.Now(), entries...)
+}
+
+// HandleGetAgentHistory returns the transcript of an agent. Transcripts recorded by
+// the LLM proxy or captured when the agent left are returned first; otherwise the
+// history is read from Agora, which only keeps it while the agent runs.
+func (s *ConvoAIService) HandleGetAgentHistory(agentID string) (*AgentHistoryResponse, error) {
+	entries, ok := s.transcripts.get(agentID, s.getConfig().transcriptLimits(), time.Now())
+	if !ok {
+		var err error
+		if entries, err = s.fetchAgoraHistory(agentID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &AgentHistoryResponse{
+		AgentID: agentID,
+		Entries: entries,
+		Total:   len(entries),
+	}, nil
+}
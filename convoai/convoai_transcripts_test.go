@@ -0,0 +1,160 @@
+package convoai
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentHistoryFromLLMProxy(t *testing.T) {
+	agora := newFakeAgora(t)
+	llm := newFakeLLM(t)
+	router := newTestRouter(newTestService(t, agora, withLLMProxy(llm, 0)))
+	agentLLM := inviteProxiedAgent(t, router, agora)
+
+	body := `{"messages": [{"role": "user", "content": "Where is my order?"}]}`
+	if rr := doProxy(router, agentLLM.APIKey, body); rr.Code != http.StatusOK {
+		t.Fatalf("proxy returned status %d", rr.Code)
+	}
+
+	var history AgentHistoryResponse
+	if status := doJSON(t, router, "GET", "/agent/AGENT0001/history", "", &history); status != http.StatusOK {
+		t.Fatalf("history returned status %d", status)
+	}
+	if history.AgentID != "AGENT0001" || history.Total != 2 {
+		t.Fatalf("history = %+v", history)
+	}
+	user, assistant := history.Entries[0], history.Entries[1]
+	if user.Role != TranscriptRoleUser || user.Text != "Where is my order?" || user.Source != TranscriptSourceLLMProxy || user.Timestamp == 0 {
+		t.Errorf("user entry = %+v", user)
+	}
+	if assistant.Role != TranscriptRoleAssistant || assistant.Text != "Your order has shipped." {
+		t.Errorf("assistant entry = %+v", assistant)
+	}
+}
+
+func TestAgentHistoryFromAgora(t *testing.T) {
+	agora := newFakeAgora(t)
+	router := newTestRouter(newTestService(t, agora))
+	if status := doJSON(t, router, "POST", "/agent/invite", `{"requester_id": "user-1", "channel_name": "test-channel"}`, nil); status != http.StatusOK {
+		t.Fatalf("invite returned status %d", status)
+	}
+
+	// While the agent runs, the history is read from Agora
+	var history AgentHistoryResponse
+	if status := doJSON(t, router, "GET", "/agent/AGENT0001/history", "", &history); status != http.StatusOK || history.Total != 2 {
+		t.Fatalf("history returned status %d: %+v", status, history)
+	}
+	if history.Entries[0].Source != TranscriptSourceAgora || history.Entries[1].Text != "It shipped today." {
+		t.Errorf("entries = %+v", history.Entries)
+	}
+
+	// It is captured before the agent leaves, as Agora discards it
+	if status := doJSON(t, router, "POST", "/agent/remove", `{"agent_id": "AGENT0001"}`, nil); status != http.StatusOK {
+		t.Fatalf("remove returned status %d", status)
+	}
+	history = AgentHistoryResponse{}
+	if status := doJSON(t, router, "GET", "/agent/AGENT0001/history", "", &history); status != http.StatusOK || history.Total != 2 {
+		t.Errorf("history after remove returned status %d: %+v", status, history)
+	}
+
+	if status := doJSON(t, router, "GET", "/agent/UNKNOWN/history", "", nil); status != http.StatusNotFound {
+		t.Errorf("history of an unknown agent returned status %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestAgentHistoryFormats(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	router := newTestRouter(service)
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	service.transcripts.append("AGENT0001", service.getConfig().transcriptLimits(), time.Now(),
+		TranscriptEntry{Role: TranscriptRoleUser, Text: "Hi", Timestamp: now.UnixMilli(), Source: TranscriptSourceLLMProxy},
+		TranscriptEntry{Role: TranscriptRoleAssistant, Text: "Hello!", Timestamp: now.UnixMilli(), Source: TranscriptSourceLLMProxy},
+	)
+
+	tests := []struct {
+		name            string
+		format          string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "NDJSON",
+			format:          "ndjson",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-ndjson",
+			wantBody: `{"role":"user","text":"Hi","timestamp":1767366245000,"source":"llm_proxy"}` + "\n" +
+				`{"role":"assistant","text":"Hello!","timestamp":1767366245000,"source":"llm_proxy"}` + "\n",
+		},
+		{
+			name:            "Text",
+			format:          "text",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "[2026-01-02T15:04:05Z] user: Hi\n[2026-01-02T15:04:05Z] assistant: Hello!\n",
+		},
+		{
+			name:       "Unsupported",
+			format:     "csv",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", "/agent/AGENT0001/history?format="+tt.format, nil))
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rr.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if !strings.HasPrefix(rr.Header().Get("Content-Disposition"), "attachment") {
+				t.Errorf("Content-Disposition = %q", rr.Header().Get("Content-Disposition"))
+			}
+			if rr.Body.String() != tt.wantBody {
+				t.Errorf("body =\n%s\nwant\n%s", rr.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestTranscriptStoreRetention(t *testing.T) {
+	limits := transcriptLimits{maxEntries: 2, maxAgents: 2, retention: time.Hour}
+	start := time.Now()
+	entry := func(text string) TranscriptEntry { return TranscriptEntry{Role: TranscriptRoleUser, Text: text} }
+
+	store := NewTranscriptStore()
+	store.append("a", limits, start, entry("1"), entry("2"), entry("3"))
+	if entries, _ := store.get("a", limits, start); len(entries) != 2 || entries[0].Text != "2" {
+		t.Errorf("entries over the limit were not dropped: %+v", entries)
+	}
+
+	// The least recently updated transcript is evicted over the agent limit
+	store.append("b", limits, start.Add(time.Minute), entry("1"))
+	store.append("a", limits, start.Add(2*time.Minute), entry("4"))
+	store.append("c", limits, start.Add(3*time.Minute), entry("1"))
+	if _, ok := store.get("b", limits, start.Add(3*time.Minute)); ok {
+		t.Errorf("least recently updated transcript was kept")
+	}
+	if _, ok := store.get("a", limits, start.Add(3*time.Minute)); !ok {
+		t.Errorf("recently updated transcript was evicted")
+	}
+
+	// Transcripts expire after the retention period
+	if _, ok := store.get("a", limits, start.Add(2*time.Minute+time.Hour+time.Second)); ok {
+		t.Errorf("expired transcript was kept")
+	}
+	if _, ok := store.get("c", limits, start.Add(2*time.Minute+time.Hour+time.Second)); !ok {
+		t.Errorf("transcript within the retention period expired")
+	}
+}
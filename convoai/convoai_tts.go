@@ -0,0 +1,122 @@
+package convoai
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// TTSProvider describes a text-to-speech vendor supported by the Agora engine.
+// Vendors register themselves with RegisterTTSProvider, so adding one doesn't
+// require changes to the config loading, validation or invite code.
+type TTSProvider interface {
+	// Vendor returns the name used in TTS_VENDOR and in the Agora request
+	Vendor() TTSVendor
+	// LoadEnv reads the vendor configuration from the environment, returning nil
+	// when the vendor isn't configured
+	LoadEnv(getenv func(string) string) TTSVendorConfig
+	// NewConfig returns an empty configuration to decode profile params into
+	NewConfig() TTSVendorConfig
+}
+
+// TTSVendorConfig is the configuration of a single TTS vendor
+type TTSVendorConfig interface {
+	// Validate reports missing or invalid settings
+	Validate() error
+	// Params builds the vendor params sent to the Agora engine. It validates the
+	// configuration first, so validation and request building share one path.
+	// The params are a typed struct with JSON tags, e.g. MicrosoftTTSParams.
+	Params() (interface{}, error)
+}
+
+// ttsProviders holds the registered TTS vendors
+var ttsProviders = struct {
+	sync.RWMutex
+	byVendor map[TTSVendor]TTSProvider
+}{byVendor: make(map[TTSVendor]TTSProvider)}
+
+// RegisterTTSProvider makes a TTS vendor available, replacing any provider
+// previously registered for the same vendor
+func RegisterTTSProvider(provider TTSProvider) {
+	ttsProviders.Lock()
+	defer ttsProviders.Unlock()
+	ttsProviders.byVendor[provider.Vendor()] = provider
+}
+
+// GetTTSProvider returns the provider registered for the vendor
+func GetTTSProvider(vendor TTSVendor) (TTSProvider, bool) {
+	ttsProviders.RLock()
+	defer ttsProviders.RUnlock()
+	provider, ok := ttsProviders.byVendor[vendor]
+	return provider, ok
+}
+
+// TTSProviders returns the registered providers, sorted by vendor name
+func TTSProviders() []TTSProvider {
+	ttsProviders.RLock()
+	defer ttsProviders.RUnlock()
+
+	providers := make([]TTSProvider, 0, len(ttsProviders.byVendor))
+	for _, provider := range ttsProviders.byVendor {
+		providers = append(providers, provider)
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Vendor() < providers[j].Vendor() })
+	return providers
+}
+
+// LoadTTSConfigs reads the configuration of every registered vendor from the environment
+func LoadTTSConfigs(getenv func(string) string) map[TTSVendor]TTSVendorConfig {
+	configs := make(map[TTSVendor]TTSVendorConfig)
+	for _, provider := range TTSProviders() {
+		if config := provider.LoadEnv(getenv); config != nil {
+			configs[provider.Vendor()] = config
+		}
+	}
+	return configs
+}
+
+// ResolveTTSConfig returns the TTS configuration for a profile. The profile may select a
+// different vendor than TTS_VENDOR, and its params are laid over that vendor's configuration.
+func ResolveTTSConfig(config *ConvoAIConfig, profile *AgentProfile) (*TTSConfig, error) {
+	vendor, vendorConfig, err := resolveTTSVendorConfig(config, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	ttsParams, err := vendorConfig.Params()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TTSConfig{
+		Vendor: vendor,
+		Params: ttsParams,
+	}, nil
+}
+
+// resolveTTSVendorConfig selects the vendor for a profile and lays the profile params over its configuration
+func resolveTTSVendorConfig(config *ConvoAIConfig, profile *AgentProfile) (TTSVendor, TTSVendorConfig, error) {
+	vendor := TTSVendor(config.TTSVendor)
+	var params map[string]interface{}
+	if profile != nil && profile.TTS != nil {
+		if profile.TTS.Vendor != "" {
+			vendor = TTSVendor(profile.TTS.Vendor)
+		}
+		params = profile.TTS.Params
+	}
+
+	provider, ok := GetTTSProvider(vendor)
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported TTS vendor: %s", vendor)
+	}
+	base := config.TTS[vendor]
+	if base == nil {
+		return "", nil, fmt.Errorf("missing %s TTS configuration", vendor)
+	}
+
+	vendorConfig, err := overlayVendorConfig(provider.NewConfig, base, params)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid %s TTS params: %v", vendor, err)
+	}
+	return vendor, vendorConfig, nil
+}
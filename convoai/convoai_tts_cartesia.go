@@ -0,0 +1,79 @@
+package convoai
+
+import "errors"
+
+func init() {
+	RegisterTTSProvider(cartesiaTTSProvider{})
+}
+
+// CartesiaTTSConfig holds Cartesia TTS specific configuration
+type CartesiaTTSConfig struct {
+	Key      string `json:"key"`
+	ModelID  string `json:"model_id"`
+	VoiceID  string `json:"voice_id"`
+	Language string `json:"language,omitempty"`
+}
+
+// CartesiaTTSParams are the Cartesia params sent to the Agora engine
+type CartesiaTTSParams struct {
+	APIKey   string        `json:"api_key"`
+	ModelID  string        `json:"model_id"`
+	Voice    CartesiaVoice `json:"voice"`
+	Language string        `json:"language,omitempty"`
+}
+
+// CartesiaVoice selects a Cartesia voice by ID
+type CartesiaVoice struct {
+	Mode string `json:"mode"`
+	ID   string `json:"id"`
+}
+
+// cartesiaTTSProvider registers Cartesia TTS
+type cartesiaTTSProvider struct{}
+
+func (cartesiaTTSProvider) Vendor() TTSVendor {
+	return TTSVendorCartesia
+}
+
+func (cartesiaTTSProvider) LoadEnv(getenv func(string) string) TTSVendorConfig {
+	key := getenv("CARTESIA_API_KEY")
+	if key == "" {
+		return nil
+	}
+	return &CartesiaTTSConfig{
+		Key:      key,
+		ModelID:  getenv("CARTESIA_MODEL_ID"),
+		VoiceID:  getenv("CARTESIA_VOICE_ID"),
+		Language: getenv("CARTESIA_LANGUAGE"),
+	}
+}
+
+func (cartesiaTTSProvider) NewConfig() TTSVendorConfig {
+	return &CartesiaTTSConfig{}
+}
+
+// Validate checks the Cartesia TTS configuration is complete
+func (c *CartesiaTTSConfig) Validate() error {
+	if c.Key == "" ||
+		c.ModelID == "" ||
+		c.VoiceID == "" {
+		return errors.New("cartesia TTS configuration is incomplete")
+	}
+	return nil
+}
+
+// Params builds the Cartesia TTS params for the Agora engine
+func (c *CartesiaTTSConfig) Params() (interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return CartesiaTTSParams{
+		APIKey:  c.Key,
+		ModelID: c.ModelID,
+		Voice: CartesiaVoice{
+			Mode: "id",
+			ID:   c.VoiceID,
+		},
+		Language: c.Language,
+	}, nil
+}
@@ -0,0 +1,59 @@
+package convoai
+
+import "errors"
+
+func init() {
+	RegisterTTSProvider(elevenLabsTTSProvider{})
+}
+
+// ElevenLabsTTSParams are the ElevenLabs params sent to the Agora engine
+type ElevenLabsTTSParams struct {
+	APIKey  string `json:"api_key"`
+	ModelID string `json:"model_id"`
+	VoiceID string `json:"voice_id"`
+}
+
+// elevenLabsTTSProvider registers ElevenLabs TTS
+type elevenLabsTTSProvider struct{}
+
+func (elevenLabsTTSProvider) Vendor() TTSVendor {
+	return TTSVendorElevenLabs
+}
+
+func (elevenLabsTTSProvider) LoadEnv(getenv func(string) string) TTSVendorConfig {
+	key := getenv("ELEVENLABS_API_KEY")
+	if key == "" {
+		return nil
+	}
+	return &ElevenLabsTTSConfig{
+		Key:     key,
+		VoiceID: getenv("ELEVENLABS_VOICE_ID"),
+		ModelID: getenv("ELEVENLABS_MODEL_ID"),
+	}
+}
+
+func (elevenLabsTTSProvider) NewConfig() TTSVendorConfig {
+	return &ElevenLabsTTSConfig{}
+}
+
+// Validate checks the ElevenLabs TTS configuration is complete
+func (c *ElevenLabsTTSConfig) Validate() error {
+	if c.Key == "" ||
+		c.ModelID == "" ||
+		c.VoiceID == "" {
+		return errors.New("elevenlabs TTS configuration is incomplete")
+	}
+	return nil
+}
+
+// Params builds the ElevenLabs TTS params for the Agora engine
+func (c *ElevenLabsTTSConfig) Params() (interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return ElevenLabsTTSParams{
+		APIKey:  c.Key,
+		ModelID: c.ModelID,
+		VoiceID: c.VoiceID,
+	}, nil
+}
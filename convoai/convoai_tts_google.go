@@ -0,0 +1,114 @@
+package convoai
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Google TTS speaking rate range
+const (
+	minGoogleTTSSpeakingRate = 0.25
+	maxGoogleTTSSpeakingRate = 4.0
+)
+
+func init() {
+	RegisterTTSProvider(googleTTSProvider{})
+}
+
+// GoogleTTSConfig holds Google Cloud TTS specific configuration
+type GoogleTTSConfig struct {
+	Credentials  string `json:"credentials"`
+	VoiceName    string `json:"voice_name"`
+	LanguageCode string `json:"language_code"`
+	SpeakingRate string `json:"speaking_rate,omitempty"`
+}
+
+// GoogleTTSParams are the Google params sent to the Agora engine
+type GoogleTTSParams struct {
+	Credentials          string               `json:"credentials"`
+	VoiceSelectionParams GoogleVoiceSelection `json:"VoiceSelectionParams"`
+	AudioConfig          *GoogleAudioConfig   `json:"AudioConfig,omitempty"`
+}
+
+// GoogleVoiceSelection selects a Google voice
+type GoogleVoiceSelection struct {
+	Name         string `json:"name"`
+	LanguageCode string `json:"language_code"`
+}
+
+// GoogleAudioConfig tunes the Google audio output
+type GoogleAudioConfig struct {
+	SpeakingRate float64 `json:"speaking_rate,omitempty"`
+}
+
+// googleTTSProvider registers Google Cloud TTS
+type googleTTSProvider struct{}
+
+func (googleTTSProvider) Vendor() TTSVendor {
+	return TTSVendorGoogle
+}
+
+func (googleTTSProvider) LoadEnv(getenv func(string) string) TTSVendorConfig {
+	credentials := getenv("GOOGLE_TTS_CREDENTIALS")
+	if credentials == "" {
+		return nil
+	}
+	return &GoogleTTSConfig{
+		Credentials:  credentials,
+		VoiceName:    getenv("GOOGLE_TTS_VOICE_NAME"),
+		LanguageCode: getenv("GOOGLE_TTS_LANGUAGE_CODE"),
+		SpeakingRate: getenv("GOOGLE_TTS_SPEAKING_RATE"),
+	}
+}
+
+func (googleTTSProvider) NewConfig() TTSVendorConfig {
+	return &GoogleTTSConfig{}
+}
+
+// Validate checks the Google TTS configuration is complete and the speaking rate is in range
+func (c *GoogleTTSConfig) Validate() error {
+	if c.Credentials == "" ||
+		c.VoiceName == "" ||
+		c.LanguageCode == "" {
+		return errors.New("google TTS configuration is incomplete")
+	}
+	if _, err := c.speakingRate(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Params builds the Google TTS params for the Agora engine
+func (c *GoogleTTSConfig) Params() (interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	params := GoogleTTSParams{
+		Credentials: c.Credentials,
+		VoiceSelectionParams: GoogleVoiceSelection{
+			Name:         c.VoiceName,
+			LanguageCode: c.LanguageCode,
+		},
+	}
+	if rate, _ := c.speakingRate(); rate != 0 {
+		params.AudioConfig = &GoogleAudioConfig{SpeakingRate: rate}
+	}
+	return params, nil
+}
+
+// speakingRate parses the optional speaking rate, returning 0 when it isn't set
+func (c *GoogleTTSConfig) speakingRate() (float64, error) {
+	if c.SpeakingRate == "" {
+		return 0, nil
+	}
+	rate, err := strconv.ParseFloat(c.SpeakingRate, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid speaking rate value: %v", err)
+	}
+	if rate < minGoogleTTSSpeakingRate || rate > maxGoogleTTSSpeakingRate {
+		return 0, fmt.Errorf("google TTS speaking rate must be between %.2f and %.1f", minGoogleTTSSpeakingRate, maxGoogleTTSSpeakingRate)
+	}
+	return rate, nil
+}
@@ -0,0 +1,153 @@
+package convoai
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Microsoft TTS rate and volume ranges
+const (
+	minMicrosoftTTSRate   = 0.5
+	maxMicrosoftTTSRate   = 2.0
+	minMicrosoftTTSVolume = 0.0
+	maxMicrosoftTTSVolume = 100.0
+)
+
+func init() {
+	RegisterTTSProvider(microsoftTTSProvider{})
+}
+
+// MicrosoftTTSParams are the Microsoft params sent to the Agora engine
+type MicrosoftTTSParams struct {
+	Key       string  `json:"key"`
+	Region    string  `json:"region"`
+	VoiceName string  `json:"voice_name"`
+	Rate      float64 `json:"rate"`
+	Volume    float64 `json:"volume"`
+}
+
+// microsoftTTSProvider registers Microsoft Azure TTS
+type microsoftTTSProvider struct{}
+
+func (microsoftTTSProvider) Vendor() TTSVendor {
+	return TTSVendorMicrosoft
+}
+
+func (microsoftTTSProvider) LoadEnv(getenv func(string) string) TTSVendorConfig {
+	key := getenv("MICROSOFT_TTS_KEY")
+	if key == "" {
+		return nil
+	}
+	return &MicrosoftTTSConfig{
+		Key:       key,
+		Region:    getenv("MICROSOFT_TTS_REGION"),
+		VoiceName: getenv("MICROSOFT_TTS_VOICE_NAME"),
+		Rate:      getenv("MICROSOFT_TTS_RATE"),
+		Volume:    getenv("MICROSOFT_TTS_VOLUME"),
+		Voices:    parseVoiceMap(getenv("MICROSOFT_TTS_VOICES")),
+	}
+}
+
+// parseVoiceMap parses a comma-separated list of locale=voice pairs. A locale listed
+// without a voice is kept with an empty voice, for Validate to report.
+func parseVoiceMap(value string) map[string]string {
+	var voices map[string]string
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		locale, voice, _ := strings.Cut(pair, "=")
+		if voices == nil {
+			voices = make(map[string]string)
+		}
+		voices[strings.TrimSpace(locale)] = strings.TrimSpace(voice)
+	}
+	return voices
+}
+
+func (microsoftTTSProvider) NewConfig() TTSVendorConfig {
+	return &MicrosoftTTSConfig{}
+}
+
+// Validate checks the Microsoft TTS configuration is complete and the rate and volume are in range
+func (c *MicrosoftTTSConfig) Validate() error {
+	if c.Key == "" ||
+		c.Region == "" ||
+		c.VoiceName == "" ||
+		c.Rate == "" ||
+		c.Volume == "" {
+		return errors.New("microsoft TTS configuration is incomplete")
+	}
+	if _, _, err := c.rateAndVolume(); err != nil {
+		return err
+	}
+	for locale, voice := range c.Voices {
+		if locale == "" || !microsoftVoiceSpeaks(voice, locale) {
+			return fmt.Errorf("microsoft TTS voice %q configured for %q does not speak it", voice, locale)
+		}
+	}
+	return nil
+}
+
+// Params builds the Microsoft TTS params for the Agora engine
+func (c *MicrosoftTTSConfig) Params() (interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	rate, volume, _ := c.rateAndVolume()
+	return MicrosoftTTSParams{
+		Key:       c.Key,
+		Region:    c.Region,
+		VoiceName: c.VoiceName,
+		Rate:      rate,
+		Volume:    volume,
+	}, nil
+}
+
+// rateAndVolume converts rate and volume from string to float64 and checks their ranges
+func (c *MicrosoftTTSConfig) rateAndVolume() (float64, float64, error) {
+	rate, err := strconv.ParseFloat(c.Rate, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid rate value: %v", err)
+	}
+	if rate < minMicrosoftTTSRate || rate > maxMicrosoftTTSRate {
+		return 0, 0, fmt.Errorf("microsoft TTS rate must be between %.1f and %.1f", minMicrosoftTTSRate, maxMicrosoftTTSRate)
+	}
+
+	volume, err := strconv.ParseFloat(c.Volume, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid volume value: %v", err)
+	}
+	if volume < minMicrosoftTTSVolume || volume > maxMicrosoftTTSVolume {
+		return 0, 0, fmt.Errorf("microsoft TTS volume must be between %.1f and %.1f", minMicrosoftTTSVolume, maxMicrosoftTTSVolume)
+	}
+	return rate, volume, nil
+}
+
+// VoiceFor returns the voice mapped to the locale. Without a mapping, the configured voice is
+// used if it belongs to the locale, or as a fallback if it is a multilingual voice, such as
+// en-US-AndrewMultilingualNeural, which speaks the language of the text it is given.
+func (c *MicrosoftTTSConfig) VoiceFor(locale string) (string, error) {
+	if voice, ok := c.Voices[locale]; ok {
+		return voice, nil
+	}
+	if microsoftVoiceSpeaks(c.VoiceName, locale) {
+		return c.VoiceName, nil
+	}
+	return "", fmt.Errorf("no microsoft TTS voice is configured for %s; add it to MICROSOFT_TTS_VOICES or use a multilingual voice", locale)
+}
+
+// WithVoice returns a copy of the configuration using the given voice
+func (c *MicrosoftTTSConfig) WithVoice(voice string) TTSVendorConfig {
+	config := *c
+	config.VoiceName = voice
+	return &config
+}
+
+// microsoftVoiceSpeaks reports whether the voice belongs to the locale, e.g. es-ES-AlvaroNeural
+// to es-ES, or is a multilingual voice
+func microsoftVoiceSpeaks(voice, locale string) bool {
+	return strings.HasPrefix(voice, locale+"-") || strings.Contains(voice, "Multilingual")
+}
@@ -0,0 +1,103 @@
+package convoai
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// OpenAI TTS speed range
+const (
+	minOpenAITTSSpeed = 0.25
+	maxOpenAITTSSpeed = 4.0
+)
+
+func init() {
+	RegisterTTSProvider(openAITTSProvider{})
+}
+
+// OpenAITTSConfig holds OpenAI TTS specific configuration
+type OpenAITTSConfig struct {
+	Key          string `json:"key"`
+	Model        string `json:"model"`
+	Voice        string `json:"voice"`
+	Instructions string `json:"instructions,omitempty"`
+	Speed        string `json:"speed,omitempty"`
+}
+
+// OpenAITTSParams are the OpenAI params sent to the Agora engine
+type OpenAITTSParams struct {
+	APIKey       string  `json:"api_key"`
+	Model        string  `json:"model"`
+	Voice        string  `json:"voice"`
+	Instructions string  `json:"instructions,omitempty"`
+	Speed        float64 `json:"speed,omitempty"`
+}
+
+// openAITTSProvider registers OpenAI TTS
+type openAITTSProvider struct{}
+
+func (openAITTSProvider) Vendor() TTSVendor {
+	return TTSVendorOpenAI
+}
+
+func (openAITTSProvider) LoadEnv(getenv func(string) string) TTSVendorConfig {
+	key := getenv("OPENAI_TTS_API_KEY")
+	if key == "" {
+		return nil
+	}
+	return &OpenAITTSConfig{
+		Key:          key,
+		Model:        getenv("OPENAI_TTS_MODEL"),
+		Voice:        getenv("OPENAI_TTS_VOICE"),
+		Instructions: getenv("OPENAI_TTS_INSTRUCTIONS"),
+		Speed:        getenv("OPENAI_TTS_SPEED"),
+	}
+}
+
+func (openAITTSProvider) NewConfig() TTSVendorConfig {
+	return &OpenAITTSConfig{}
+}
+
+// Validate checks the OpenAI TTS configuration is complete and the speed is in range
+func (c *OpenAITTSConfig) Validate() error {
+	if c.Key == "" ||
+		c.Model == "" ||
+		c.Voice == "" {
+		return errors.New("openai TTS configuration is incomplete")
+	}
+	if _, err := c.speed(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Params builds the OpenAI TTS params for the Agora engine
+func (c *OpenAITTSConfig) Params() (interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	speed, _ := c.speed()
+	return OpenAITTSParams{
+		APIKey:       c.Key,
+		Model:        c.Model,
+		Voice:        c.Voice,
+		Instructions: c.Instructions,
+		Speed:        speed,
+	}, nil
+}
+
+// speed parses the optional speed, returning 0 when it isn't set
+func (c *OpenAITTSConfig) speed() (float64, error) {
+	if c.Speed == "" {
+		return 0, nil
+	}
+	speed, err := strconv.ParseFloat(c.Speed, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid speed value: %v", err)
+	}
+	if speed < minOpenAITTSSpeed || speed > maxOpenAITTSSpeed {
+		return 0, fmt.Errorf("openai TTS speed must be between %.2f and %.1f", minOpenAITTSSpeed, maxOpenAITTSSpeed)
+	}
+	return speed, nil
+}
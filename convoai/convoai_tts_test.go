@@ -0,0 +1,127 @@
+package convoai
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeTTSConfig is the configuration of the fake TTS vendor used in tests
+type fakeTTSConfig struct {
+	Token string `json:"token"`
+	Voice string `json:"voice"`
+}
+
+func (c *fakeTTSConfig) Validate() error {
+	if c.Token == "" || c.Voice == "" {
+		return errors.New("fake TTS configuration is incomplete")
+	}
+	return nil
+}
+
+func (c *fakeTTSConfig) Params() (interface{}, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return map[string]string{"token": c.Token, "voice": c.Voice}, nil
+}
+
+// fakeTTSProvider registers the fake TTS vendor
+type fakeTTSProvider struct{}
+
+func (fakeTTSProvider) Vendor() TTSVendor { return "fake" }
+
+func (fakeTTSProvider) LoadEnv(getenv func(string) string) TTSVendorConfig {
+	if getenv("FAKE_TTS_TOKEN") == "" {
+		return nil
+	}
+	return &fakeTTSConfig{Token: getenv("FAKE_TTS_TOKEN"), Voice: getenv("FAKE_TTS_VOICE")}
+}
+
+func (fakeTTSProvider) NewConfig() TTSVendorConfig { return &fakeTTSConfig{} }
+
+// registerFakeTTSProvider registers the fake vendor until the test ends
+func registerFakeTTSProvider(t *testing.T) {
+	t.Helper()
+	RegisterTTSProvider(fakeTTSProvider{})
+	t.Cleanup(func() {
+		ttsProviders.Lock()
+		delete(ttsProviders.byVendor, "fake")
+		ttsProviders.Unlock()
+	})
+}
+
+func TestTTSProviderRegistry(t *testing.T) {
+	registerFakeTTSProvider(t)
+
+	env := map[string]string{
+		"FAKE_TTS_TOKEN": "fake-token",
+		"FAKE_TTS_VOICE": "narrator",
+	}
+	configs := LoadTTSConfigs(func(key string) string { return env[key] })
+	if _, ok := configs["fake"]; !ok {
+		t.Fatalf("LoadTTSConfigs() did not load the fake vendor: %v", configs)
+	}
+	if _, ok := configs[TTSVendorMicrosoft]; ok {
+		t.Errorf("LoadTTSConfigs() loaded an unconfigured vendor")
+	}
+
+	config := &ConvoAIConfig{TTSVendor: "fake", TTS: configs}
+
+	tests := []struct {
+		name      string
+		config    *ConvoAIConfig
+		profile   *AgentProfile
+		wantVoice string
+		wantErr   bool
+	}{
+		{
+			name:      "Configured vendor",
+			config:    config,
+			wantVoice: "narrator",
+		},
+		{
+			name:      "Profile overrides the voice",
+			config:    config,
+			profile:   &AgentProfile{TTS: &ProfileTTS{Params: map[string]interface{}{"voice": "announcer"}}},
+			wantVoice: "announcer",
+		},
+		{
+			name:    "Profile blanks the voice",
+			config:  config,
+			profile: &AgentProfile{TTS: &ProfileTTS{Params: map[string]interface{}{"voice": ""}}},
+			wantErr: true,
+		},
+		{
+			name:    "Profile selects an unconfigured vendor",
+			config:  config,
+			profile: &AgentProfile{TTS: &ProfileTTS{Vendor: string(TTSVendorElevenLabs)}},
+			wantErr: true,
+		},
+		{
+			name:    "Unregistered vendor",
+			config:  &ConvoAIConfig{TTSVendor: "unknown", TTS: configs},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tts, err := ResolveTTSConfig(tt.config, tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveTTSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			params := tts.Params.(map[string]string)
+			if tts.Vendor != "fake" || params["voice"] != tt.wantVoice || params["token"] != "fake-token" {
+				t.Errorf("ResolveTTSConfig() = %+v", tts)
+			}
+		})
+	}
+
+	// Overlaying a profile never changes the shared vendor configuration
+	if voice := configs["fake"].(*fakeTTSConfig).Voice; voice != "narrator" {
+		t.Errorf("base configuration was modified, voice = %q", voice)
+	}
+}
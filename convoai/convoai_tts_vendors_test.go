@@ -0,0 +1,283 @@
+package convoai
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTTSVendorParamsJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		vendor   TTSVendor
+		env      map[string]string
+		wantJSON string
+		wantErr  bool
+	}{
+		{
+			name:   "Cartesia",
+			vendor: TTSVendorCartesia,
+			env: map[string]string{
+				"CARTESIA_API_KEY":  "cartesia-key",
+				"CARTESIA_MODEL_ID": "sonic-2",
+				"CARTESIA_VOICE_ID": "voice-123",
+			},
+			wantJSON: `{"vendor":"cartesia","params":{"api_key":"cartesia-key","model_id":"sonic-2","voice":{"mode":"id","id":"voice-123"}}}`,
+		},
+		{
+			name:   "Cartesia with language",
+			vendor: TTSVendorCartesia,
+			env: map[string]string{
+				"CARTESIA_API_KEY":  "cartesia-key",
+				"CARTESIA_MODEL_ID": "sonic-2",
+				"CARTESIA_VOICE_ID": "voice-123",
+				"CARTESIA_LANGUAGE": "es",
+			},
+			wantJSON: `{"vendor":"cartesia","params":{"api_key":"cartesia-key","model_id":"sonic-2","voice":{"mode":"id","id":"voice-123"},"language":"es"}}`,
+		},
+		{
+			name:   "Cartesia missing voice",
+			vendor: TTSVendorCartesia,
+			env: map[string]string{
+				"CARTESIA_API_KEY":  "cartesia-key",
+				"CARTESIA_MODEL_ID": "sonic-2",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "OpenAI",
+			vendor: TTSVendorOpenAI,
+			env: map[string]string{
+				"OPENAI_TTS_API_KEY": "openai-key",
+				"OPENAI_TTS_MODEL":   "gpt-4o-mini-tts",
+				"OPENAI_TTS_VOICE":   "coral",
+			},
+			wantJSON: `{"vendor":"openai","params":{"api_key":"openai-key","model":"gpt-4o-mini-tts","voice":"coral"}}`,
+		},
+		{
+			name:   "OpenAI with instructions and speed",
+			vendor: TTSVendorOpenAI,
+			env: map[string]string{
+				"OPENAI_TTS_API_KEY":      "openai-key",
+				"OPENAI_TTS_MODEL":        "gpt-4o-mini-tts",
+				"OPENAI_TTS_VOICE":        "coral",
+				"OPENAI_TTS_INSTRUCTIONS": "Speak calmly.",
+				"OPENAI_TTS_SPEED":        "1.25",
+			},
+			wantJSON: `{"vendor":"openai","params":{"api_key":"openai-key","model":"gpt-4o-mini-tts","voice":"coral","instructions":"Speak calmly.","speed":1.25}}`,
+		},
+		{
+			name:   "OpenAI speed out of range",
+			vendor: TTSVendorOpenAI,
+			env: map[string]string{
+				"OPENAI_TTS_API_KEY": "openai-key",
+				"OPENAI_TTS_MODEL":   "gpt-4o-mini-tts",
+				"OPENAI_TTS_VOICE":   "coral",
+				"OPENAI_TTS_SPEED":   "5",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "Google",
+			vendor: TTSVendorGoogle,
+			env: map[string]string{
+				"GOOGLE_TTS_CREDENTIALS":   `{"type":"service_account"}`,
+				"GOOGLE_TTS_VOICE_NAME":    "en-US-Chirp3-HD-Charon",
+				"GOOGLE_TTS_LANGUAGE_CODE": "en-US",
+			},
+			wantJSON: `{"vendor":"google","params":{"credentials":"{\"type\":\"service_account\"}","VoiceSelectionParams":{"name":"en-US-Chirp3-HD-Charon","language_code":"en-US"}}}`,
+		},
+		{
+			name:   "Google with speaking rate",
+			vendor: TTSVendorGoogle,
+			env: map[string]string{
+				"GOOGLE_TTS_CREDENTIALS":   "credentials",
+				"GOOGLE_TTS_VOICE_NAME":    "ja-JP-Neural2-B",
+				"GOOGLE_TTS_LANGUAGE_CODE": "ja-JP",
+				"GOOGLE_TTS_SPEAKING_RATE": "0.9",
+			},
+			wantJSON: `{"vendor":"google","params":{"credentials":"credentials","VoiceSelectionParams":{"name":"ja-JP-Neural2-B","language_code":"ja-JP"},"AudioConfig":{"speaking_rate":0.9}}}`,
+		},
+		{
+			name:   "Google invalid speaking rate",
+			vendor: TTSVendorGoogle,
+			env: map[string]string{
+				"GOOGLE_TTS_CREDENTIALS":   "credentials",
+				"GOOGLE_TTS_VOICE_NAME":    "ja-JP-Neural2-B",
+				"GOOGLE_TTS_LANGUAGE_CODE": "ja-JP",
+				"GOOGLE_TTS_SPEAKING_RATE": "fast",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &ConvoAIConfig{
+				TTSVendor: string(tt.vendor),
+				TTS:       LoadTTSConfigs(func(key string) string { return tt.env[key] }),
+			}
+
+			tts, err := ResolveTTSConfig(config, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveTTSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			data, err := json.Marshal(tts)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(data) != tt.wantJSON {
+				t.Errorf("TTSConfig JSON =\n%s\nwant\n%s", data, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func TestTTSParamsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   TTSVendorConfig
+		decoded  interface{}
+		wantKeys []string
+	}{
+		{
+			name: "Microsoft",
+			config: &MicrosoftTTSConfig{
+				Key:       "ms-key",
+				Region:    "eastus",
+				VoiceName: "en-US-AndrewMultilingualNeural",
+				Rate:      "1.1",
+				Volume:    "70",
+			},
+			decoded:  &MicrosoftTTSParams{},
+			wantKeys: []string{"key", "rate", "region", "voice_name", "volume"},
+		},
+		{
+			name: "ElevenLabs",
+			config: &ElevenLabsTTSConfig{
+				Key:     "el-key",
+				VoiceID: "voice-123",
+				ModelID: "eleven_flash_v2_5",
+			},
+			decoded:  &ElevenLabsTTSParams{},
+			wantKeys: []string{"api_key", "model_id", "voice_id"},
+		},
+		{
+			name: "Cartesia",
+			config: &CartesiaTTSConfig{
+				Key:      "cartesia-key",
+				ModelID:  "sonic-2",
+				VoiceID:  "voice-123",
+				Language: "en",
+			},
+			decoded:  &CartesiaTTSParams{},
+			wantKeys: []string{"api_key", "language", "model_id", "voice"},
+		},
+		{
+			name: "OpenAI",
+			config: &OpenAITTSConfig{
+				Key:          "openai-key",
+				Model:        "gpt-4o-mini-tts",
+				Voice:        "coral",
+				Instructions: "Speak calmly.",
+				Speed:        "1.5",
+			},
+			decoded:  &OpenAITTSParams{},
+			wantKeys: []string{"api_key", "instructions", "model", "speed", "voice"},
+		},
+		{
+			name: "Google",
+			config: &GoogleTTSConfig{
+				Credentials:  "credentials",
+				VoiceName:    "en-US-Chirp3-HD-Charon",
+				LanguageCode: "en-US",
+				SpeakingRate: "1.2",
+			},
+			decoded:  &GoogleTTSParams{},
+			wantKeys: []string{"AudioConfig", "VoiceSelectionParams", "credentials"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := tt.config.Params()
+			if err != nil {
+				t.Fatalf("Params() error = %v", err)
+			}
+			data, err := json.Marshal(params)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+
+			// The params decode back into the same typed struct unchanged
+			if err := json.Unmarshal(data, tt.decoded); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if got := reflect.ValueOf(tt.decoded).Elem().Interface(); !reflect.DeepEqual(got, params) {
+				t.Errorf("round trip = %+v, want %+v", got, params)
+			}
+
+			// And carry exactly the keys the Agora engine expects
+			var fields map[string]interface{}
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			keys := make([]string, 0, len(fields))
+			for key := range fields {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("params keys = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestMicrosoftTTSConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    string
+		volume  string
+		voices  string
+		wantErr bool
+	}{
+		{name: "Defaults", rate: "1.0", volume: "100.0"},
+		{name: "Minimum rate and volume", rate: "0.5", volume: "0"},
+		{name: "Maximum rate", rate: "2.0", volume: "50"},
+		{name: "Rate too low", rate: "0.4", volume: "50", wantErr: true},
+		{name: "Rate too high", rate: "2.5", volume: "50", wantErr: true},
+		{name: "Negative volume", rate: "1.0", volume: "-1", wantErr: true},
+		{name: "Volume too high", rate: "1.0", volume: "101", wantErr: true},
+		{name: "Rate not a number", rate: "fast", volume: "50", wantErr: true},
+		{name: "Missing volume", rate: "1.0", volume: "", wantErr: true},
+		{name: "Per-locale voices", rate: "1.0", volume: "100", voices: "es-ES=es-ES-AlvaroNeural, ja-JP=ja-JP-KeitaNeural"},
+		{name: "Multilingual voice for a locale", rate: "1.0", volume: "100", voices: "fr-FR=en-US-AndrewMultilingualNeural"},
+		{name: "Locale without a voice", rate: "1.0", volume: "100", voices: "es-ES=es-ES-AlvaroNeural,ja-JP", wantErr: true},
+		{name: "Voice of another locale", rate: "1.0", volume: "100", voices: "es-ES=ja-JP-KeitaNeural", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &MicrosoftTTSConfig{
+				Key:       "ms-key",
+				Region:    "eastus",
+				VoiceName: "en-US-AndrewMultilingualNeural",
+				Rate:      tt.rate,
+				Volume:    tt.volume,
+				Voices:    parseVoiceMap(tt.voices),
+			}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, err := config.Params(); (err != nil) != tt.wantErr {
+				t.Errorf("Params() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package convoai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+)
+
+// DefaultAgentPollInterval is the number of seconds between polls of the agents' status
+const DefaultAgentPollInterval = 30
+
+// idleTimeoutReason explains an agent that stopped without a remove request
+const idleTimeoutReason = "agent stopped without a remove request, e.g. on idle timeout"
+
+// WatchAgents polls Agora for the status of the agents this instance started until
+// ctx is done, publishing the changes Agora reported no notification for
+func (s *ConvoAIService) WatchAgents(ctx context.Context) {
+	runPeriodically(ctx, func() int { return s.getConfig().AgentPollInterval }, DefaultAgentPollInterval, func(int) {
+		if err := s.pollAgents(); err != nil {
+			log.Println("Warning: failed to poll agent status:", err)
+		}
+	})
+}
+
+// runPeriodically calls fn every interval seconds until ctx is done. The interval is re-read
+// from intervalFn before each wait, so a configuration reload can enable or disable the task;
+// while it is disabled, the interval is checked again after defaultInterval seconds.
+func runPeriodically(ctx context.Context, intervalFn func() int, defaultInterval int, fn func(interval int)) {
+	for {
+		interval := intervalFn()
+		wait := time.Duration(interval) * time.Second
+		if interval <= 0 {
+			wait = time.Duration(defaultInterval) * time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if interval > 0 {
+			fn(interval)
+		}
+	}
+}
+
+// pollAgents compares the sessions of this instance against the agents Agora reports.
+// An agent that is no longer listed stopped on its own, usually on idle timeout, or failed.
+func (s *ConvoAIService) pollAgents() error {
+	sessions, err := s.sessions.List(SessionFilter{InstanceID: s.instanceID})
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %v", err)
+	}
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	agoraAgents, err := s.listAgoraAgents("")
+	if err != nil {
+		return err
+	}
+	listed := make(map[string]AgoraAgentStatus, len(agoraAgents))
+	for _, agent := range agoraAgents {
+		listed[agent.AgentID] = agent
+	}
+
+	var errs []error
+	for _, session := range sessions {
+		current, _ := s.agentStates.get(session.AgentID)
+		if isAgentEnded(current.State) {
+			continue
+		}
+
+		agent, ok := listed[session.AgentID]
+		if !ok {
+			// The list only holds active agents; query the agent to tell stopped from failed
+			agent = AgoraAgentStatus{AgentID: session.AgentID, Status: AgentStateStopped}
+			err := s.agoraRequest("GET", "/agents/"+url.PathEscape(session.AgentID), nil, &agent)
+			if err != nil && !errors.Is(err, ErrAgentNotFound) {
+				errs = append(errs, err)
+				continue
+			}
+		}
+
+		switch agent.Status {
+		case AgentStateStopped:
+			s.setAgentState(AgentEventIdleTimeout, session.AgentID, session.ChannelName, AgentStateStopped, idleTimeoutReason, agent)
+		case AgentStateFailed:
+			s.setAgentState(AgentEventFailed, session.AgentID, session.ChannelName, AgentStateFailed, "agent failed", agent)
+		case AgentStateRunning:
+			if current.State == "" || current.State == AgentStateStarting {
+				s.setAgentState(AgentEventJoined, session.AgentID, session.ChannelName, AgentStateRunning, "", agent)
+			}
+			continue
+		default:
+			continue
+		}
+
+		if err := s.sessions.Delete(session.AgentID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete session for agent %s: %v", session.AgentID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
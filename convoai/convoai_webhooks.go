@@ -0,0 +1,484 @@
+package convoai
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Webhook delivery states
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// Webhook delivery settings
+const (
+	defaultWebhookMaxAttempts = 5
+	defaultWebhookBackoff     = time.Second
+	maxWebhookBackoff         = 5 * time.Minute
+	webhookTimeout            = 10 * time.Second
+	maxWebhookDeliveryLog     = 500
+	maxWebhookDeadLetters     = 1000
+	webhookWorkers            = 4
+)
+
+// defaultWebhookEvents are the lifecycle events sent to subscriptions that list no events
+var defaultWebhookEvents = []string{
+	AgentEventInvited, AgentEventJoined, AgentEventLeft, AgentEventFailed, AgentEventIdleTimeout,
+}
+
+// ErrDeliveryNotFound is returned when a dead letter is not kept
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// WebhookSubscription sends the agent events matching Events to URL, signed with Secret.
+// The lifecycle events in defaultWebhookEvents are sent when Events is empty.
+type WebhookSubscription struct {
+	Name   string   `json:"name" yaml:"name"`
+	URL    string   `json:"url" yaml:"url"`
+	Events []string `json:"events,omitempty" yaml:"events,omitempty"`
+	Secret string   `json:"secret" yaml:"secret"`
+}
+
+// Validate checks the subscription is complete and only filters on known events
+func (w WebhookSubscription) Validate() error {
+	if w.Name == "" {
+		return errors.New("webhook subscription name is required")
+	}
+	if !(strings.HasPrefix(w.URL, "http://") || strings.HasPrefix(w.URL, "https://")) {
+		return fmt.Errorf("webhook subscription %s url must be an http(s) URL", w.Name)
+	}
+	if w.Secret == "" {
+		return fmt.Errorf("webhook subscription %s requires a secret", w.Name)
+	}
+	for _, event := range w.Events {
+		if !ValidAgentEvent(event) {
+			return fmt.Errorf("webhook subscription %s: unknown event %q", w.Name, event)
+		}
+	}
+	return nil
+}
+
+// wants reports whether the subscription receives the event type
+func (w WebhookSubscription) wants(eventType string) bool {
+	events := w.Events
+	if len(events) == 0 {
+		events = defaultWebhookEvents
+	}
+	for _, event := range events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadWebhookSubscriptions reads the subscriptions declared in a .yaml, .yml or .json file
+func LoadWebhookSubscriptions(path string) ([]WebhookSubscription, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhooks file %s: %v", path, err)
+	}
+
+	var file struct {
+		Subscriptions []WebhookSubscription `json:"subscriptions" yaml:"subscriptions"`
+	}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = json.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse webhooks file %s: %v", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, subscription := range file.Subscriptions {
+		if seen[subscription.Name] {
+			return nil, errors.New("webhook subscription " + subscription.Name + " is declared more than once")
+		}
+		seen[subscription.Name] = true
+	}
+	return file.Subscriptions, nil
+}
+
+// WebhookDelivery is the delivery of one event to one subscription, across its attempts
+type WebhookDelivery struct {
+	ID           string     `json:"id"`
+	Subscription string     `json:"subscription"`
+	URL          string     `json:"url"`
+	State        string     `json:"state"`
+	Attempts     int        `json:"attempts"`
+	StatusCode   int        `json:"status_code,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    int64      `json:"created_at"`
+	UpdatedAt    int64      `json:"updated_at"`
+	Event        AgentEvent `json:"event"`
+}
+
+// WebhookDeliveriesResponse lists webhook deliveries, most recent first
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int               `json:"total"`
+}
+
+// webhookDispatcher delivers agent events to the configured subscriptions from a fixed
+// pool of workers, retrying failed deliveries with exponential backoff. Deliveries wait
+// in an in-memory queue, so none is dropped while the workers are busy. Deliveries that
+// exhaust their attempts are kept as dead letters until retried.
+type webhookDispatcher struct {
+	client  *http.Client
+	backoff time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	wake   chan struct{}
+
+	mu          sync.Mutex
+	queue       []*webhookJob
+	log         []*WebhookDelivery
+	deadLetters []*WebhookDelivery
+}
+
+// webhookJob is a queued delivery, waiting for its next attempt to be due
+type webhookJob struct {
+	subscription WebhookSubscription
+	delivery     *WebhookDelivery
+	body         []byte
+	maxAttempts  int
+	backoff      time.Duration
+	due          time.Time
+}
+
+// newWebhookDispatcher creates a dispatcher and starts its workers, which stop when it is closed
+func newWebhookDispatcher() *webhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &webhookDispatcher{
+		client:  &http.Client{Timeout: webhookTimeout},
+		backoff: defaultWebhookBackoff,
+		ctx:     ctx,
+		cancel:  cancel,
+		wake:    make(chan struct{}, 1),
+	}
+	d.wg.Add(webhookWorkers)
+	for i := 0; i < webhookWorkers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// dispatchWebhooks queues the event for the subscriptions of the active configuration.
+// It is called for every event published, and never blocks.
+func (s *ConvoAIService) dispatchWebhooks(event AgentEvent) {
+	config := s.getConfig()
+	for _, subscription := range config.WebhookSubscriptions {
+		if subscription.wants(event.Type) {
+			s.webhooks.enqueue(subscription, event, config.WebhookMaxAttempts)
+		}
+	}
+}
+
+// enqueue queues the delivery of an event to a subscription
+func (d *webhookDispatcher) enqueue(subscription WebhookSubscription, event AgentEvent, maxAttempts int) *WebhookDelivery {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultWebhookMaxAttempts
+	}
+	now := time.Now()
+	delivery := &WebhookDelivery{
+		ID:           fmt.Sprintf("whd_%d%s", now.UnixMilli(), randomString(8)),
+		Subscription: subscription.Name,
+		URL:          subscription.URL,
+		State:        WebhookDeliveryPending,
+		CreatedAt:    now.UnixMilli(),
+		UpdatedAt:    now.UnixMilli(),
+		Event:        event,
+	}
+
+	d.mu.Lock()
+	d.log = append(d.log, delivery)
+	if len(d.log) > maxWebhookDeliveryLog {
+		d.log = d.log[len(d.log)-maxWebhookDeliveryLog:]
+	}
+	backoff := d.backoff
+	d.mu.Unlock()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.finish(delivery, WebhookDeliveryDead, 0, err)
+		return delivery
+	}
+	d.schedule(&webhookJob{
+		subscription: subscription,
+		delivery:     delivery,
+		body:         body,
+		maxAttempts:  maxAttempts,
+		backoff:      backoff,
+		due:          now,
+	})
+	return delivery
+}
+
+// schedule queues a job and wakes a worker, or ends the delivery if the dispatcher is closed
+func (d *webhookDispatcher) schedule(job *webhookJob) {
+	d.mu.Lock()
+	if d.ctx.Err() != nil {
+		d.mu.Unlock()
+		d.finish(job.delivery, WebhookDeliveryDead, job.delivery.StatusCode, errors.New("server shutting down"))
+		return
+	}
+	d.queue = append(d.queue, job)
+	d.mu.Unlock()
+	d.signal()
+}
+
+// signal wakes one idle worker, if none is already being woken
+func (d *webhookDispatcher) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes the queued job that is due first and returns it, or returns how long
+// until the first job is due
+func (d *webhookDispatcher) next(now time.Time) (*webhookJob, time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.queue) == 0 {
+		return nil, maxWebhookBackoff
+	}
+	first := 0
+	for i, job := range d.queue {
+		if job.due.Before(d.queue[first].due) {
+			first = i
+		}
+	}
+	job := d.queue[first]
+	if wait := job.due.Sub(now); wait > 0 {
+		return nil, wait
+	}
+	d.queue = append(d.queue[:first:first], d.queue[first+1:]...)
+	return job, 0
+}
+
+// work runs the jobs as they fall due until the dispatcher is closed
+func (d *webhookDispatcher) work() {
+	defer d.wg.Done()
+	for {
+		job, wait := d.next(time.Now())
+		if job != nil {
+			// Let another worker pick up the rest of the queue meanwhile
+			d.signal()
+			d.attempt(job)
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-d.ctx.Done():
+			timer.Stop()
+			return
+		case <-d.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// attempt posts the event once, queuing a retry after the job's backoff if attempts remain
+func (d *webhookDispatcher) attempt(job *webhookJob) {
+	delivery := job.delivery
+	statusCode, err := d.post(job.subscription, delivery, job.body)
+	d.mu.Lock()
+	delivery.Attempts++
+	attempts := delivery.Attempts
+	d.mu.Unlock()
+	if err == nil {
+		d.finish(delivery, WebhookDeliveryDelivered, statusCode, nil)
+		return
+	}
+	if attempts >= job.maxAttempts {
+		log.Printf("Webhook %s to %s failed after %d attempts: %v", delivery.ID, job.subscription.Name, attempts, err)
+		d.finish(delivery, WebhookDeliveryDead, statusCode, err)
+		return
+	}
+	d.record(delivery, statusCode, err)
+
+	job.due = time.Now().Add(job.backoff)
+	job.backoff = min(job.backoff*2, maxWebhookBackoff)
+	d.schedule(job)
+}
+
+// post makes one delivery attempt. The signature covers the timestamp and body, so a
+// receiver can reject replayed deliveries.
+func (d *webhookDispatcher) post(subscription WebhookSubscription, delivery *WebhookDelivery, body []byte) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(d.ctx, "POST", subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", delivery.ID)
+	req.Header.Set("X-Webhook-Event", delivery.Event.Type)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(subscription.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhook returns the hex HMAC-SHA256 of "timestamp.body"
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// record notes the outcome of a failed attempt that will be retried
+func (d *webhookDispatcher) record(delivery *WebhookDelivery, statusCode int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delivery.StatusCode = statusCode
+	delivery.Error = err.Error()
+	delivery.UpdatedAt = time.Now().UnixMilli()
+}
+
+// finish records the final state of a delivery, moving it to the dead letters if it failed
+func (d *webhookDispatcher) finish(delivery *WebhookDelivery, state string, statusCode int, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delivery.State = state
+	delivery.StatusCode = statusCode
+	delivery.Error = ""
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	delivery.UpdatedAt = time.Now().UnixMilli()
+
+	if state == WebhookDeliveryDead {
+		d.deadLetters = append(d.deadLetters, delivery)
+		if len(d.deadLetters) > maxWebhookDeadLetters {
+			d.deadLetters = d.deadLetters[len(d.deadLetters)-maxWebhookDeadLetters:]
+		}
+	}
+}
+
+// snapshot returns copies of the deliveries, most recent first
+func (d *webhookDispatcher) snapshot(deliveries []*WebhookDelivery) []WebhookDelivery {
+	result := make([]WebhookDelivery, 0, len(deliveries))
+	for i := len(deliveries) - 1; i >= 0; i-- {
+		result = append(result, *deliveries[i])
+	}
+	return result
+}
+
+// deadLetter returns a copy of a dead letter
+func (d *webhookDispatcher) deadLetter(id string) (WebhookDelivery, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, delivery := range d.deadLetters {
+		if delivery.ID == id {
+			return *delivery, true
+		}
+	}
+	return WebhookDelivery{}, false
+}
+
+// removeDeadLetter forgets a dead letter, reporting whether it was kept
+func (d *webhookDispatcher) removeDeadLetter(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, delivery := range d.deadLetters {
+		if delivery.ID == id {
+			d.deadLetters = append(d.deadLetters[:i:i], d.deadLetters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// close stops the workers, waiting for the attempts in flight, and ends the queued
+// deliveries as dead letters
+func (d *webhookDispatcher) close() {
+	d.mu.Lock()
+	d.cancel()
+	d.mu.Unlock()
+	d.wg.Wait()
+
+	d.mu.Lock()
+	queued := d.queue
+	d.queue = nil
+	d.mu.Unlock()
+	for _, job := range queued {
+		d.finish(job.delivery, WebhookDeliveryDead, job.delivery.StatusCode, errors.New("server shutting down"))
+	}
+}
+
+// HandleWebhookDeliveries returns the delivery log, most recent first
+func (s *ConvoAIService) HandleWebhookDeliveries() *WebhookDeliveriesResponse {
+	s.webhooks.mu.Lock()
+	defer s.webhooks.mu.Unlock()
+	deliveries := s.webhooks.snapshot(s.webhooks.log)
+	return &WebhookDeliveriesResponse{Deliveries: deliveries, Total: len(deliveries)}
+}
+
+// HandleWebhookDeadLetters returns the deliveries that exhausted their attempts, most recent first
+func (s *ConvoAIService) HandleWebhookDeadLetters() *WebhookDeliveriesResponse {
+	s.webhooks.mu.Lock()
+	defer s.webhooks.mu.Unlock()
+	deliveries := s.webhooks.snapshot(s.webhooks.deadLetters)
+	return &WebhookDeliveriesResponse{Deliveries: deliveries, Total: len(deliveries)}
+}
+
+// HandleRetryWebhookDeadLetter delivers a dead letter again, as a new delivery to the
+// subscription's current URL and secret
+func (s *ConvoAIService) HandleRetryWebhookDeadLetter(id string) (*WebhookDelivery, error) {
+	config := s.getConfig()
+	dead, ok := s.webhooks.deadLetter(id)
+	if !ok {
+		return nil, ErrDeliveryNotFound
+	}
+	for _, subscription := range config.WebhookSubscriptions {
+		if subscription.Name != dead.Subscription {
+			continue
+		}
+		if !s.webhooks.removeDeadLetter(id) {
+			// Retried concurrently
+			return nil, ErrDeliveryNotFound
+		}
+		delivery := s.webhooks.enqueue(subscription, dead.Event, config.WebhookMaxAttempts)
+		s.webhooks.mu.Lock()
+		defer s.webhooks.mu.Unlock()
+		copied := *delivery
+		return &copied, nil
+	}
+	return nil, fmt.Errorf("webhook subscription %s is no longer configured", dead.Subscription)
+}
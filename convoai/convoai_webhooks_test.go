@@ -0,0 +1,302 @@
+package convoai
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records the webhooks posted to it, failing the first failures requests
+type webhookReceiver struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	failures int
+	received []receivedWebhook
+}
+
+// receivedWebhook is a webhook accepted by the receiver
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+	event  AgentEvent
+}
+
+func newWebhookReceiver(t *testing.T, failures int) *webhookReceiver {
+	t.Helper()
+	r := &webhookReceiver{failures: failures}
+	r.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.failures > 0 {
+			r.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event AgentEvent
+		json.Unmarshal(body, &event)
+		r.received = append(r.received, receivedWebhook{header: req.Header.Clone(), body: body, event: event})
+	}))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+// webhooks returns the webhooks received so far
+func (r *webhookReceiver) webhooks() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.received...)
+}
+
+// waitFor polls until the condition holds
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// withWebhookSubscriptions sends webhooks to the subscriptions, with up to maxAttempts each
+func withWebhookSubscriptions(maxAttempts int, subscriptions ...WebhookSubscription) func(*ConvoAIConfig) {
+	return func(config *ConvoAIConfig) {
+		config.WebhookSubscriptions = subscriptions
+		config.WebhookMaxAttempts = maxAttempts
+	}
+}
+
+func TestOutboundWebhooks(t *testing.T) {
+	agora := newFakeAgora(t)
+	crm := newWebhookReceiver(t, 0)
+	audit := newWebhookReceiver(t, 0)
+	service := newTestService(t, agora, withWebhookSubscriptions(0,
+		WebhookSubscription{Name: "crm", URL: crm.server.URL, Secret: "crm-secret", Events: []string{AgentEventLeft}},
+		WebhookSubscription{Name: "audit", URL: audit.server.URL, Secret: "audit-secret"},
+	))
+	router := newTestRouter(service)
+
+	var invited InviteAgentResponse
+	if status := doJSON(t, router, "POST", "/agent/invite", `{"requester_id": "user-1", "channel_name": "test-channel"}`, &invited); status != http.StatusOK {
+		t.Fatalf("invite returned status %d", status)
+	}
+	if status := doJSON(t, router, "POST", "/agent/remove", `{"agent_id": "`+invited.AgentID+`"}`, nil); status != http.StatusOK {
+		t.Fatalf("remove returned status %d", status)
+	}
+
+	// Events outside the lifecycle are only sent to subscriptions listing them
+	service.publishAgentEvent(AgentEventMetrics, invited.AgentID, "", "", nil)
+
+	// The unfiltered subscription receives both lifecycle events, the filtered one only the leave
+	waitFor(t, "audit webhooks", func() bool { return len(audit.webhooks()) == 2 })
+	waitFor(t, "crm webhook", func() bool { return len(crm.webhooks()) == 1 })
+
+	received := crm.webhooks()[0]
+	if received.event.Type != AgentEventLeft || received.event.AgentID != invited.AgentID || received.event.State != AgentStateStopped {
+		t.Errorf("crm event = %+v", received.event)
+	}
+	if got := received.header.Get("X-Webhook-Event"); got != AgentEventLeft {
+		t.Errorf("X-Webhook-Event = %q", got)
+	}
+	timestamp := received.header.Get("X-Webhook-Timestamp")
+	if got, want := received.header.Get("X-Webhook-Signature"), "sha256="+signWebhook("crm-secret", timestamp, received.body); got != want {
+		t.Errorf("X-Webhook-Signature = %q, want %q", got, want)
+	}
+
+	var log WebhookDeliveriesResponse
+	waitFor(t, "delivered log", func() bool {
+		doJSON(t, router, "GET", "/admin/webhooks/deliveries", "", &log)
+		for _, delivery := range log.Deliveries {
+			if delivery.State != WebhookDeliveryDelivered {
+				return false
+			}
+		}
+		return log.Total == 3
+	})
+	if log.Deliveries[0].Attempts != 1 || log.Deliveries[0].StatusCode != http.StatusOK {
+		t.Errorf("delivery = %+v", log.Deliveries[0])
+	}
+}
+
+func TestOutboundWebhookRetries(t *testing.T) {
+	agora := newFakeAgora(t)
+	crm := newWebhookReceiver(t, 2)
+	service := newTestService(t, agora, withWebhookSubscriptions(3,
+		WebhookSubscription{Name: "crm", URL: crm.server.URL, Secret: "crm-secret"},
+	))
+
+	service.setAgentState(AgentEventJoined, "AGENT0001", "test-channel", AgentStateRunning, "", nil)
+	waitFor(t, "retried webhook", func() bool { return len(crm.webhooks()) == 1 })
+
+	deliveries := service.HandleWebhookDeliveries().Deliveries
+	waitFor(t, "delivered state", func() bool {
+		deliveries = service.HandleWebhookDeliveries().Deliveries
+		return deliveries[0].State == WebhookDeliveryDelivered
+	})
+	if deliveries[0].Attempts != 3 {
+		t.Errorf("attempts = %d, want 3", deliveries[0].Attempts)
+	}
+	if dead := service.HandleWebhookDeadLetters(); dead.Total != 0 {
+		t.Errorf("dead letters = %+v", dead)
+	}
+}
+
+func TestOutboundWebhooksBurst(t *testing.T) {
+	agora := newFakeAgora(t)
+	crm := newWebhookReceiver(t, 0)
+	service := newTestService(t, agora, withWebhookSubscriptions(0,
+		WebhookSubscription{Name: "crm", URL: crm.server.URL, Secret: "crm-secret"},
+	))
+
+	// A burst larger than an event bus buffer is delivered in full
+	const agents = 3 * defaultEventBuffer
+	for i := 0; i < agents; i++ {
+		service.setAgentState(AgentEventJoined, fmt.Sprintf("AGENT%04d", i), "test-channel", AgentStateRunning, "", nil)
+	}
+	waitFor(t, "burst webhooks", func() bool { return len(crm.webhooks()) == agents })
+}
+
+func TestOutboundWebhookDeadLetters(t *testing.T) {
+	agora := newFakeAgora(t)
+	crm := newWebhookReceiver(t, 2)
+	service := newTestService(t, agora, withWebhookSubscriptions(2,
+		WebhookSubscription{Name: "crm", URL: crm.server.URL, Secret: "crm-secret"},
+	))
+	router := newTestRouter(service)
+
+	service.setAgentState(AgentEventFailed, "AGENT0001", "test-channel", AgentStateFailed, "tts failure", nil)
+
+	var dead WebhookDeliveriesResponse
+	waitFor(t, "dead letter", func() bool {
+		doJSON(t, router, "GET", "/admin/webhooks/dead-letters", "", &dead)
+		return dead.Total == 1
+	})
+	if dead.Deliveries[0].State != WebhookDeliveryDead || dead.Deliveries[0].Attempts != 2 || dead.Deliveries[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("dead letter = %+v", dead.Deliveries[0])
+	}
+
+	// Unknown dead letters can't be retried
+	if status := doJSON(t, router, "POST", "/admin/webhooks/dead-letters/unknown/retry", "", nil); status != http.StatusNotFound {
+		t.Errorf("retry of unknown dead letter returned status %d", status)
+	}
+
+	// The receiver has recovered, so the retry is delivered as a new delivery
+	var retried WebhookDelivery
+	if status := doJSON(t, router, "POST", "/admin/webhooks/dead-letters/"+dead.Deliveries[0].ID+"/retry", "", &retried); status != http.StatusAccepted {
+		t.Fatalf("retry returned status %d", status)
+	}
+	if retried.ID == dead.Deliveries[0].ID || retried.Event.Type != AgentEventFailed {
+		t.Errorf("retried delivery = %+v", retried)
+	}
+	waitFor(t, "retried webhook", func() bool { return len(crm.webhooks()) == 1 })
+	if dead := service.HandleWebhookDeadLetters(); dead.Total != 0 {
+		t.Errorf("dead letters after retry = %+v", dead)
+	}
+}
+
+func TestWebhookSubscriptionValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription WebhookSubscription
+		wantErr      bool
+	}{
+		{"valid", WebhookSubscription{Name: "crm", URL: "https://crm.example.com/hooks", Secret: "s", Events: []string{AgentEventIdleTimeout}}, false},
+		{"default events", WebhookSubscription{Name: "crm", URL: "http://localhost:9000", Secret: "s"}, false},
+		{"missing name", WebhookSubscription{URL: "https://crm.example.com/hooks", Secret: "s"}, true},
+		{"bad url", WebhookSubscription{Name: "crm", URL: "crm.example.com", Secret: "s"}, true},
+		{"missing secret", WebhookSubscription{Name: "crm", URL: "https://crm.example.com/hooks"}, true},
+		{"unknown event", WebhookSubscription{Name: "crm", URL: "https://crm.example.com/hooks", Secret: "s", Events: []string{"agent.exploded"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.subscription.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadWebhookSubscriptions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "webhooks.yaml")
+	content := `subscriptions:
+  - name: crm
+    url: https://crm.example.com/hooks
+    secret: crm-secret
+    events: [agent.joined, agent.left, agent.failed, agent.idle_timeout]
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	subscriptions, err := LoadWebhookSubscriptions(path)
+	if err != nil {
+		t.Fatalf("LoadWebhookSubscriptions() error = %v", err)
+	}
+	if len(subscriptions) != 1 || subscriptions[0].Name != "crm" || len(subscriptions[0].Events) != 4 {
+		t.Errorf("subscriptions = %+v", subscriptions)
+	}
+
+	duplicate := filepath.Join(dir, "duplicate.json")
+	content = `{"subscriptions": [{"name": "crm", "url": "https://a.example.com"}, {"name": "crm", "url": "https://b.example.com"}]}`
+	if err := os.WriteFile(duplicate, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadWebhookSubscriptions(duplicate); err == nil {
+		t.Error("expected an error for a duplicate subscription")
+	}
+}
+
+func TestPollAgents(t *testing.T) {
+	agora := newFakeAgora(t)
+	service := newTestService(t, agora)
+	router := newTestRouter(service)
+	events, unsubscribe := service.SubscribeEvents(10)
+	defer unsubscribe()
+
+	var invited InviteAgentResponse
+	if status := doJSON(t, router, "POST", "/agent/invite", `{"requester_id": "user-1", "channel_name": "test-channel"}`, &invited); status != http.StatusOK {
+		t.Fatalf("invite returned status %d", status)
+	}
+	nextEvent(t, events)
+
+	// Agora lists the agent as running
+	if err := service.pollAgents(); err != nil {
+		t.Fatalf("pollAgents() error = %v", err)
+	}
+	if event := nextEvent(t, events); event.Type != AgentEventJoined || event.State != AgentStateRunning {
+		t.Errorf("running event = %+v", event)
+	}
+	if err := service.pollAgents(); err != nil {
+		t.Fatalf("pollAgents() error = %v", err)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event for an unchanged agent: %+v", event)
+	default:
+	}
+
+	// The agent idles out
+	agora.mu.Lock()
+	agora.agents[invited.AgentID].Status = AgentStateStopped
+	agora.mu.Unlock()
+	if err := service.pollAgents(); err != nil {
+		t.Fatalf("pollAgents() error = %v", err)
+	}
+	event := nextEvent(t, events)
+	if event.Type != AgentEventIdleTimeout || event.State != AgentStateStopped || event.ChannelName != "test-channel" {
+		t.Errorf("idle timeout event = %+v", event)
+	}
+	if _, ok, _ := service.sessions.Get(invited.AgentID); ok {
+		t.Error("session of a stopped agent should be deleted")
+	}
+}
@@ -1,11 +1,20 @@
 package token_service
 
 import (
+	"bytes"
+	"compress/zlib"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/AgoraIO-Community/go-tokenbuilder/accesstoken"
 	"github.com/AgoraIO-Community/go-tokenbuilder/chatTokenBuilder"
 	rtctokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtctokenbuilder"
 	rtmtokenbuilder2 "github.com/AgoraIO-Community/go-tokenbuilder/rtmtokenbuilder"
@@ -223,3 +232,121 @@ func (s *TokenService) GenChatToken(tokenRequest TokenRequest) (string, error) {
 
 	return chatToken, tokenErr
 }
+
+// VerifyRtcToken checks that a token is an RTC token issued by this service, and returns the
+// channel and user it grants access to. Other services use it to authenticate clients with the
+// RTC token they already hold.
+//
+// Parameters:
+//   - token: string - The RTC token to verify.
+//
+// Returns:
+//   - string: The channel name the token was issued for.
+//   - string: The user ID or account the token was issued for ("" for the wildcard UID 0).
+//   - error: An error if the token is malformed, not signed with the app certificate, or expired.
+//
+// Behavior:
+//  1. Decodes the AccessToken2 ("007") token.
+//  2. Checks the token was issued for the app ID, and recomputes its signature with the app certificate.
+//  3. Checks the token and its join channel privilege have not expired.
+//
+// Notes:
+//   - The go-tokenbuilder package can parse tokens but does not verify their signature, so the token is decoded here.
+func (s *TokenService) VerifyRtcToken(token string) (string, string, error) {
+	if len(token) <= len(accesstoken.Version) || token[:len(accesstoken.Version)] != accesstoken.Version {
+		return "", "", errors.New("invalid: unsupported token version")
+	}
+	compressed, err := base64.StdEncoding.DecodeString(token[len(accesstoken.Version):])
+	if err != nil {
+		return "", "", errors.New("invalid: malformed token")
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", "", errors.New("invalid: malformed token")
+	}
+	content, err := io.ReadAll(io.LimitReader(zr, maxTokenSize))
+	if err != nil {
+		return "", "", errors.New("invalid: malformed token")
+	}
+
+	r := &tokenReader{data: content}
+	signature := r.bytes()
+	signed := r.data
+	appID := string(r.bytes())
+	issueTs := r.uint32()
+	expire := r.uint32()
+	salt := r.uint32()
+	serviceCount := r.uint16()
+	serviceType := r.uint16()
+	privileges := make(map[uint16]uint32)
+	for i := r.uint16(); i > 0 && r.err == nil; i-- {
+		privileges[r.uint16()] = r.uint32()
+	}
+	channel := string(r.bytes())
+	uid := string(r.bytes())
+	if r.err != nil || serviceCount == 0 || serviceType != accesstoken.ServiceTypeRtc {
+		return "", "", errors.New("invalid: not an RTC token")
+	}
+
+	if appID != s.appID {
+		return "", "", errors.New("invalid: token issued for another app")
+	}
+	if !hmac.Equal(signature, signToken(s.appCertificate, issueTs, salt, signed)) {
+		return "", "", errors.New("invalid: token signature mismatch")
+	}
+
+	now := time.Now().Unix()
+	joinExpire, ok := privileges[accesstoken.PrivilegeJoinChannel]
+	if !ok || now > int64(issueTs)+int64(expire) || now > int64(issueTs)+int64(joinExpire) {
+		return "", "", errors.New("invalid: token expired")
+	}
+	return channel, uid, nil
+}
+
+// maxTokenSize bounds the decompressed size of a token
+const maxTokenSize = 64 * 1024
+
+// signToken computes the AccessToken2 signature of the signed part of a token
+func signToken(appCertificate string, issueTs, salt uint32, signed []byte) []byte {
+	hIssueTs := hmac.New(sha256.New, binary.LittleEndian.AppendUint32(nil, issueTs))
+	hIssueTs.Write([]byte(appCertificate))
+	hSalt := hmac.New(sha256.New, binary.LittleEndian.AppendUint32(nil, salt))
+	hSalt.Write(hIssueTs.Sum(nil))
+	hSign := hmac.New(sha256.New, hSalt.Sum(nil))
+	hSign.Write(signed)
+	return hSign.Sum(nil)
+}
+
+// tokenReader reads the little-endian fields of a decoded token, recording the first error
+type tokenReader struct {
+	data []byte
+	err  error
+}
+
+func (r *tokenReader) next(n int) []byte {
+	if r.err != nil || len(r.data) < n {
+		r.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	field := r.data[:n]
+	r.data = r.data[n:]
+	return field
+}
+
+func (r *tokenReader) uint16() uint16 {
+	if field := r.next(2); field != nil {
+		return binary.LittleEndian.Uint16(field)
+	}
+	return 0
+}
+
+func (r *tokenReader) uint32() uint32 {
+	if field := r.next(4); field != nil {
+		return binary.LittleEndian.Uint32(field)
+	}
+	return 0
+}
+
+func (r *tokenReader) bytes() []byte {
+	return r.next(int(r.uint16()))
+}
@@ -217,3 +217,80 @@ func TestGetToken(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyRtcToken(t *testing.T) {
+	service := NewTestTokenService()
+	otherApp := &TokenService{appID: "0123456789abcdef0123456789abcdef", appCertificate: service.appCertificate}
+	otherCert := &TokenService{appID: service.appID, appCertificate: "fedcba9876543210fedcba9876543210"}
+
+	genToken := func(s *TokenService, req TokenRequest) string {
+		token, err := s.GenRtcToken(req)
+		if err != nil {
+			t.Fatalf("GenRtcToken() error = %v", err)
+		}
+		return token
+	}
+	rtcRequest := TokenRequest{TokenType: "rtc", Channel: "test-channel", Uid: "1234", RtcRole: "publisher"}
+	rtmToken, err := service.GenRtmToken(TokenRequest{TokenType: "rtm", Uid: "1234"})
+	if err != nil {
+		t.Fatalf("GenRtmToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		wantChannel string
+		wantUid     string
+		wantErr     bool
+	}{
+		{
+			name:        "Valid UID token",
+			token:       genToken(service, rtcRequest),
+			wantChannel: "test-channel",
+			wantUid:     "1234",
+		},
+		{
+			name:        "Valid account token",
+			token:       genToken(service, TokenRequest{TokenType: "rtc", Channel: "test-channel", Uid: "user-1"}),
+			wantChannel: "test-channel",
+			wantUid:     "user-1",
+		},
+		{
+			name:    "Token of another app",
+			token:   genToken(otherApp, rtcRequest),
+			wantErr: true,
+		},
+		{
+			name:    "Token signed with another certificate",
+			token:   genToken(otherCert, rtcRequest),
+			wantErr: true,
+		},
+		{
+			name:    "RTM token",
+			token:   rtmToken,
+			wantErr: true,
+		},
+		{
+			name:    "Malformed token",
+			token:   "007not-a-token",
+			wantErr: true,
+		},
+		{
+			name:    "Empty token",
+			token:   "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel, uid, err := service.VerifyRtcToken(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyRtcToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if channel != tt.wantChannel || uid != tt.wantUid {
+				t.Errorf("VerifyRtcToken() = (%q, %q), want (%q, %q)", channel, uid, tt.wantChannel, tt.wantUid)
+			}
+		})
+	}
+}
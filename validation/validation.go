@@ -2,6 +2,8 @@ package validation
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/AgoraIO-Community/convo-ai-go-server/convoai"
@@ -19,8 +21,8 @@ func ValidateEnvironment(config *convoai.ConvoAIConfig) error {
 	}
 
 	// Validate LLM Configuration
-	if config.LLMURL == "" || config.LLMToken == "" {
-		return errors.New("config error: LLM configuration (LLM_URL, LLM_TOKEN) is not set")
+	if err := validateLLMConfig(config); err != nil {
+		return err
 	}
 
 	// Validate TTS Configuration
@@ -32,6 +34,16 @@ func ValidateEnvironment(config *convoai.ConvoAIConfig) error {
 		return err
 	}
 
+	// Validate LLM Proxy Configuration
+	if err := validateLLMProxyConfig(config); err != nil {
+		return err
+	}
+
+	// Validate ASR Configuration
+	if err := validateASRConfig(config); err != nil {
+		return err
+	}
+
 	// Validate Modalities (optional, using defaults if not set)
 	if config.InputModalities != "" && !validateModalities(config.InputModalities) {
 		return errors.New("config error: Invalid INPUT_MODALITIES format")
@@ -40,32 +52,226 @@ func ValidateEnvironment(config *convoai.ConvoAIConfig) error {
 		return errors.New("config error: Invalid OUTPUT_MODALITIES format")
 	}
 
+	// Validate Agent Profiles
+	if err := validateProfiles(config); err != nil {
+		return err
+	}
+
+	// Validate Session Store Configuration
+	switch config.SessionStore {
+	case "", convoai.SessionStoreMemory:
+	case convoai.SessionStoreBolt:
+		if config.SessionStorePath == "" {
+			return errors.New("config error: SESSION_STORE_PATH is required for the bolt session store")
+		}
+	default:
+		return errors.New("config error: Unsupported SESSION_STORE: " + config.SessionStore)
+	}
+
+	// Validate Shutdown Configuration
+	switch config.ShutdownPolicy {
+	case "", convoai.ShutdownPolicyLeave, convoai.ShutdownPolicyRemove, convoai.ShutdownPolicyHandoff:
+	default:
+		return errors.New("config error: Unsupported SHUTDOWN_POLICY: " + config.ShutdownPolicy)
+	}
+	if config.ShutdownConcurrency < 0 {
+		return errors.New("config error: SHUTDOWN_CONCURRENCY must not be negative")
+	}
+
+	// Validate Transcript Configuration
+	if config.TranscriptMaxEntries < 0 || config.TranscriptMaxAgents < 0 || config.TranscriptRetentionHours < 0 {
+		return errors.New("config error: TRANSCRIPT_MAX_ENTRIES, TRANSCRIPT_MAX_AGENTS and TRANSCRIPT_RETENTION_HOURS must not be negative")
+	}
+
+	// Validate Outbound Webhook Configuration
+	for _, subscription := range config.WebhookSubscriptions {
+		if err := subscription.Validate(); err != nil {
+			return fmt.Errorf("config error: %v", err)
+		}
+	}
+	if config.WebhookMaxAttempts < 0 || config.AgentPollInterval < 0 {
+		return errors.New("config error: WEBHOOK_MAX_ATTEMPTS and AGENT_POLL_INTERVAL must not be negative")
+	}
+
+	// Validate Agent Token Configuration
+	if config.AgentTokenRefreshInterval < 0 {
+		return errors.New("config error: AGENT_TOKEN_REFRESH_INTERVAL must not be negative")
+	}
+
 	return nil
 }
 
-// Validates the TTS configuration based on the vendor
+// Validates the TTS configuration of the selected vendor through its registered provider
 func validateTTSConfig(config *convoai.ConvoAIConfig) error {
-	switch config.TTSVendor {
-	case "microsoft":
-		if config.MicrosoftTTS == nil {
-			return errors.New("config error: Microsoft TTS configuration is missing")
+	vendor := convoai.TTSVendor(config.TTSVendor)
+	if _, ok := convoai.GetTTSProvider(vendor); !ok {
+		return errors.New("config error: Unsupported TTS vendor: " + config.TTSVendor)
+	}
+	vendorConfig := config.TTS[vendor]
+	if vendorConfig == nil {
+		return fmt.Errorf("config error: %s TTS configuration is missing", vendor)
+	}
+	if err := vendorConfig.Validate(); err != nil {
+		return fmt.Errorf("config error: %v", err)
+	}
+	return nil
+}
+
+// Validates the configuration of the selected LLM provider, defaulting to OpenAI
+func validateLLMConfig(config *convoai.ConvoAIConfig) error {
+	name := convoai.LLMProviderName(config.LLMProvider)
+	if name == "" {
+		name = convoai.DefaultLLMProvider
+	}
+	if _, ok := convoai.GetLLMProvider(name); !ok {
+		return errors.New("config error: Unsupported LLM provider: " + config.LLMProvider)
+	}
+	providerConfig := config.LLM[name]
+	if providerConfig == nil {
+		if name == convoai.LLMProviderOpenAI {
+			return errors.New("config error: LLM configuration (LLM_URL, LLM_TOKEN) is not set")
 		}
-		if config.MicrosoftTTS.Key == "" ||
-			config.MicrosoftTTS.Region == "" ||
-			config.MicrosoftTTS.VoiceName == "" {
-			return errors.New("config error: Microsoft TTS configuration is incomplete")
+		return fmt.Errorf("config error: %s LLM configuration is missing", name)
+	}
+	if err := providerConfig.Validate(); err != nil {
+		return fmt.Errorf("config error: %v", err)
+	}
+	return nil
+}
+
+// Validates the LLM proxy URL, and that the proxied providers speak the OpenAI style
+func validateLLMProxyConfig(config *convoai.ConvoAIConfig) error {
+	if config.LLMProxyRateLimit < 0 {
+		return errors.New("config error: LLM_PROXY_RATE_LIMIT must not be negative")
+	}
+	if config.RetrievalTopK < 0 || config.RetrievalTopK > convoai.MaxRetrievalTopK {
+		return fmt.Errorf("config error: RAG_TOP_K must be between 0 and %d", convoai.MaxRetrievalTopK)
+	}
+	if config.LLMProxyURL == "" {
+		if config.Retriever != nil {
+			return errors.New("config error: RAG_DOCS_DIR requires LLM_PROXY_URL, as retrieval runs in the LLM proxy")
 		}
-	case "elevenlabs":
-		if config.ElevenLabsTTS == nil {
-			return errors.New("config error: ElevenLabs TTS configuration is missing")
+		if len(config.WebhookTools) > 0 || len(config.MCPServers) > 0 {
+			return errors.New("config error: TOOLS_FILE requires LLM_PROXY_URL, as tools run in the LLM proxy")
 		}
-		if config.ElevenLabsTTS.Key == "" ||
-			config.ElevenLabsTTS.VoiceID == "" ||
-			config.ElevenLabsTTS.ModelID == "" {
-			return errors.New("config error: ElevenLabs TTS configuration is incomplete")
+		if config.LLMProxyContextSecret != "" {
+			return errors.New("config error: LLM_PROXY_CONTEXT_SECRET requires LLM_PROXY_URL, as the channel context is injected by the LLM proxy")
 		}
-	default:
-		return errors.New("config error: Unsupported TTS vendor: " + config.TTSVendor)
+		return nil
+	}
+	for _, tool := range config.WebhookTools {
+		if err := tool.Validate(); err != nil {
+			return fmt.Errorf("config error: %v", err)
+		}
+	}
+	for _, server := range config.MCPServers {
+		if err := server.Validate(); err != nil {
+			return fmt.Errorf("config error: %v", err)
+		}
+	}
+	proxyURL, err := url.Parse(config.LLMProxyURL)
+	if err != nil || (proxyURL.Scheme != "http" && proxyURL.Scheme != "https") || proxyURL.Host == "" {
+		return errors.New("config error: LLM_PROXY_URL must be an absolute http(s) URL")
+	}
+	for name, profile := range config.Profiles {
+		endpoint, _, err := convoai.ResolveLLMConfig(config, profile, "")
+		if err == nil && endpoint.Style != convoai.LLMStyleOpenAI {
+			return fmt.Errorf("config error: profile %q: the LLM proxy does not support the %s style", name, endpoint.Style)
+		}
+	}
+	return nil
+}
+
+// Validates the ASR configuration of the selected vendor, defaulting to Agora's built-in ASR
+func validateASRConfig(config *convoai.ConvoAIConfig) error {
+	vendor := convoai.ASRVendor(config.ASRVendor)
+	if vendor == "" {
+		vendor = convoai.DefaultASRVendor
+	}
+	provider, ok := convoai.GetASRProvider(vendor)
+	if !ok {
+		return errors.New("config error: Unsupported ASR vendor: " + config.ASRVendor)
+	}
+	vendorConfig := config.ASR[vendor]
+	if vendorConfig == nil {
+		vendorConfig = provider.NewConfig()
+	}
+	if err := vendorConfig.Validate(); err != nil {
+		return fmt.Errorf("config error: %v", err)
+	}
+	return nil
+}
+
+// Validates every agent profile, including that its TTS, ASR and LLM configurations resolve
+func validateProfiles(config *convoai.ConvoAIConfig) error {
+	for name, profile := range config.Profiles {
+		if err := validateProfile(profile); err != nil {
+			return fmt.Errorf("config error: profile %q: %v", name, err)
+		}
+		if _, err := convoai.ResolveTTSConfig(config, profile); err != nil {
+			return fmt.Errorf("config error: profile %q: %v", name, err)
+		}
+		if _, err := convoai.ResolveASRConfig(config, profile, profile.ASRLanguage); err != nil {
+			return fmt.Errorf("config error: profile %q: %v", name, err)
+		}
+		if _, _, err := convoai.ResolveLLMConfig(config, profile, ""); err != nil {
+			return fmt.Errorf("config error: profile %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Validates the settings of a single agent profile
+func validateProfile(profile *convoai.AgentProfile) error {
+	if len(profile.SystemMessages) == 0 {
+		return errors.New("at least one system message is required")
+	}
+	for _, message := range profile.SystemMessages {
+		if message.Role == "" || message.Content == "" {
+			return errors.New("system messages require a role and content")
+		}
+	}
+	if profile.MaxHistory < 1 {
+		return errors.New("max_history must be at least 1")
+	}
+	if profile.LLMParams.MaxTokens < 1 {
+		return errors.New("llm_params.max_tokens must be at least 1")
+	}
+	if profile.LLMParams.Temperature < 0 || profile.LLMParams.Temperature > 2 {
+		return errors.New("llm_params.temperature must be between 0 and 2")
+	}
+	if profile.LLMParams.TopP < 0 || profile.LLMParams.TopP > 1 {
+		return errors.New("llm_params.top_p must be between 0 and 1")
+	}
+	if len(profile.LLMParams.Stop) > 4 {
+		return errors.New("llm_params.stop must list at most 4 sequences")
+	}
+	if p := profile.LLMParams.PresencePenalty; p != nil && (*p < -2 || *p > 2) {
+		return errors.New("llm_params.presence_penalty must be between -2 and 2")
+	}
+	if p := profile.LLMParams.FrequencyPenalty; p != nil && (*p < -2 || *p > 2) {
+		return errors.New("llm_params.frequency_penalty must be between -2 and 2")
+	}
+	if k := profile.RetrievalTopK; k != nil && (*k < 0 || *k > convoai.MaxRetrievalTopK) {
+		return fmt.Errorf("retrieval_top_k must be between 0 and %d", convoai.MaxRetrievalTopK)
+	}
+	for _, pattern := range profile.Tools {
+		if !convoai.ValidToolPattern(pattern) {
+			return fmt.Errorf("tools entry %q must be a tool name or a prefix ending in *", pattern)
+		}
+	}
+	if profile.VAD.Threshold < 0 || profile.VAD.Threshold > 1 {
+		return errors.New("vad.threshold must be between 0 and 1")
+	}
+	if profile.VAD.SilenceDurationMS < 0 || profile.VAD.SpeechDurationMS < 0 ||
+		profile.VAD.InterruptDurationMS < 0 || profile.VAD.PrefixPaddingMS < 0 {
+		return errors.New("vad durations must not be negative")
+	}
+	if profile.ASRLanguage == "" {
+		return errors.New("asr_language is required")
+	}
+	if profile.IdleTimeout < 0 {
+		return errors.New("idle_timeout must not be negative")
 	}
 	return nil
 }